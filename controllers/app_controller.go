@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
@@ -16,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -30,6 +32,8 @@ import (
 	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider/simpleprovider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 )
 
 // AppReconciler reconciles a App object
@@ -45,7 +49,19 @@ type AppReconciler struct {
 	ProviderCredentials      string
 	GiantswarmWriteAllGroups []string
 	CustomerWriteAllGroups   []string
-	EnableSelfRenewal        bool
+	AuthBindings             []auth.Binding
+	AuthRoleMappings         []auth.RoleMapping
+	AuthAPIEndpointSources   []auth.APIEndpointSource
+	AuthOIDC                 *auth.OIDCConfig
+	AuthKubeconfigClientID   string
+	DryRun                   bool
+	DeletionPolicy           idp.DeletionPolicy
+	RetentionNamespace       string
+	StaticClients            []idp.StaticClient
+	RotationPolicy           *rotation.Policy
+	EnableAppMigration       bool
+
+	pausedTargets pausedTargets
 }
 
 //+kubebuilder:rbac:groups=application.giantswarm.io.giantswarm,resources=apps,verbs=get;list;watch;create;update;patch;delete
@@ -53,6 +69,14 @@ type AppReconciler struct {
 //+kubebuilder:rbac:groups=application.giantswarm.io.giantswarm,resources=apps/finalizers,verbs=update
 
 func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "AppReconciler.Reconcile", tracing.TargetAttributes(dextarget.AppTargetType, req.NamespacedName)...)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	recordReconcile(dextarget.AppTargetType, req.NamespacedName, err)
+	return result, err
+}
+
+func (r *AppReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("app", req.NamespacedName)
 
 	// Fetch the App instance.
@@ -67,44 +91,59 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{}, err
 	}
 
+	// Wrap in DexTarget
+	target := dextarget.NewAppTarget(app)
+
+	// Skip any changes while reconciliation is paused, including the handover to a HelmRelease. Deletion still
+	// cleans up identity provider apps and the auth config, so that nothing is left behind once the finalizer is released.
+	paused, err := isReconciliationPaused(ctx, r.Client, target)
+	if err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+	paused = paused && !target.IsBeingDeleted()
+	if r.pausedTargets.update(req.NamespacedName, paused) {
+		if paused {
+			r.Recorder.Event(app, corev1.EventTypeNormal, reconciliationPausedReason,
+				fmt.Sprintf("Reconciliation is paused by annotation %s", key.PausedAnnotation))
+		} else if !target.IsBeingDeleted() {
+			r.Recorder.Event(app, corev1.EventTypeNormal, reconciliationResumedReason, "Reconciliation is resumed")
+		}
+	}
+	if paused {
+		log.Info(fmt.Sprintf("Reconciliation is paused by annotation %s, skipping.", key.PausedAnnotation))
+		return DefaultRequeue(), nil
+	}
+
 	// Check for HelmRelease with same name - HelmRelease takes priority
-	// If a HelmRelease exists, skip App reconciliation to avoid conflicts.
+	// If a HelmRelease exists, skip App reconciliation to avoid conflicts or,
+	// with app migration enabled, hand the App's resources over to the HelmRelease.
 	// We fail if we can't determine the state to avoid dual reconciliation.
-	hasHelmRelease, err := r.hasMatchingHelmRelease(ctx, req.NamespacedName)
+	hr, err := r.getMatchingHelmRelease(ctx, req.NamespacedName)
 	if err != nil {
 		return ctrl.Result{}, microerror.Mask(err)
 	}
-	if hasHelmRelease {
+	if hr != nil {
+		if r.EnableAppMigration {
+			// the handover changes the HelmRelease's resources too, so it waits while either is paused
+			hrPaused, err := isReconciliationPaused(ctx, r.Client, dextarget.NewHelmReleaseTarget(hr))
+			if err != nil {
+				return ctrl.Result{}, microerror.Mask(err)
+			}
+			if hrPaused {
+				log.Info(fmt.Sprintf("HelmRelease with same name is paused by annotation %s, skipping handover.", key.PausedAnnotation))
+				return DefaultRequeue(), nil
+			}
+			if err := r.handOverToHelmRelease(ctx, log, app, hr); err != nil {
+				return ctrl.Result{}, microerror.Mask(err)
+			}
+			return ctrl.Result{}, nil
+		}
 		log.Info("HelmRelease with same name exists, skipping App reconciliation. The HelmRelease takes priority.",
 			"namespace", req.Namespace, "name", req.Name)
 		// Requeue to check again later in case the HelmRelease is deleted
 		return ctrl.Result{RequeueAfter: time.Minute * 2}, nil
 	}
 
-	// Wrap in DexTarget
-	target := dextarget.NewAppTarget(app)
-
-	var authService *auth.Service
-	{
-		writeAllGroups, err := r.GetWriteAllGroups()
-		if err != nil {
-			return ctrl.Result{}, microerror.Mask(err)
-		}
-
-		c := auth.Config{
-			Log:                             log,
-			Client:                          r.Client,
-			Target:                          target,
-			ManagementClusterName:           r.ManagementCluster,
-			ManagementClusterWriteAllGroups: writeAllGroups,
-		}
-
-		authService, err = auth.New(c)
-		if err != nil {
-			return ctrl.Result{}, microerror.Mask(err)
-		}
-	}
-
 	var idpService *idp.Service
 	{
 		providers, err := r.GetProviders()
@@ -122,6 +161,12 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			ManagementClusterName:          r.ManagementCluster,
 			Owner:                          app,
 			Scheme:                         r.Scheme,
+			DryRun:                         r.DryRun,
+			DeletionPolicy:                 r.DeletionPolicy,
+			RetentionNamespace:             r.RetentionNamespace,
+			StaticClients:                  r.StaticClients,
+			RotationPolicy:                 r.RotationPolicy,
+			Recorder:                       r.Recorder,
 		}
 
 		idpService, err = idp.New(c)
@@ -130,14 +175,46 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		}
 	}
 
+	var authService *auth.Service
+	{
+		writeAllGroups, err := r.GetWriteAllGroups()
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		c := auth.Config{
+			Log:                             log,
+			Client:                          r.Client,
+			Target:                          target,
+			ManagementClusterName:           r.ManagementCluster,
+			ManagementClusterWriteAllGroups: writeAllGroups,
+			Bindings:                        r.AuthBindings,
+			RoleMappings:                    r.AuthRoleMappings,
+			APIEndpointSources:              r.AuthAPIEndpointSources,
+			AppConfigGetter:                 idpService,
+			OIDC:                            r.AuthOIDC,
+			KubeconfigClientID:              r.AuthKubeconfigClientID,
+			DryRun:                          r.DryRun,
+		}
+
+		authService, err = auth.New(c)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+	}
+
 	// App is deleted.
 	if !app.DeletionTimestamp.IsZero() {
-		if err := idpService.ReconcileDelete(ctx); err != nil {
+		if err := runReconcilePhase(ctx, dextarget.AppTargetType, reconcilePhaseIdp, idpService.ReconcileDelete); err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
-		if err := authService.ReconcileDelete(ctx); err != nil {
+		if err := runReconcilePhase(ctx, dextarget.AppTargetType, reconcilePhaseAuth, authService.ReconcileDelete); err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
+		APIEndpointMissing.DeleteLabelValues(dextarget.AppTargetType, app.Name, app.Namespace)
+		if r.DryRun {
+			return ctrl.Result{}, nil
+		}
 		// remove finalizer
 		if controllerutil.ContainsFinalizer(app, key.DexOperatorFinalizer) {
 			controllerutil.RemoveFinalizer(app, key.DexOperatorFinalizer)
@@ -150,7 +227,7 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 
 	// Add finalizer
-	if !controllerutil.ContainsFinalizer(app, key.DexOperatorFinalizer) {
+	if !r.DryRun && !controllerutil.ContainsFinalizer(app, key.DexOperatorFinalizer) {
 		controllerutil.AddFinalizer(app, key.DexOperatorFinalizer)
 		if err := r.Update(ctx, app); err != nil {
 			return ctrl.Result{}, err
@@ -159,24 +236,19 @@ func (r *AppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	}
 
 	// App is not deleted
-	if err := authService.Reconcile(ctx); err != nil {
-		return ctrl.Result{}, microerror.Mask(err)
+	authErr := runReconcilePhase(ctx, dextarget.AppTargetType, reconcilePhaseAuth, authService.Reconcile)
+	if auth.IsAPIEndpointNotFound(authErr) {
+		// the auth config is written without the API server port, dex itself is still reconciled
+		log.Info(fmt.Sprintf("Auth config is incomplete: %s", authErr))
+		r.Recorder.Event(app, corev1.EventTypeWarning, apiEndpointNotFoundReason, authErr.Error())
+	} else if authErr != nil {
+		return ctrl.Result{}, microerror.Mask(authErr)
 	}
-	if err := idpService.Reconcile(ctx); err != nil {
+	recordAPIEndpoint(dextarget.AppTargetType, target.GetNamespacedName(), authErr)
+	if err := runReconcilePhase(ctx, dextarget.AppTargetType, reconcilePhaseIdp, idpService.Reconcile); err != nil {
 		return ctrl.Result{}, microerror.Mask(err)
 	}
 
-	if r.EnableSelfRenewal && key.IsManagementClusterDexApp(app) {
-		if err := idpService.CheckAndRotateServiceCredentials(ctx); err != nil {
-			log.Error(err, "Service credential rotation failed")
-			// Emit a warning event so users can monitor rotation failures
-			r.Recorder.Event(app, corev1.EventTypeWarning, "CredentialRotationFailed",
-				"Failed to rotate service credentials")
-			// Don't fail the reconciliation, just log the error
-			// This prevents self-renewal issues from blocking normal dex operations
-		}
-	}
-
 	return DefaultRequeue(), nil
 }
 
@@ -189,11 +261,33 @@ func (r *AppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.App{}).
-		WithEventFilter(predicate.Or(labelPredicate, namespacedNamePredicate)).
-		Owns(&corev1.Secret{}).
-		Complete(r)
+	dexAppPredicate := predicate.Or(labelPredicate, namespacedNamePredicate)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.App{}, builder.WithPredicates(dexAppPredicate)).
+		Owns(&corev1.Secret{}, builder.WithPredicates(dexAppPredicate))
+
+	b, err = watchAuthSources(b, mgr, r.listDexTargets, r.ManagementCluster, r.Log)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	return b.Complete(r)
+}
+
+// listDexTargets lists all dex apps selected by the label selector.
+func (r *AppReconciler) listDexTargets(ctx context.Context) ([]dextarget.DexTarget, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&r.LabelSelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	apps := &v1alpha1.AppList{}
+	if err := r.List(ctx, apps, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	targets := []dextarget.DexTarget{}
+	for i := range apps.Items {
+		targets = append(targets, dextarget.NewAppTarget(&apps.Items[i]))
+	}
+	return targets, nil
 }
 
 // namespacedNamePredicate constructs a Predicate from a namespaced name.
@@ -205,60 +299,41 @@ func namespacedNamePredicate(s types.NamespacedName) (predicate.Predicate, error
 }
 
 func (r *AppReconciler) GetProviders() ([]provider.Provider, error) {
-	providerCredentials, err := provider.ReadCredentials(r.ProviderCredentials)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	providers := []provider.Provider{}
-	for _, p := range providerCredentials {
-		config := provider.ProviderConfig{
-			Credential:            p,
-			Log:                   r.Log,
-			ManagementClusterName: r.ManagementCluster,
-		}
-
-		provider, err := NewProvider(config)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		providers = append(providers, provider)
-	}
-	return providers, nil
+	return newProviders(r.ProviderCredentials, r.Log, r.ManagementCluster, r.DryRun)
 }
 
 func (r *AppReconciler) GetWriteAllGroups() ([]string, error) {
 	return append(r.GiantswarmWriteAllGroups, r.CustomerWriteAllGroups...), nil
 }
 
-// hasMatchingHelmRelease checks if a HelmRelease with the same name exists in the same namespace.
-// This is used to warn users during migration that both resources exist.
-func (r *AppReconciler) hasMatchingHelmRelease(ctx context.Context, nn types.NamespacedName) (bool, error) {
+// getMatchingHelmRelease returns the dex HelmRelease with the same name in the same namespace, if any.
+// This is used to hand over during migration when both resources exist.
+func (r *AppReconciler) getMatchingHelmRelease(ctx context.Context, nn types.NamespacedName) (*helmv2.HelmRelease, error) {
 	hr := &helmv2.HelmRelease{}
 	err := r.Get(ctx, nn, hr)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return false, nil
+			return nil, nil
 		}
 		// If the HelmRelease CRD is not installed, we can't have any HelmReleases
 		if meta.IsNoMatchError(err) {
-			return false, nil
+			return nil, nil
 		}
-		return false, err
+		return nil, err
 	}
 
 	// Check if the HelmRelease has the dex-app label
 	labels := hr.GetLabels()
 	if labels != nil && labels[key.AppLabel] == key.DexAppLabelValue {
-		return true, nil
+		return hr, nil
 	}
 
 	// Also check if it's the management cluster dex HelmRelease by name
 	if key.IsManagementClusterDexHelmRelease(hr.Name, hr.Namespace) {
-		return true, nil
+		return hr, nil
 	}
 
-	return false, nil
+	return nil, nil
 }
 
 func NewProvider(config provider.ProviderConfig) (provider.Provider, error) {
@@ -278,4 +353,17 @@ func NewProvider(config provider.ProviderConfig) (provider.Provider, error) {
 func init() {
 	// Register custom metrics with the global prometheus registry
 	metrics.Registry.MustRegister(idp.AppInfo)
+	metrics.Registry.MustRegister(idp.MissingSecretConfig)
+	metrics.Registry.MustRegister(idp.ServiceCredentialCreated)
+	metrics.Registry.MustRegister(idp.ServiceCredentialExpiry)
+	metrics.Registry.MustRegister(idp.ServiceCredentialLastRotation)
+	metrics.Registry.MustRegister(idp.ServiceCredentialRotations)
+	metrics.Registry.MustRegister(idp.UserConfigConnectors)
+	metrics.Registry.MustRegister(Reconciles)
+	metrics.Registry.MustRegister(APIEndpointMissing)
+	metrics.Registry.MustRegister(SelfRenewalTargetMissing)
+	metrics.Registry.MustRegister(ReconcilePhaseDuration)
+	metrics.Registry.MustRegister(idp.ConnectorChanges)
+	metrics.Registry.MustRegister(provider.RequestDuration)
+	metrics.Registry.MustRegister(provider.RequestErrors)
 }
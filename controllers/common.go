@@ -1,10 +1,34 @@
 package controllers
 
 import (
+	"context"
+	"sync"
 	"time"
 
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	reconciliationPausedReason  = "ReconciliationPaused"
+	reconciliationResumedReason = "ReconciliationResumed"
+	apiEndpointNotFoundReason   = "APIEndpointNotFound"
+
+	apiEndpointFoundCondition = "APIEndpointFound"
+	apiEndpointFoundReason    = "APIEndpointFound"
 )
 
 // DefaultRequeue returns the default requeue result for dex-operator controllers.
@@ -14,3 +38,99 @@ func DefaultRequeue() reconcile.Result {
 		RequeueAfter: time.Minute * 5,
 	}
 }
+
+// isReconciliationPaused checks whether the paused annotation is set on the dex target
+// or on the dex config secret generated for it.
+func isReconciliationPaused(ctx context.Context, c client.Client, target dextarget.DexTarget) (bool, error) {
+	if key.IsPaused(target.GetObject()) {
+		return true, nil
+	}
+
+	nn := target.GetNamespacedName()
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: key.GetDexConfigName(nn.Name), Namespace: nn.Namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, microerror.Mask(err)
+	}
+	return key.IsPaused(secret), nil
+}
+
+// setAPIEndpointCondition records in the status of the HelmRelease whether the API server endpoint of its
+// workload cluster was found, notFoundErr is the error of the auth phase if it was not. Flux keeps conditions of other controllers when it patches the status.
+// The condition is only added once an endpoint is missing and is not available for App CRs, which have no
+// status conditions.
+func setAPIEndpointCondition(ctx context.Context, c client.Client, hr *helmv2.HelmRelease, notFoundErr error) error {
+	condition := metav1.Condition{
+		Type:               apiEndpointFoundCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: hr.Generation,
+		Reason:             apiEndpointFoundReason,
+		Message:            "The API server endpoint of the workload cluster was found.",
+	}
+	if notFoundErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = apiEndpointNotFoundReason
+		condition.Message = notFoundErr.Error()
+	} else if apimeta.FindStatusCondition(hr.Status.Conditions, apiEndpointFoundCondition) == nil {
+		return nil
+	}
+	base := hr.DeepCopy()
+	if !apimeta.SetStatusCondition(&hr.Status.Conditions, condition) {
+		return nil
+	}
+	if err := c.Status().Patch(ctx, hr, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+		return microerror.Mask(err)
+	}
+	return nil
+}
+
+// pausedTargets keeps track of the dex targets whose reconciliation is paused, so that events are only
+// recorded when the paused state changes and not on every requeue.
+type pausedTargets struct {
+	mutex   sync.Mutex
+	targets map[types.NamespacedName]bool
+}
+
+// update records the paused state of the dex target and returns true if it changed.
+// Targets which were not seen before count as not paused.
+func (p *pausedTargets) update(nn types.NamespacedName, paused bool) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.targets == nil {
+		p.targets = map[types.NamespacedName]bool{}
+	}
+	changed := p.targets[nn] != paused
+	if paused {
+		p.targets[nn] = true
+	} else {
+		delete(p.targets, nn)
+	}
+	return changed
+}
+
+// newProviders constructs a provider for each entry in the credentials file.
+func newProviders(credentialsFile string, log logr.Logger, managementCluster string, dryRun bool) ([]provider.Provider, error) {
+	providerCredentials, err := provider.ReadCredentials(credentialsFile)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	providers := []provider.Provider{}
+	for _, p := range providerCredentials {
+		config := provider.ProviderConfig{
+			Credential:            p,
+			Log:                   log,
+			ManagementClusterName: managementCluster,
+			DryRun:                dryRun,
+		}
+
+		provider, err := NewProvider(config)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
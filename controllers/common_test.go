@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestPausedTargets(t *testing.T) {
+	nn := types.NamespacedName{Name: "dex-app", Namespace: "example"}
+	testCases := []struct {
+		name            string
+		states          []bool
+		expectedChanges []bool
+	}{
+		{
+			name:            "case 0: never paused",
+			states:          []bool{false, false},
+			expectedChanges: []bool{false, false},
+		},
+		{
+			name:            "case 1: paused on every requeue",
+			states:          []bool{true, true, true},
+			expectedChanges: []bool{true, false, false},
+		},
+		{
+			name:            "case 2: paused and resumed",
+			states:          []bool{true, false, false, true},
+			expectedChanges: []bool{true, true, false, true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := pausedTargets{}
+			for i, paused := range tc.states {
+				if changed := p.update(nn, paused); changed != tc.expectedChanges[i] {
+					t.Fatalf("expected change %v in update %d, got %v", tc.expectedChanges[i], i, changed)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilePausedApp(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = helmv2.AddToScheme(scheme)
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "dex-app",
+			Namespace:   "example",
+			Annotations: map[string]string{key.PausedAnnotation: "true"},
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &AppReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(app).Build(),
+		Log:      ctrl.Log.WithName("test"),
+		Recorder: recorder,
+		Scheme:   scheme,
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: app.Name, Namespace: app.Namespace}}
+	for i := 0; i < 3; i++ {
+		if _, err := r.reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected a single %s event, got %d", reconciliationPausedReason, len(recorder.Events))
+	}
+}
+
+func TestSetAPIEndpointCondition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = helmv2.AddToScheme(scheme)
+
+	notFound := metav1.Condition{
+		Type:    apiEndpointFoundCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  apiEndpointNotFoundReason,
+		Message: "no API endpoint found",
+	}
+	testCases := []struct {
+		name              string
+		conditions        []metav1.Condition
+		notFoundErr       error
+		expectedCondition *metav1.Condition
+		expectedCount     int
+	}{
+		{
+			name:              "case 0: endpoint found without condition",
+			expectedCondition: nil,
+		},
+		{
+			name:        "case 1: endpoint not found",
+			notFoundErr: errors.New("no API endpoint found"),
+			expectedCondition: &metav1.Condition{
+				Type:    apiEndpointFoundCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  apiEndpointNotFoundReason,
+				Message: "no API endpoint found",
+			},
+			expectedCount: 1,
+		},
+		{
+			name:       "case 2: endpoint found again",
+			conditions: []metav1.Condition{notFound},
+			expectedCondition: &metav1.Condition{
+				Type:   apiEndpointFoundCondition,
+				Status: metav1.ConditionTrue,
+				Reason: apiEndpointFoundReason,
+			},
+			expectedCount: 1,
+		},
+		{
+			name:        "case 3: flux conditions are kept",
+			conditions:  []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "InstallSucceeded"}},
+			notFoundErr: errors.New("no API endpoint found"),
+			expectedCondition: &metav1.Condition{
+				Type:    apiEndpointFoundCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  apiEndpointNotFoundReason,
+				Message: "no API endpoint found",
+			},
+			expectedCount: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			hr := &helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "dex-app", Namespace: "example"},
+			}
+			for _, c := range tc.conditions {
+				c.LastTransitionTime = metav1.Now()
+				hr.Status.Conditions = append(hr.Status.Conditions, c)
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(hr).WithStatusSubresource(hr).Build()
+
+			current := &helmv2.HelmRelease{}
+			if err := c.Get(ctx, types.NamespacedName{Name: hr.Name, Namespace: hr.Namespace}, current); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := setAPIEndpointCondition(ctx, c, current, tc.notFoundErr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			stored := &helmv2.HelmRelease{}
+			if err := c.Get(ctx, types.NamespacedName{Name: hr.Name, Namespace: hr.Namespace}, stored); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := apimeta.FindStatusCondition(stored.Status.Conditions, apiEndpointFoundCondition)
+			if tc.expectedCondition == nil {
+				if condition != nil {
+					t.Fatalf("expected no %s condition, got %v", apiEndpointFoundCondition, condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("expected %s condition", apiEndpointFoundCondition)
+			}
+			if condition.Status != tc.expectedCondition.Status || condition.Reason != tc.expectedCondition.Reason {
+				t.Fatalf("expected condition %s/%s, got %s/%s", tc.expectedCondition.Status, tc.expectedCondition.Reason, condition.Status, condition.Reason)
+			}
+			if tc.expectedCondition.Message != "" && condition.Message != tc.expectedCondition.Message {
+				t.Fatalf("expected message %q, got %q", tc.expectedCondition.Message, condition.Message)
+			}
+			if len(stored.Status.Conditions) != tc.expectedCount {
+				t.Fatalf("expected %d conditions, got %d", tc.expectedCount, len(stored.Status.Conditions))
+			}
+		})
+	}
+}
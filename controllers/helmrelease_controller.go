@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	"github.com/giantswarm/microerror"
@@ -13,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -22,6 +24,8 @@ import (
 	"github.com/giantswarm/dex-operator/pkg/idp"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 )
 
 // HelmReleaseReconciler reconciles a Flux HelmRelease object for dex-app
@@ -37,7 +41,18 @@ type HelmReleaseReconciler struct {
 	ProviderCredentials      string
 	GiantswarmWriteAllGroups []string
 	CustomerWriteAllGroups   []string
-	EnableSelfRenewal        bool
+	AuthBindings             []auth.Binding
+	AuthRoleMappings         []auth.RoleMapping
+	AuthAPIEndpointSources   []auth.APIEndpointSource
+	AuthOIDC                 *auth.OIDCConfig
+	AuthKubeconfigClientID   string
+	DryRun                   bool
+	DeletionPolicy           idp.DeletionPolicy
+	RetentionNamespace       string
+	StaticClients            []idp.StaticClient
+	RotationPolicy           *rotation.Policy
+
+	pausedTargets pausedTargets
 }
 
 //+kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;create;update;patch;delete
@@ -45,6 +60,14 @@ type HelmReleaseReconciler struct {
 //+kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases/finalizers,verbs=update
 
 func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "HelmReleaseReconciler.Reconcile", tracing.TargetAttributes(dextarget.HelmReleaseTargetType, req.NamespacedName)...)
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	recordReconcile(dextarget.HelmReleaseTargetType, req.NamespacedName, err)
+	return result, err
+}
+
+func (r *HelmReleaseReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("helmrelease", req.NamespacedName)
 
 	// Fetch the HelmRelease instance
@@ -62,26 +85,25 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Wrap in DexTarget
 	target := dextarget.NewHelmReleaseTarget(hr)
 
-	var authService *auth.Service
-	{
-		writeAllGroups, err := r.GetWriteAllGroups()
-		if err != nil {
-			return ctrl.Result{}, microerror.Mask(err)
-		}
-
-		c := auth.Config{
-			Log:                             log,
-			Client:                          r.Client,
-			Target:                          target,
-			ManagementClusterName:           r.ManagementCluster,
-			ManagementClusterWriteAllGroups: writeAllGroups,
-		}
-
-		authService, err = auth.New(c)
-		if err != nil {
-			return ctrl.Result{}, microerror.Mask(err)
+	// Skip any changes while reconciliation is paused. Deletion still cleans up identity provider
+	// apps and the auth config, so that nothing is left behind once the finalizer is released.
+	paused, err := isReconciliationPaused(ctx, r.Client, target)
+	if err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
+	}
+	paused = paused && !target.IsBeingDeleted()
+	if r.pausedTargets.update(req.NamespacedName, paused) {
+		if paused {
+			r.Recorder.Event(hr, corev1.EventTypeNormal, reconciliationPausedReason,
+				fmt.Sprintf("Reconciliation is paused by annotation %s", key.PausedAnnotation))
+		} else if !target.IsBeingDeleted() {
+			r.Recorder.Event(hr, corev1.EventTypeNormal, reconciliationResumedReason, "Reconciliation is resumed")
 		}
 	}
+	if paused {
+		log.Info(fmt.Sprintf("Reconciliation is paused by annotation %s, skipping.", key.PausedAnnotation))
+		return DefaultRequeue(), nil
+	}
 
 	var idpService *idp.Service
 	{
@@ -100,6 +122,12 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			ManagementClusterName:          r.ManagementCluster,
 			Owner:                          hr,
 			Scheme:                         r.Scheme,
+			DryRun:                         r.DryRun,
+			DeletionPolicy:                 r.DeletionPolicy,
+			RetentionNamespace:             r.RetentionNamespace,
+			StaticClients:                  r.StaticClients,
+			RotationPolicy:                 r.RotationPolicy,
+			Recorder:                       r.Recorder,
 		}
 
 		idpService, err = idp.New(c)
@@ -108,14 +136,47 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	var authService *auth.Service
+	{
+		writeAllGroups, err := r.GetWriteAllGroups()
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+
+		c := auth.Config{
+			Log:                             log,
+			Client:                          r.Client,
+			Target:                          target,
+			ManagementClusterName:           r.ManagementCluster,
+			ManagementClusterWriteAllGroups: writeAllGroups,
+			Bindings:                        r.AuthBindings,
+			RoleMappings:                    r.AuthRoleMappings,
+			APIEndpointSources:              r.AuthAPIEndpointSources,
+			AppConfigGetter:                 idpService,
+			OIDC:                            r.AuthOIDC,
+			KubeconfigClientID:              r.AuthKubeconfigClientID,
+			DryRun:                          r.DryRun,
+		}
+
+		authService, err = auth.New(c)
+		if err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
+	}
+
 	// HelmRelease is deleted
 	if target.IsBeingDeleted() {
-		if err := idpService.ReconcileDelete(ctx); err != nil {
+		if err := runReconcilePhase(ctx, dextarget.HelmReleaseTargetType, reconcilePhaseIdp, idpService.ReconcileDelete); err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
-		if err := authService.ReconcileDelete(ctx); err != nil {
+		if err := runReconcilePhase(ctx, dextarget.HelmReleaseTargetType, reconcilePhaseAuth, authService.ReconcileDelete); err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
 		}
+		idp.MissingSecretConfig.DeleteLabelValues(hr.Name, hr.Namespace)
+		APIEndpointMissing.DeleteLabelValues(dextarget.HelmReleaseTargetType, hr.Name, hr.Namespace)
+		if r.DryRun {
+			return ctrl.Result{}, nil
+		}
 		// Remove finalizer
 		if controllerutil.ContainsFinalizer(hr, key.DexOperatorFinalizer) {
 			controllerutil.RemoveFinalizer(hr, key.DexOperatorFinalizer)
@@ -128,7 +189,7 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Add finalizer
-	if !controllerutil.ContainsFinalizer(hr, key.DexOperatorFinalizer) {
+	if !r.DryRun && !controllerutil.ContainsFinalizer(hr, key.DexOperatorFinalizer) {
 		controllerutil.AddFinalizer(hr, key.DexOperatorFinalizer)
 		if err := r.Update(ctx, hr); err != nil {
 			return ctrl.Result{}, microerror.Mask(err)
@@ -137,24 +198,29 @@ func (r *HelmReleaseReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Reconcile auth configuration (for workload clusters)
-	if err := authService.Reconcile(ctx); err != nil {
-		return ctrl.Result{}, microerror.Mask(err)
+	authErr := runReconcilePhase(ctx, dextarget.HelmReleaseTargetType, reconcilePhaseAuth, authService.Reconcile)
+	if auth.IsAPIEndpointNotFound(authErr) {
+		// the auth config is written without the API server port, dex itself is still reconciled
+		log.Info(fmt.Sprintf("Auth config is incomplete: %s", authErr))
+		r.Recorder.Event(hr, corev1.EventTypeWarning, apiEndpointNotFoundReason, authErr.Error())
+	} else if authErr != nil {
+		return ctrl.Result{}, microerror.Mask(authErr)
+	}
+	recordAPIEndpoint(dextarget.HelmReleaseTargetType, target.GetNamespacedName(), authErr)
+	if !r.DryRun {
+		if err := setAPIEndpointCondition(ctx, r.Client, hr, authErr); err != nil {
+			return ctrl.Result{}, microerror.Mask(err)
+		}
 	}
 
 	// Reconcile IDP configuration
-	if err := idpService.Reconcile(ctx); err != nil {
+	if err := runReconcilePhase(ctx, dextarget.HelmReleaseTargetType, reconcilePhaseIdp, idpService.Reconcile); err != nil {
 		return ctrl.Result{}, microerror.Mask(err)
 	}
 
-	// Self-renewal for management cluster dex HelmRelease
-	if r.EnableSelfRenewal && key.IsManagementClusterDexHelmRelease(hr.Name, hr.Namespace) {
-		if err := idpService.CheckAndRotateServiceCredentials(ctx); err != nil {
-			log.Error(err, "Service credential rotation failed")
-			// Emit a warning event so users can monitor rotation failures
-			r.Recorder.Event(hr, corev1.EventTypeWarning, "CredentialRotationFailed",
-				"Failed to rotate service credentials")
-			// Don't fail the reconciliation, just log the error
-		}
+	// Publish the patch for Flux-managed HelmReleases missing the dex config secret reference
+	if err := r.reconcileSecretConfigPatch(ctx, log, target); err != nil {
+		return ctrl.Result{}, microerror.Mask(err)
 	}
 
 	return DefaultRequeue(), nil
@@ -169,11 +235,33 @@ func (r *HelmReleaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return microerror.Mask(err)
 	}
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&helmv2.HelmRelease{}).
-		WithEventFilter(predicate.Or(labelPredicate, namespacedNamePredicate)).
-		Owns(&corev1.Secret{}).
-		Complete(r)
+	dexHelmReleasePredicate := predicate.Or(labelPredicate, namespacedNamePredicate)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&helmv2.HelmRelease{}, builder.WithPredicates(dexHelmReleasePredicate)).
+		Owns(&corev1.Secret{}, builder.WithPredicates(dexHelmReleasePredicate))
+
+	b, err = watchAuthSources(b, mgr, r.listDexTargets, r.ManagementCluster, r.Log)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	return b.Complete(r)
+}
+
+// listDexTargets lists all dex HelmReleases selected by the label selector.
+func (r *HelmReleaseReconciler) listDexTargets(ctx context.Context) ([]dextarget.DexTarget, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&r.LabelSelector)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	helmReleases := &helmv2.HelmReleaseList{}
+	if err := r.List(ctx, helmReleases, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	targets := []dextarget.DexTarget{}
+	for i := range helmReleases.Items {
+		targets = append(targets, dextarget.NewHelmReleaseTarget(&helmReleases.Items[i]))
+	}
+	return targets, nil
 }
 
 // helmReleaseNamespacedNamePredicate constructs a Predicate from a namespaced name.
@@ -185,26 +273,7 @@ func helmReleaseNamespacedNamePredicate(s types.NamespacedName) (predicate.Predi
 }
 
 func (r *HelmReleaseReconciler) GetProviders() ([]provider.Provider, error) {
-	providerCredentials, err := provider.ReadCredentials(r.ProviderCredentials)
-	if err != nil {
-		return nil, microerror.Mask(err)
-	}
-
-	providers := []provider.Provider{}
-	for _, p := range providerCredentials {
-		config := provider.ProviderConfig{
-			Credential:            p,
-			Log:                   r.Log,
-			ManagementClusterName: r.ManagementCluster,
-		}
-
-		provider, err := NewProvider(config)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		providers = append(providers, provider)
-	}
-	return providers, nil
+	return newProviders(r.ProviderCredentials, r.Log, r.ManagementCluster, r.DryRun)
 }
 
 func (r *HelmReleaseReconciler) GetWriteAllGroups() ([]string, error) {
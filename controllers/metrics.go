@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/dex-operator/pkg/auth"
+)
+
+const (
+	metricNamespace = "dex_operator"
+
+	reconcileResultSuccess = "success"
+	reconcileResultError   = "error"
+
+	reconcilePhaseAuth        = "auth"
+	reconcilePhaseIdp         = "idp"
+	reconcilePhaseSelfRenewal = "self-renewal"
+)
+
+var (
+	// Reconciles counts reconciliations of dex targets by result, so that the error rate of single targets can be alerted on.
+	Reconciles = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "reconciles_total",
+			Help:      "Counts reconciliations of dex targets by result.",
+		},
+		[]string{
+			"target_type",
+			"app_name",
+			"app_namespace",
+			"result",
+		},
+	)
+
+	// APIEndpointMissing is set for dex targets of workload clusters whose API server endpoint was not found
+	// in any API endpoint source. App CRs have no status conditions, so it is their lasting signal next to the
+	// APIEndpointNotFound events.
+	APIEndpointMissing = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "api_endpoint_missing",
+			Help:      "Set to 1 for dex targets whose workload cluster API server endpoint was not found.",
+		},
+		[]string{
+			"target_type",
+			"app_name",
+			"app_namespace",
+		},
+	)
+
+	// SelfRenewalTargetMissing is set to 1 while self-renewal finds no management cluster dex,
+	// in which case the credentials of dex-operator are not renewed.
+	SelfRenewalTargetMissing = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "self_renewal_target_missing",
+			Help:      "Set to 1 while self-renewal finds no management cluster dex HelmRelease or App.",
+		},
+	)
+
+	// ReconcilePhaseDuration tracks how long the phases of reconciliations of dex targets take.
+	ReconcilePhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "reconcile_phase_duration_seconds",
+			Help:      "Gives the duration of the auth, idp and self-renewal phases of reconciliations of dex targets.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{
+			"target_type",
+			"phase",
+		},
+	)
+)
+
+// runReconcilePhase runs a phase of the reconciliation of a dex target and records its duration.
+func runReconcilePhase(ctx context.Context, targetType string, phase string, f func(context.Context) error) error {
+	start := time.Now()
+	err := f(ctx)
+	ReconcilePhaseDuration.WithLabelValues(targetType, phase).Observe(time.Since(start).Seconds())
+	return err
+}
+
+// recordAPIEndpoint sets APIEndpointMissing if the API server endpoint of the target was not found.
+func recordAPIEndpoint(targetType string, nn types.NamespacedName, err error) {
+	if auth.IsAPIEndpointNotFound(err) {
+		APIEndpointMissing.WithLabelValues(targetType, nn.Name, nn.Namespace).Set(1)
+		return
+	}
+	APIEndpointMissing.DeleteLabelValues(targetType, nn.Name, nn.Namespace)
+}
+
+func recordReconcile(targetType string, nn types.NamespacedName, err error) {
+	result := reconcileResultSuccess
+	if err != nil {
+		result = reconcileResultError
+	}
+	Reconciles.WithLabelValues(targetType, nn.Name, nn.Namespace, result).Inc()
+}
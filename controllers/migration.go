@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	migratedToHelmReleaseReason = "MigratedToHelmRelease"
+)
+
+// handOverToHelmRelease transfers the dex config secret and the static clients secret of an App to the
+// HelmRelease replacing it. The identity provider app registrations are named after namespace and name of
+// the dex target, so the HelmRelease takes them over as they are. The auth configmap is named after the
+// cluster, is not owned by the App and is kept by its own finalizer, and the kubeconfig configmap is owned by
+// the auth configmap, so the HelmRelease takes both over as they are. The App's secret config and finalizer
+// are removed so that deleting the App afterwards does not clean up anything the HelmRelease uses.
+func (r *AppReconciler) handOverToHelmRelease(ctx context.Context, log logr.Logger, app *v1alpha1.App, hr *helmv2.HelmRelease) error {
+	secretName := key.GetDexConfigName(app.Name)
+	target := dextarget.NewAppTarget(app)
+
+	if r.DryRun {
+		log.Info(fmt.Sprintf("Dry run: would hand over dex config secret %s/%s, static clients secret and app registrations from App to HelmRelease %s.", app.Namespace, secretName, hr.Name))
+		return nil
+	}
+
+	// Transfer ownership of the secrets first, so that they survive deletion of the App.
+	for _, name := range []string{secretName, key.GetStaticClientsSecretName(app.Name)} {
+		if err := r.transferSecretToHelmRelease(ctx, log, types.NamespacedName{Name: name, Namespace: app.Namespace}, hr); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
+	// Remove secret config and finalizer from the App in a single update.
+	updateApp := false
+	if target.HasSecretConfig(secretName) {
+		if err := target.RemoveSecretConfig(secretName, app.Namespace); err != nil {
+			return microerror.Mask(err)
+		}
+		updateApp = true
+	}
+	if controllerutil.ContainsFinalizer(app, key.DexOperatorFinalizer) {
+		controllerutil.RemoveFinalizer(app, key.DexOperatorFinalizer)
+		updateApp = true
+	}
+	if !updateApp {
+		return nil
+	}
+	if err := r.Update(ctx, app); err != nil {
+		return microerror.Mask(err)
+	}
+	log.Info("Removed secret config and finalizer from dex app instance after handover to HelmRelease.")
+	r.Recorder.Event(app, corev1.EventTypeNormal, migratedToHelmReleaseReason,
+		fmt.Sprintf("Handed over dex config secret %s, static clients secret and identity provider app registrations to HelmRelease %s", secretName, hr.Name))
+
+	return nil
+}
+
+// transferSecretToHelmRelease makes the HelmRelease the controller of the secret, if it exists.
+func (r *AppReconciler) transferSecretToHelmRelease(ctx context.Context, log logr.Logger, nn types.NamespacedName, hr *helmv2.HelmRelease) error {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, nn, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return microerror.Mask(err)
+	}
+	if metav1.IsControlledBy(secret, hr) {
+		return nil
+	}
+	// The secret can only have one controller, which is the App if it was created by dex-operator.
+	ownerReferences := []metav1.OwnerReference{}
+	for _, ref := range secret.OwnerReferences {
+		if ref.Controller == nil || !*ref.Controller {
+			ownerReferences = append(ownerReferences, ref)
+		}
+	}
+	secret.OwnerReferences = ownerReferences
+	if err := controllerutil.SetControllerReference(hr, secret, r.Scheme); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := r.Update(ctx, secret); err != nil {
+		return microerror.Mask(err)
+	}
+	log.Info(fmt.Sprintf("Transferred ownership of secret %s to HelmRelease.", nn.Name))
+	return nil
+}
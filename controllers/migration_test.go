@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestHandOverToHelmRelease(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = helmv2.AddToScheme(scheme)
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "dex-app",
+			Namespace:  "example",
+			UID:        "app-uid",
+			Finalizers: []string{key.DexOperatorFinalizer},
+		},
+		Spec: v1alpha1.AppSpec{
+			ExtraConfigs: []v1alpha1.AppExtraConfig{
+				{Kind: "secret", Name: key.GetDexConfigName("dex-app"), Namespace: "example", Priority: key.DexSecretConfigPriority},
+				{Kind: "configMap", Name: "other", Namespace: "example"},
+			},
+		},
+	}
+	hr := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dex-app",
+			Namespace: "example",
+			UID:       "hr-uid",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       key.GetDexConfigName("dex-app"),
+			Namespace:  "example",
+			Finalizers: []string{key.DexOperatorFinalizer},
+		},
+	}
+	if err := controllerutil.SetControllerReference(app, secret, scheme); err != nil {
+		t.Fatal(err)
+	}
+	staticClientsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.GetStaticClientsSecretName("dex-app"),
+			Namespace: "example",
+		},
+	}
+	if err := controllerutil.SetControllerReference(app, staticClientsSecret, scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(app, hr, secret, staticClientsSecret).Build()
+	r := &AppReconciler{
+		Client:             fakeClient,
+		Log:                ctrl.Log.WithName("test"),
+		Recorder:           record.NewFakeRecorder(10),
+		Scheme:             scheme,
+		EnableAppMigration: true,
+	}
+
+	if err := r.handOverToHelmRelease(ctx, r.Log, app, hr); err != nil {
+		t.Fatal(err)
+	}
+
+	updatedSecret := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, updatedSecret); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(updatedSecret, hr) {
+		t.Fatalf("expected secret to be controlled by the HelmRelease, got %v", updatedSecret.OwnerReferences)
+	}
+	if len(updatedSecret.OwnerReferences) != 1 {
+		t.Fatalf("expected a single owner reference, got %v", updatedSecret.OwnerReferences)
+	}
+	if !controllerutil.ContainsFinalizer(updatedSecret, key.DexOperatorFinalizer) {
+		t.Fatalf("expected secret to keep its finalizer")
+	}
+	updatedStaticClientsSecret := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: staticClientsSecret.Name, Namespace: staticClientsSecret.Namespace}, updatedStaticClientsSecret); err != nil {
+		t.Fatal(err)
+	}
+	if !metav1.IsControlledBy(updatedStaticClientsSecret, hr) {
+		t.Fatalf("expected static clients secret to be controlled by the HelmRelease, got %v", updatedStaticClientsSecret.OwnerReferences)
+	}
+
+	updatedApp := &v1alpha1.App{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, updatedApp); err != nil {
+		t.Fatal(err)
+	}
+	if controllerutil.ContainsFinalizer(updatedApp, key.DexOperatorFinalizer) {
+		t.Fatalf("expected finalizer to be removed from the App")
+	}
+	if len(updatedApp.Spec.ExtraConfigs) != 1 || updatedApp.Spec.ExtraConfigs[0].Name != "other" {
+		t.Fatalf("expected only the dex secret config to be removed, got %v", updatedApp.Spec.ExtraConfigs)
+	}
+
+	// A second handover is a no-op.
+	if err := r.handOverToHelmRelease(ctx, r.Log, updatedApp, hr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcilePausedHandOver(t *testing.T) {
+	testCases := []struct {
+		name      string
+		appPaused bool
+		hrPaused  bool
+	}{
+		{
+			name:      "case 0: paused App is not handed over",
+			appPaused: true,
+		},
+		{
+			name:     "case 1: App is not handed over to a paused HelmRelease",
+			hrPaused: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			_ = v1alpha1.AddToScheme(scheme)
+			_ = helmv2.AddToScheme(scheme)
+
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "dex-app",
+					Namespace:  "example",
+					Finalizers: []string{key.DexOperatorFinalizer},
+				},
+			}
+			hr := &helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "dex-app",
+					Namespace: "example",
+					Labels:    map[string]string{key.AppLabel: key.DexAppLabelValue},
+				},
+			}
+			if tc.appPaused {
+				app.Annotations = map[string]string{key.PausedAnnotation: "true"}
+			}
+			if tc.hrPaused {
+				hr.Annotations = map[string]string{key.PausedAnnotation: "true"}
+			}
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(app, hr).Build()
+			r := &AppReconciler{
+				Client:             fakeClient,
+				Log:                ctrl.Log.WithName("test"),
+				Recorder:           record.NewFakeRecorder(10),
+				Scheme:             scheme,
+				EnableAppMigration: true,
+			}
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: app.Name, Namespace: app.Namespace}}
+			if _, err := r.reconcile(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			updatedApp := &v1alpha1.App{}
+			if err := fakeClient.Get(ctx, req.NamespacedName, updatedApp); err != nil {
+				t.Fatal(err)
+			}
+			if !controllerutil.ContainsFinalizer(updatedApp, key.DexOperatorFinalizer) {
+				t.Fatalf("expected the App to keep its finalizer while the handover is paused")
+			}
+		})
+	}
+}
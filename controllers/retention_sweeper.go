@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/idp"
+)
+
+// RetentionSweeper periodically removes app registrations which were retained on
+// deletion of their dex target once their retention duration has passed.
+// It runs on the leader only.
+type RetentionSweeper struct {
+	client.Client
+	Log                 logr.Logger
+	ManagementCluster   string
+	ProviderCredentials string
+	Namespace           string
+	Interval            time.Duration
+	DryRun              bool
+}
+
+func (r *RetentionSweeper) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.sweep(ctx); err != nil {
+			r.Log.Error(err, "Sweeping retained app registrations failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *RetentionSweeper) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *RetentionSweeper) sweep(ctx context.Context) error {
+	providers, err := newProviders(r.ProviderCredentials, r.Log, r.ManagementCluster, r.DryRun)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	sweeper, err := idp.NewRetentionSweeper(idp.RetentionSweeperConfig{
+		Client:    r.Client,
+		Log:       r.Log,
+		Providers: providers,
+		Namespace: r.Namespace,
+		DryRun:    r.DryRun,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	return sweeper.Sweep(ctx, time.Now())
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	missingSecretConfigReason = "MissingSecretConfig"
+)
+
+// reconcileSecretConfigPatch publishes the kustomize patch which adds the dex config secret to a
+// Flux-managed HelmRelease that does not reference it yet. The patch is recorded in an event and in
+// a configmap next to the HelmRelease, and the HelmRelease is counted in the missing secret config metric.
+// Once the reference is present, the configmap and the metric are cleaned up again.
+func (r *HelmReleaseReconciler) reconcileSecretConfigPatch(ctx context.Context, log logr.Logger, target *dextarget.HelmReleaseTarget) error {
+	nn := target.GetNamespacedName()
+	secretName := key.GetDexConfigName(nn.Name)
+	patchConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.GetSecretConfigPatchName(nn.Name),
+			Namespace: nn.Namespace,
+		},
+	}
+
+	if target.ManagesSecretConfig() || target.HasSecretConfig(secretName) {
+		idp.MissingSecretConfig.DeleteLabelValues(nn.Name, nn.Namespace)
+		if r.DryRun {
+			return nil
+		}
+		if err := r.Delete(ctx, patchConfigMap); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return microerror.Mask(err)
+			}
+		} else {
+			log.Info(fmt.Sprintf("Deleted secret config patch configmap %s.", patchConfigMap.Name))
+		}
+		return nil
+	}
+
+	patch, err := target.GetSecretConfigPatch(secretName)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	idp.MissingSecretConfig.WithLabelValues(nn.Name, nn.Namespace).Set(1)
+
+	if r.DryRun {
+		log.Info(fmt.Sprintf("Dry run: would write secret config patch configmap %s.", patchConfigMap.Name))
+		return nil
+	}
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(patchConfigMap), patchConfigMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+	if patchConfigMap.Data[key.SecretConfigPatchKey] != patch {
+		if patchConfigMap.Labels == nil {
+			patchConfigMap.Labels = map[string]string{}
+		}
+		patchConfigMap.Labels[key.SecretConfigPatchLabel] = "true"
+		patchConfigMap.Data = map[string]string{key.SecretConfigPatchKey: patch}
+		if err := controllerutil.SetControllerReference(target.HelmRelease, patchConfigMap, r.Scheme); err != nil {
+			return microerror.Mask(err)
+		}
+		if patchConfigMap.ResourceVersion == "" {
+			err = r.Create(ctx, patchConfigMap)
+		} else {
+			err = r.Update(ctx, patchConfigMap)
+		}
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		log.Info(fmt.Sprintf("Wrote secret config patch configmap %s.", patchConfigMap.Name))
+	}
+
+	r.Recorder.Event(target.HelmRelease, corev1.EventTypeWarning, missingSecretConfigReason,
+		fmt.Sprintf("HelmRelease does not reference dex config secret %s in spec.valuesFrom. Add the following patch to the Flux Kustomization managing it (also available in configmap %s):\n%s",
+			secretName, patchConfigMap.Name, patch))
+	return nil
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const credentialRotationFailedReason = "CredentialRotationFailed"
+
+// SelfRenewer periodically renews the credentials of dex-operator itself in the identity providers.
+// It renews them for the management cluster dex, no matter if it is deployed as HelmRelease or App CR.
+// It runs on the leader only.
+type SelfRenewer struct {
+	client.Client
+	Log                 logr.Logger
+	Recorder            record.EventRecorder
+	Scheme              *runtime.Scheme
+	BaseDomain          string
+	IssuerAddress       string
+	ManagementCluster   string
+	ProviderCredentials string
+	Interval            time.Duration
+	// ManagementClusterDex is the name of the HelmRelease or App CR of the management cluster dex,
+	// giantswarm/dex-app if unset.
+	ManagementClusterDex types.NamespacedName
+}
+
+func (r *SelfRenewer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.renew(ctx); err != nil {
+			r.Log.Error(err, "Service credential rotation failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *SelfRenewer) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *SelfRenewer) renew(ctx context.Context) error {
+	target, err := r.getManagementClusterDexTarget(ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if target == nil {
+		// without a target the credentials of dex-operator expire unnoticed, so this is an error
+		r.Log.Error(microerror.Maskf(notFoundError, "management cluster dex %s", r.getManagementClusterDexName()), "Found no management cluster dex HelmRelease or App, skipping self-renewal.")
+		SelfRenewalTargetMissing.Set(1)
+		return nil
+	}
+	SelfRenewalTargetMissing.Set(0)
+	log := r.Log.WithValues(target.GetTargetType(), target.GetNamespacedName())
+
+	paused, err := isReconciliationPaused(ctx, r.Client, target)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if paused {
+		log.Info(fmt.Sprintf("Reconciliation is paused by annotation %s, skipping self-renewal.", key.PausedAnnotation))
+		return nil
+	}
+
+	providers, err := newProviders(r.ProviderCredentials, log, r.ManagementCluster, false)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	idpService, err := idp.New(idp.Config{
+		Log:                            log,
+		Client:                         r.Client,
+		Target:                         target,
+		Providers:                      providers,
+		ManagementClusterBaseDomain:    r.BaseDomain,
+		ManagementClusterIssuerAddress: r.IssuerAddress,
+		ManagementClusterName:          r.ManagementCluster,
+		Owner:                          target.GetObject(),
+		Scheme:                         r.Scheme,
+		Recorder:                       r.Recorder,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	if err := runReconcilePhase(ctx, target.GetTargetType(), reconcilePhaseSelfRenewal, idpService.CheckAndRotateServiceCredentials); err != nil {
+		// Emit a warning event so users can monitor rotation failures
+		r.Recorder.Event(target.GetObject(), corev1.EventTypeWarning, credentialRotationFailedReason,
+			"Failed to rotate service credentials")
+		return microerror.Mask(err)
+	}
+	return nil
+}
+
+func (r *SelfRenewer) getManagementClusterDexName() types.NamespacedName {
+	if r.ManagementClusterDex.Name == "" {
+		return key.MCDexDefaultNamespacedName()
+	}
+	return r.ManagementClusterDex
+}
+
+// getManagementClusterDexTarget returns the management cluster dex. Like in reconciliation,
+// the HelmRelease takes priority over an App CR with the same name. It returns nil if neither exists.
+func (r *SelfRenewer) getManagementClusterDexTarget(ctx context.Context) (dextarget.DexTarget, error) {
+	hr := &helmv2.HelmRelease{}
+	nn := r.getManagementClusterDexName()
+	err := r.Get(ctx, nn, hr)
+	if err == nil {
+		return dextarget.NewHelmReleaseTarget(hr), nil
+	}
+	// If the HelmRelease CRD is not installed, the management cluster dex can only be an App CR
+	if !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return nil, microerror.Mask(err)
+	}
+
+	app := &v1alpha1.App{}
+	err = r.Get(ctx, nn, app)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	return dextarget.NewAppTarget(app), nil
+}
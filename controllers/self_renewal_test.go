@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestGetManagementClusterDexTarget(t *testing.T) {
+	mcApp := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.MCDexAppDefaultName,
+			Namespace: key.MCDexAppDefaultNamespace,
+		},
+	}
+	mcHelmRelease := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.MCDexHelmReleaseDefaultName,
+			Namespace: key.MCDexAppDefaultNamespace,
+		},
+	}
+	wcApp := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.MCDexAppDefaultName,
+			Namespace: "org-example",
+		},
+	}
+
+	customHelmRelease := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dex",
+			Namespace: "flux-giantswarm",
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		dex          types.NamespacedName
+		objects      []client.Object
+		expectedType string
+	}{
+		{
+			name:    "case 0: no management cluster dex",
+			objects: []client.Object{wcApp},
+		},
+		{
+			name:         "case 1: management cluster dex app",
+			objects:      []client.Object{mcApp, wcApp},
+			expectedType: "App",
+		},
+		{
+			name:         "case 2: management cluster dex helmrelease",
+			objects:      []client.Object{mcHelmRelease},
+			expectedType: "HelmRelease",
+		},
+		{
+			name:         "case 3: helmrelease takes priority over app",
+			objects:      []client.Object{mcApp, mcHelmRelease},
+			expectedType: "HelmRelease",
+		},
+		{
+			name:         "case 4: configured management cluster dex",
+			dex:          types.NamespacedName{Name: "dex", Namespace: "flux-giantswarm"},
+			objects:      []client.Object{mcApp, customHelmRelease},
+			expectedType: "HelmRelease",
+		},
+		{
+			name:    "case 5: configured management cluster dex not found",
+			dex:     types.NamespacedName{Name: "dex", Namespace: "flux-giantswarm"},
+			objects: []client.Object{mcApp, mcHelmRelease},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = v1alpha1.AddToScheme(scheme)
+			_ = helmv2.AddToScheme(scheme)
+
+			r := &SelfRenewer{
+				Client:               fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build(),
+				Log:                  ctrl.Log.WithName("test"),
+				ManagementClusterDex: tc.dex,
+			}
+
+			target, err := r.getManagementClusterDexTarget(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectedType == "" {
+				if target != nil {
+					t.Fatalf("expected no target, got %s %s", target.GetTargetType(), target.GetNamespacedName())
+				}
+				return
+			}
+			if target == nil {
+				t.Fatalf("expected %s target, got none", tc.expectedType)
+			}
+			if tc.dex.Name != "" && target.GetNamespacedName() != tc.dex {
+				t.Fatalf("expected target %s, got %s", tc.dex, target.GetNamespacedName())
+			}
+			if target.GetTargetType() != tc.expectedType {
+				t.Fatalf("expected %s target, got %s", tc.expectedType, target.GetTargetType())
+			}
+		})
+	}
+}
+
+func TestRenewWithoutManagementClusterDex(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = helmv2.AddToScheme(scheme)
+
+	r := &SelfRenewer{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:    ctrl.Log.WithName("test"),
+	}
+	if err := r.renew(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value := testutil.ToFloat64(SelfRenewalTargetMissing); value != 1 {
+		t.Fatalf("expected %s to be 1, got %v", "dex_operator_self_renewal_target_missing", value)
+	}
+}
@@ -98,6 +98,7 @@ var _ = BeforeSuite(func() {
 		ManagementCluster:        "something",
 		GiantswarmWriteAllGroups: []string{"group_a", "group_b"},
 		Log:                      ctrl.Log.WithName("controllers").WithName("App"),
+		Recorder:                 k8sManager.GetEventRecorderFor("app-controller"),
 		Client:                   k8sManager.GetClient(),
 		Scheme:                   k8sManager.GetScheme(),
 		LabelSelector:            key.DexLabelSelector(),
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+// dexTargetLister lists all dex targets of a reconciler.
+type dexTargetLister func(ctx context.Context) ([]dextarget.DexTarget, error)
+
+// watchAuthSources adds watches on the role bindings, cluster role bindings, auth bindings configmaps and
+// CAPI clusters which the auth config of workload cluster dex targets is derived from. Only changes which can affect the
+// auth config are admitted, since every event reconciles all dex targets of an organization.
+func watchAuthSources(b *builder.Builder, mgr ctrl.Manager, list dexTargetLister, managementCluster string, log logr.Logger) (*builder.Builder, error) {
+	b = b.
+		Watches(&rbacv1.RoleBinding{},
+			handler.EnqueueRequestsFromMapFunc(roleBindingToDexTargets(list, managementCluster, log)),
+			builder.WithPredicates(organizationNamespacePredicate())).
+		Watches(&rbacv1.ClusterRoleBinding{},
+			handler.EnqueueRequestsFromMapFunc(clusterRoleBindingToDexTargets(list, managementCluster, log)),
+			builder.WithPredicates(organizationLabelPredicate())).
+		Watches(&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(authBindingsToDexTargets(list, managementCluster, log)),
+			builder.WithPredicates(organizationNamespacePredicate(), authBindingsPredicate()))
+
+	hasClusters, err := hasClusterAPI(mgr)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if hasClusters {
+		b = b.Watches(&capi.Cluster{},
+			handler.EnqueueRequestsFromMapFunc(clusterToDexTargets(list, managementCluster, log)),
+			builder.WithPredicates(controlPlaneEndpointChangedPredicate()))
+	} else {
+		log.Info("Cluster API is not installed, not watching clusters.")
+	}
+	return b, nil
+}
+
+// organizationNamespacePredicate admits objects in organization namespaces.
+func organizationNamespacePredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return key.IsOrganizationNamespace(o.GetNamespace())
+	})
+}
+
+// organizationLabelPredicate admits objects labelled with an organization.
+func organizationLabelPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetLabels()[label.Organization] != ""
+	})
+}
+
+// authBindingsPredicate admits the configmaps holding the auth bindings of an organization.
+func authBindingsPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetName() == key.AuthBindingsConfigMapName
+	})
+}
+
+// controlPlaneEndpointChangedPredicate admits creations and deletions of clusters, but updates only
+// if the control plane endpoint changed. Status updates of clusters are frequent and do not affect dex targets.
+func controlPlaneEndpointChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCluster, ok := e.ObjectOld.(*capi.Cluster)
+			if !ok {
+				return false
+			}
+			newCluster, ok := e.ObjectNew.(*capi.Cluster)
+			if !ok {
+				return false
+			}
+			return oldCluster.Spec.ControlPlaneEndpoint != newCluster.Spec.ControlPlaneEndpoint
+		},
+	}
+}
+
+// roleBindingToDexTargets maps role bindings to the workload cluster dex targets of the organization
+// whose namespace they are in, so that auth configmaps follow RBAC changes promptly.
+func roleBindingToDexTargets(list dexTargetLister, managementCluster string, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		return authDexTargetRequests(ctx, list, managementCluster, log, o.GetNamespace(), "")
+	}
+}
+
+// authBindingsToDexTargets maps the auth bindings configmap of an organization to the workload cluster
+// dex targets of that organization.
+func authBindingsToDexTargets(list dexTargetLister, managementCluster string, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		return authDexTargetRequests(ctx, list, managementCluster, log, o.GetNamespace(), "")
+	}
+}
+
+// clusterRoleBindingToDexTargets maps cluster role bindings labelled with an organization
+// to the workload cluster dex targets of that organization.
+func clusterRoleBindingToDexTargets(list dexTargetLister, managementCluster string, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		organization := o.GetLabels()[label.Organization]
+		if organization == "" {
+			return nil
+		}
+		return authDexTargetRequests(ctx, list, managementCluster, log, key.GetOrganizationNamespace("", organization), "")
+	}
+}
+
+// clusterToDexTargets maps CAPI clusters to the dex targets deployed to them.
+func clusterToDexTargets(list dexTargetLister, managementCluster string, log logr.Logger) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		return authDexTargetRequests(ctx, list, managementCluster, log, o.GetNamespace(), o.GetName())
+	}
+}
+
+// authDexTargetRequests returns requests for all workload cluster dex targets in the organization namespace.
+// If cluster is set, only dex targets of that cluster are returned.
+func authDexTargetRequests(ctx context.Context, list dexTargetLister, managementCluster string, log logr.Logger, organizationNamespace string, cluster string) []reconcile.Request {
+	targets, err := list(ctx)
+	if err != nil {
+		log.Error(err, "Failed to list dex targets for watch event")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, target := range targets {
+		targetCluster := target.GetClusterLabel()
+		if targetCluster == "" || targetCluster == managementCluster {
+			continue
+		}
+		if cluster != "" && targetCluster != cluster {
+			continue
+		}
+		nn := target.GetNamespacedName()
+		if key.GetOrganizationNamespace(nn.Namespace, target.GetOrganizationLabel()) != organizationNamespace {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: nn.Name, Namespace: nn.Namespace}})
+	}
+	return requests
+}
+
+// hasClusterAPI checks whether the CAPI Cluster kind is served. Without it the cluster watch is skipped.
+func hasClusterAPI(mgr ctrl.Manager) (bool, error) {
+	_, err := mgr.GetRESTMapper().RESTMapping(schema.GroupKind{Group: capi.GroupVersion.Group, Kind: "Cluster"}, capi.GroupVersion.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, microerror.Mask(err)
+	}
+	return true, nil
+}
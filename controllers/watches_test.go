@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestDexTargetMapFuncs(t *testing.T) {
+	app := func(name string, namespace string, cluster string, organization string) dextarget.DexTarget {
+		return dextarget.NewAppTarget(&v1alpha1.App{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{label.Cluster: cluster, label.Organization: organization},
+			},
+		})
+	}
+	targets := []dextarget.DexTarget{
+		app("wc1-dex-app", "org-example", "wc1", "example"),
+		app("wc2-dex-app", "org-example", "wc2", "example"),
+		app("wc3-dex-app", "wc3", "wc3", "example"),
+		app("other-dex-app", "org-other", "other", "other"),
+		app("dex-app", "giantswarm", "mc", ""),
+	}
+	list := func(ctx context.Context) ([]dextarget.DexTarget, error) {
+		return targets, nil
+	}
+	log := ctrl.Log.WithName("test")
+
+	testCases := []struct {
+		name     string
+		mapFunc  handler.MapFunc
+		object   client.Object
+		expected []string
+	}{
+		{
+			name:     "case 0: role binding in organization namespace",
+			mapFunc:  roleBindingToDexTargets(list, "mc", log),
+			object:   &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "org-example"}},
+			expected: []string{"org-example/wc1-dex-app", "org-example/wc2-dex-app", "wc3/wc3-dex-app"},
+		},
+		{
+			name:     "case 1: role binding in other namespace",
+			mapFunc:  roleBindingToDexTargets(list, "mc", log),
+			object:   &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "default"}},
+			expected: []string{},
+		},
+		{
+			name:    "case 2: cluster role binding of organization",
+			mapFunc: clusterRoleBindingToDexTargets(list, "mc", log),
+			object: &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{
+				Name:   "org-other-read",
+				Labels: map[string]string{label.Organization: "other"},
+			}},
+			expected: []string{"org-other/other-dex-app"},
+		},
+		{
+			name:     "case 3: cluster role binding without organization",
+			mapFunc:  clusterRoleBindingToDexTargets(list, "mc", log),
+			object:   &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"}},
+			expected: []string{},
+		},
+		{
+			name:     "case 4: cluster",
+			mapFunc:  clusterToDexTargets(list, "mc", log),
+			object:   &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "wc3", Namespace: "org-example"}},
+			expected: []string{"wc3/wc3-dex-app"},
+		},
+		{
+			name:     "case 5: management cluster",
+			mapFunc:  clusterToDexTargets(list, "mc", log),
+			object:   &capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "mc", Namespace: "org-giantswarm"}},
+			expected: []string{},
+		},
+		{
+			name:     "case 6: auth bindings of organization",
+			mapFunc:  authBindingsToDexTargets(list, "mc", log),
+			object:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.AuthBindingsConfigMapName, Namespace: "org-other"}},
+			expected: []string{"org-other/other-dex-app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := tc.mapFunc(context.Background(), tc.object)
+			result := []string{}
+			for _, request := range requests {
+				result = append(result, request.String())
+			}
+			sort.Strings(result)
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAuthSourcePredicates(t *testing.T) {
+	cluster := func(host string, phase string) *capi.Cluster {
+		return &capi.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "wc1", Namespace: "org-example"},
+			Spec:       capi.ClusterSpec{ControlPlaneEndpoint: capi.APIEndpoint{Host: host, Port: 6443}},
+			Status:     capi.ClusterStatus{Phase: phase},
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		predicate predicate.Predicate
+		event     func(p predicate.Predicate) bool
+		expected  bool
+	}{
+		{
+			name:      "case 0: role binding in organization namespace",
+			predicate: organizationNamespacePredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "org-example"}}})
+			},
+			expected: true,
+		},
+		{
+			name:      "case 1: role binding in other namespace",
+			predicate: organizationNamespacePredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "admins", Namespace: "kube-system"}}})
+			},
+		},
+		{
+			name:      "case 2: cluster role binding without organization",
+			predicate: organizationLabelPredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"}}})
+			},
+		},
+		{
+			name:      "case 3: cluster created",
+			predicate: controlPlaneEndpointChangedPredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: cluster("", "Pending")})
+			},
+			expected: true,
+		},
+		{
+			name:      "case 4: cluster status changed",
+			predicate: controlPlaneEndpointChangedPredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Update(event.UpdateEvent{ObjectOld: cluster("api.wc1.example.com", "Provisioning"), ObjectNew: cluster("api.wc1.example.com", "Provisioned")})
+			},
+		},
+		{
+			name:      "case 5: cluster control plane endpoint changed",
+			predicate: controlPlaneEndpointChangedPredicate(),
+			event: func(p predicate.Predicate) bool {
+				return p.Update(event.UpdateEvent{ObjectOld: cluster("", "Provisioning"), ObjectNew: cluster("api.wc1.example.com", "Provisioning")})
+			},
+			expected: true,
+		},
+		{
+			name:      "case 6: auth bindings configmap",
+			predicate: predicate.And(organizationNamespacePredicate(), authBindingsPredicate()),
+			event: func(p predicate.Predicate) bool {
+				return p.Update(event.UpdateEvent{
+					ObjectOld: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.AuthBindingsConfigMapName, Namespace: "org-example"}},
+					ObjectNew: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.AuthBindingsConfigMapName, Namespace: "org-example"}},
+				})
+			},
+			expected: true,
+		},
+		{
+			name:      "case 7: other configmap in organization namespace",
+			predicate: predicate.And(organizationNamespacePredicate(), authBindingsPredicate()),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "wc1-auth-config", Namespace: "org-example"}}})
+			},
+		},
+		{
+			name:      "case 8: auth bindings configmap outside of organization namespaces",
+			predicate: predicate.And(organizationNamespacePredicate(), authBindingsPredicate()),
+			event: func(p predicate.Predicate) bool {
+				return p.Create(event.CreateEvent{Object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: key.AuthBindingsConfigMapName, Namespace: "default"}}})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := tc.event(tc.predicate); result != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, result)
+			}
+		})
+	}
+}
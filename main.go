@@ -17,9 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -27,6 +30,7 @@ import (
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -38,7 +42,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/giantswarm/dex-operator/controllers"
+	"github.com/giantswarm/dex-operator/pkg/auth"
+	"github.com/giantswarm/dex-operator/pkg/idp"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -57,6 +65,15 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == missingSecretConfigCommand {
+		// the logger is not set up for subcommands, so errors are printed directly
+		if err := runMissingSecretConfigCommand(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to list HelmReleases missing the dex config secret: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var (
 		baseDomain               string
 		issuerAddress            string
@@ -68,6 +85,25 @@ func main() {
 		giantswarmWriteAllGroups string
 		customerWriteAllGroups   string
 		enableSelfRenewal        bool
+		selfRenewalInterval      time.Duration
+		selfRenewalDexName       string
+		selfRenewalDexNamespace  string
+		dryRun                   bool
+		deletionPolicy           string
+		retentionSweepInterval   time.Duration
+		retentionNamespace       string
+		enableAppMigration       bool
+		authBindingsFile         string
+		authRoleMappingsFile     string
+		authAPIEndpointSources   string
+		authOIDCConfigFile       string
+		authKubeconfigClientID   string
+		staticClientsFile        string
+		rotationWindows          string
+		rotationJitter           time.Duration
+		maxConcurrentRotations   int
+		tracingEndpoint          string
+		tracingSampleRatio       float64
 	)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&idpCredentials, "idp-credentials-file", "/home/.idp/credentials", "The location of the idp credentials file.")
@@ -82,6 +118,25 @@ func main() {
 	flag.StringVar(&giantswarmWriteAllGroups, "giantswarm-write-all-groups", "", "Comma separated list of giantswarm admin groups.")
 	flag.StringVar(&customerWriteAllGroups, "customer-write-all-groups", "", "Comma separated list of customer admin groups.")
 	flag.BoolVar(&enableSelfRenewal, "enable-self-renewal", false, "Enable automatic self-renewal of operator credentials")
+	flag.DurationVar(&selfRenewalInterval, "self-renewal-interval", 5*time.Minute, "Interval in which the operator credentials are checked for renewal.")
+	flag.StringVar(&selfRenewalDexName, "self-renewal-dex-name", key.MCDexAppDefaultName, "Name of the HelmRelease or App CR of the management cluster dex whose credentials are renewed.")
+	flag.StringVar(&selfRenewalDexNamespace, "self-renewal-dex-namespace", key.MCDexAppDefaultNamespace, "Namespace of the HelmRelease or App CR of the management cluster dex whose credentials are renewed.")
+	flag.BoolVar(&dryRun, "dry-run", false, "Compute the desired dex configuration for every target and report changes without writing to identity providers or Kubernetes.")
+	flag.StringVar(&deletionPolicy, "deletion-policy", idp.DeletionPolicyDelete, "What happens to identity provider app registrations when their dex target is deleted. One of Delete, Retain or RetainFor:<duration>.")
+	flag.StringVar(&retentionNamespace, "retention-namespace", key.MCDexAppDefaultNamespace, "Namespace in which retained app registrations are recorded, usually the namespace of dex-operator.")
+	flag.DurationVar(&retentionSweepInterval, "retention-sweep-interval", time.Hour, "Interval in which expired retained app registrations are removed.")
+	flag.BoolVar(&enableAppMigration, "enable-app-migration", false, "Hand over dex config secret and app registrations from App CRs to HelmReleases with the same name and release the App CRs.")
+	flag.StringVar(&authBindingsFile, "auth-bindings-file", "", "The location of a file with additional auth bindings for all workload clusters.")
+	flag.StringVar(&authRoleMappingsFile, "auth-role-mappings-file", "", "The location of a file mapping organization role bindings to auth bindings. Defaults to mapping cluster-admin and write-all-* to cluster-admin and read-all-* to view.")
+	flag.StringVar(&authOIDCConfigFile, "auth-oidc-config-file", "", "The location of a file with the OIDC client ID, claims, prefixes and claim validation rules rendered into the auth config of workload clusters.")
+	flag.StringVar(&staticClientsFile, "static-clients-file", "", "The location of a file with dex static clients added to every dex target with generated client secrets.")
+	flag.StringVar(&rotationWindows, "rotation-windows", "", "Semicolon separated list of maintenance windows in UTC in which client secrets are rotated, each a cron expression followed by a duration, e.g. '0 2 * * 1-4 4h'. Rotations are allowed at any time if empty.")
+	flag.DurationVar(&rotationJitter, "rotation-jitter", 0, "Maximum delay added per dex target to the rotation of client secrets to spread rotations over time.")
+	flag.IntVar(&maxConcurrentRotations, "max-concurrent-rotations", 0, "Maximum number of dex targets rotating client secrets at the same time. Unlimited if zero.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "", "URL of an OTLP/HTTP endpoint traces of reconciliations and identity provider requests are exported to, e.g. http://otel-collector:4318/v1/traces. Tracing is disabled if empty.")
+	flag.Float64Var(&tracingSampleRatio, "tracing-sample-ratio", 1, "Ratio of reconciliations which are traced, between 0 and 1.")
+	flag.StringVar(&authKubeconfigClientID, "auth-kubeconfig-client-id", "", "ID of the public static client used by the oidc-login kubeconfig configmap generated for every workload cluster. The kubeconfig is not generated if empty.")
+	flag.StringVar(&authAPIEndpointSources, "auth-api-endpoint-sources", "annotation,capi,cluster-values", "Comma separated list of sources asked in order for the API server port of workload clusters. One of annotation, cluster-values[:<path>], capi or <group>/<version>/<kind>:<path> of a hosted control plane resource.")
 	opts := zap.Options{
 		Development: false,
 		TimeEncoder: zapcore.RFC3339TimeEncoder,
@@ -100,6 +155,100 @@ func main() {
 		customerGroups = strings.Split(customerWriteAllGroups, ",")
 	}
 
+	var authBindings []auth.Binding
+	if authBindingsFile != "" {
+		var err error
+		authBindings, err = auth.ReadBindings(authBindingsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read auth bindings")
+			os.Exit(1)
+		}
+	}
+
+	var authRoleMappings []auth.RoleMapping
+	if authRoleMappingsFile != "" {
+		var err error
+		authRoleMappings, err = auth.ReadRoleMappings(authRoleMappingsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read auth role mappings")
+			os.Exit(1)
+		}
+	}
+
+	var staticClients []idp.StaticClient
+	if staticClientsFile != "" {
+		var err error
+		staticClients, err = idp.ReadStaticClients(staticClientsFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read static clients")
+			os.Exit(1)
+		}
+	}
+	if authKubeconfigClientID != "" {
+		if err := idp.ValidatePublicStaticClient(staticClients, authKubeconfigClientID); err != nil {
+			setupLog.Error(err, "invalid auth kubeconfig client")
+			os.Exit(1)
+		}
+	}
+
+	var authOIDC *auth.OIDCConfig
+	if authOIDCConfigFile != "" {
+		oidc, err := auth.ReadOIDCConfig(authOIDCConfigFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read auth oidc config")
+			os.Exit(1)
+		}
+		authOIDC = &oidc
+	}
+
+	apiEndpointSources, err := auth.ParseAPIEndpointSources(authAPIEndpointSources)
+	if err != nil {
+		setupLog.Error(err, "invalid auth api endpoint sources")
+		os.Exit(1)
+	}
+
+	policy, err := idp.ParseDeletionPolicy(deletionPolicy)
+	if err != nil {
+		setupLog.Error(err, "invalid deletion policy")
+		os.Exit(1)
+	}
+
+	var rotationPolicy *rotation.Policy
+	{
+		windows, err := rotation.ParseWindows(rotationWindows)
+		if err != nil {
+			setupLog.Error(err, "invalid rotation windows")
+			os.Exit(1)
+		}
+		rotationPolicy, err = rotation.NewPolicy(rotation.Config{
+			Windows:       windows,
+			MaxJitter:     rotationJitter,
+			MaxConcurrent: maxConcurrentRotations,
+		})
+		if err != nil {
+			setupLog.Error(err, "invalid rotation policy")
+			os.Exit(1)
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    tracingEndpoint,
+		SampleRatio: tracingSampleRatio,
+		ServiceName: "dex-operator",
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
+	// A dry-run instance is meant to run side-by-side with the production instance,
+	// so it must not compete for the same leader election lease.
+	leaderElectionID := "bf139543.giantswarm"
+	if dryRun {
+		leaderElectionID = "dry-run.bf139543.giantswarm"
+		setupLog.Info("running in dry-run mode, no changes will be applied")
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: server.Options{
@@ -110,7 +259,7 @@ func main() {
 		}),
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "bf139543.giantswarm",
+		LeaderElectionID:       leaderElectionID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -141,7 +290,17 @@ func main() {
 		ProviderCredentials:      idpCredentials,
 		GiantswarmWriteAllGroups: gsGroups,
 		CustomerWriteAllGroups:   customerGroups,
-		EnableSelfRenewal:        enableSelfRenewal,
+		AuthBindings:             authBindings,
+		AuthRoleMappings:         authRoleMappings,
+		AuthAPIEndpointSources:   apiEndpointSources,
+		AuthOIDC:                 authOIDC,
+		AuthKubeconfigClientID:   authKubeconfigClientID,
+		StaticClients:            staticClients,
+		RotationPolicy:           rotationPolicy,
+		DryRun:                   dryRun,
+		DeletionPolicy:           policy,
+		RetentionNamespace:       retentionNamespace,
+		EnableAppMigration:       enableAppMigration,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "App")
 		os.Exit(1)
@@ -160,11 +319,55 @@ func main() {
 		ProviderCredentials:      idpCredentials,
 		GiantswarmWriteAllGroups: gsGroups,
 		CustomerWriteAllGroups:   customerGroups,
-		EnableSelfRenewal:        enableSelfRenewal,
+		AuthBindings:             authBindings,
+		AuthRoleMappings:         authRoleMappings,
+		AuthAPIEndpointSources:   apiEndpointSources,
+		AuthOIDC:                 authOIDC,
+		AuthKubeconfigClientID:   authKubeconfigClientID,
+		StaticClients:            staticClients,
+		RotationPolicy:           rotationPolicy,
+		DryRun:                   dryRun,
+		DeletionPolicy:           policy,
+		RetentionNamespace:       retentionNamespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HelmRelease")
 		os.Exit(1)
 	}
+
+	// Retention sweeper
+	if err = mgr.Add(&controllers.RetentionSweeper{
+		Client:              mgr.GetClient(),
+		Log:                 ctrl.Log.WithName("controllers").WithName("RetentionSweeper"),
+		ManagementCluster:   managementCluster,
+		ProviderCredentials: idpCredentials,
+		Namespace:           retentionNamespace,
+		Interval:            retentionSweepInterval,
+		DryRun:              dryRun,
+	}); err != nil {
+		setupLog.Error(err, "unable to add retention sweeper")
+		os.Exit(1)
+	}
+	// Self-renewal of the operator credentials, independent of the kind of the management cluster dex
+	if enableSelfRenewal && !dryRun {
+		if err = mgr.Add(&controllers.SelfRenewer{
+			Client:              mgr.GetClient(),
+			Log:                 ctrl.Log.WithName("controllers").WithName("SelfRenewer"),
+			Recorder:            mgr.GetEventRecorderFor("self-renewer"),
+			Scheme:              mgr.GetScheme(),
+			BaseDomain:          baseDomain,
+			IssuerAddress:       issuerAddress,
+			ManagementCluster:   managementCluster,
+			ProviderCredentials: idpCredentials,
+			Interval:            selfRenewalInterval,
+			ManagementClusterDex: types.NamespacedName{
+				Name:      selfRenewalDexName,
+				Namespace: selfRenewalDexNamespace,
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to add self-renewer")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -177,7 +380,16 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+
+	// flush the spans of the last reconciliations
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to shut down tracing")
+	}
+	cancel()
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const missingSecretConfigCommand = "list-missing-secret-config"
+
+// runMissingSecretConfigCommand lists all Flux-managed dex HelmReleases which do not reference
+// their dex config secret, optionally together with the kustomize patch fixing them.
+func runMissingSecretConfigCommand(args []string) error {
+	var printPatches bool
+	flags := flag.NewFlagSet(missingSecretConfigCommand, flag.ExitOnError)
+	flags.BoolVar(&printPatches, "patch", false, "Print the kustomize patch for each HelmRelease.")
+	if err := flags.Parse(args); err != nil {
+		return microerror.Mask(err)
+	}
+
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+
+	targets, err := dextarget.ListHelmReleasesMissingSecretConfig(context.Background(), c)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	return printMissingSecretConfig(os.Stdout, targets, printPatches)
+}
+
+func printMissingSecretConfig(w io.Writer, targets []*dextarget.HelmReleaseTarget, printPatches bool) error {
+	for _, target := range targets {
+		nn := target.GetNamespacedName()
+		if !printPatches {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", nn.Namespace, nn.Name); err != nil {
+				return microerror.Mask(err)
+			}
+			continue
+		}
+		patch, err := target.GetSecretConfigPatch(key.GetDexConfigName(nn.Name))
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if _, err := fmt.Fprintf(w, "# %s/%s\n%s", nn.Namespace, nn.Name, patch); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	return nil
+}
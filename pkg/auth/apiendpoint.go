@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	APIEndpointSourceAnnotation    = "annotation"
+	APIEndpointSourceClusterValues = "cluster-values"
+	APIEndpointSourceCAPI          = "capi"
+
+	// DefaultClusterValuesAPIServerPortPath is the path of the API server port in the cluster values.
+	DefaultClusterValuesAPIServerPortPath = "kubernetes.api.port"
+)
+
+// APIEndpointCluster identifies the workload cluster whose API endpoint is looked up.
+type APIEndpointCluster struct {
+	Name                  string
+	OrganizationNamespace string
+	Target                dextarget.DexTarget
+}
+
+// APIEndpoint is the API server endpoint of a workload cluster. Host is empty if the source does not know it.
+type APIEndpoint struct {
+	Host string
+	Port int
+}
+
+// APIEndpointSource looks up the API server endpoint of a workload cluster.
+type APIEndpointSource interface {
+	// Name identifies the source in logs and events.
+	Name() string
+	// GetAPIEndpoint returns false if the source holds no API endpoint data for the cluster.
+	GetAPIEndpoint(ctx context.Context, c client.Client, cluster APIEndpointCluster) (APIEndpoint, bool, error)
+}
+
+// DefaultAPIEndpointSources are used if no API endpoint sources are configured.
+func DefaultAPIEndpointSources() []APIEndpointSource {
+	return []APIEndpointSource{
+		AnnotationAPIEndpointSource{},
+		CAPIClusterAPIEndpointSource{},
+		ClusterValuesAPIEndpointSource{Path: DefaultClusterValuesAPIServerPortPath},
+	}
+}
+
+// ParseAPIEndpointSources parses a comma separated list of API endpoint sources.
+// Valid sources are annotation, cluster-values, cluster-values:<path>, capi and
+// <group>/<version>/<kind>:<path> for hosted control plane resources named after the cluster.
+func ParseAPIEndpointSources(s string) ([]APIEndpointSource, error) {
+	sources := []APIEndpointSource{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, path, _ := strings.Cut(entry, ":")
+		switch {
+		case name == APIEndpointSourceAnnotation && path == "":
+			sources = append(sources, AnnotationAPIEndpointSource{})
+		case name == APIEndpointSourceClusterValues:
+			if path == "" {
+				path = DefaultClusterValuesAPIServerPortPath
+			}
+			sources = append(sources, ClusterValuesAPIEndpointSource{Path: path})
+		case name == APIEndpointSourceCAPI && path == "":
+			sources = append(sources, CAPIClusterAPIEndpointSource{})
+		case strings.Count(name, "/") == 2 && path != "":
+			parts := strings.Split(name, "/")
+			gvk := schema.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]}
+			if gvk.Version == "" || gvk.Kind == "" {
+				return nil, microerror.Maskf(invalidConfigError, "invalid api endpoint source %s", entry)
+			}
+			sources = append(sources, HostedControlPlaneAPIEndpointSource{GroupVersionKind: gvk, Path: path})
+		default:
+			return nil, microerror.Maskf(invalidConfigError, "invalid api endpoint source %s", entry)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, microerror.Maskf(invalidConfigError, "no api endpoint sources given")
+	}
+	return sources, nil
+}
+
+// AnnotationAPIEndpointSource reads the API server port and optionally the host from annotations on the dex target.
+type AnnotationAPIEndpointSource struct{}
+
+func (AnnotationAPIEndpointSource) Name() string {
+	return APIEndpointSourceAnnotation
+}
+
+func (AnnotationAPIEndpointSource) GetAPIEndpoint(ctx context.Context, c client.Client, cluster APIEndpointCluster) (APIEndpoint, bool, error) {
+	value, ok := cluster.Target.GetAnnotations()[key.APIServerPortAnnotation]
+	if !ok {
+		return APIEndpoint{}, false, nil
+	}
+	port, err := parsePort(value)
+	if err != nil {
+		return APIEndpoint{}, false, microerror.Maskf(invalidConfigError, "annotation %s: %s", key.APIServerPortAnnotation, err)
+	}
+	return APIEndpoint{Host: cluster.Target.GetAnnotations()[key.APIServerHostAnnotation], Port: port}, true, nil
+}
+
+// ClusterValuesAPIEndpointSource reads the API server port from the cluster values configmap of the dex target.
+// The host is derived from the base domain in the cluster values.
+type ClusterValuesAPIEndpointSource struct {
+	// Path is the dot separated path of the port in the cluster values.
+	Path string
+}
+
+func (s ClusterValuesAPIEndpointSource) Name() string {
+	return fmt.Sprintf("%s:%s", APIEndpointSourceClusterValues, s.Path)
+}
+
+func (s ClusterValuesAPIEndpointSource) GetAPIEndpoint(ctx context.Context, c client.Client, cluster APIEndpointCluster) (APIEndpoint, bool, error) {
+	if !cluster.Target.HasClusterValuesConfig() {
+		return APIEndpoint{}, false, nil
+	}
+	name, namespace := cluster.Target.GetClusterValuesConfigMapRef()
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return APIEndpoint{}, false, nil
+		}
+		return APIEndpoint{}, false, microerror.Mask(err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(cm.Data[key.ValuesConfigMapKey]), &values); err != nil {
+		return APIEndpoint{}, false, microerror.Maskf(invalidConfigError, "cluster values %s/%s: %s", namespace, name, err)
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(values, strings.Split(s.Path, ".")...)
+	if err != nil || !found {
+		return APIEndpoint{}, false, nil
+	}
+	port, err := parsePort(value)
+	if err != nil {
+		return APIEndpoint{}, false, microerror.Maskf(invalidConfigError, "cluster values %s/%s %s: %s", namespace, name, s.Path, err)
+	}
+	endpoint := APIEndpoint{Port: port}
+	if baseDomain, ok := values[key.BaseDomainKey].(string); ok && baseDomain != "" {
+		endpoint.Host = key.GetAPIServerHost(baseDomain)
+	}
+	return endpoint, true, nil
+}
+
+// CAPIClusterAPIEndpointSource reads the API server endpoint from the control plane endpoint of the CAPI cluster.
+type CAPIClusterAPIEndpointSource struct{}
+
+func (CAPIClusterAPIEndpointSource) Name() string {
+	return APIEndpointSourceCAPI
+}
+
+func (CAPIClusterAPIEndpointSource) GetAPIEndpoint(ctx context.Context, c client.Client, cluster APIEndpointCluster) (APIEndpoint, bool, error) {
+	capiCluster := &capi.Cluster{}
+	found, err := getClusterObject(ctx, c, cluster, capiCluster)
+	if err != nil || !found {
+		return APIEndpoint{}, false, err
+	}
+	endpoint := APIEndpoint{
+		Host: capiCluster.Spec.ControlPlaneEndpoint.Host,
+		Port: int(capiCluster.Spec.ControlPlaneEndpoint.Port),
+	}
+	return endpoint, endpoint.Port != 0, nil
+}
+
+// HostedControlPlaneAPIEndpointSource reads the API server endpoint from a field of a hosted control plane
+// resource named after the cluster. The field can either hold the port or a host:port endpoint.
+// Resources which dex-operator is not allowed to read are reported as not found, so that the next source is asked.
+type HostedControlPlaneAPIEndpointSource struct {
+	GroupVersionKind schema.GroupVersionKind
+	// Path is the dot separated path of the port or endpoint in the resource.
+	Path string
+}
+
+func (s HostedControlPlaneAPIEndpointSource) Name() string {
+	return fmt.Sprintf("%s/%s/%s:%s", s.GroupVersionKind.Group, s.GroupVersionKind.Version, s.GroupVersionKind.Kind, s.Path)
+}
+
+func (s HostedControlPlaneAPIEndpointSource) GetAPIEndpoint(ctx context.Context, c client.Client, cluster APIEndpointCluster) (APIEndpoint, bool, error) {
+	controlPlane := &unstructured.Unstructured{}
+	controlPlane.SetGroupVersionKind(s.GroupVersionKind)
+	found, err := getClusterObject(ctx, c, cluster, controlPlane)
+	if apierrors.IsForbidden(microerror.Cause(err)) {
+		return APIEndpoint{}, false, nil
+	}
+	if err != nil || !found {
+		return APIEndpoint{}, false, err
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(controlPlane.Object, strings.Split(s.Path, ".")...)
+	if err != nil || !found {
+		return APIEndpoint{}, false, nil
+	}
+	var host string
+	if endpoint, ok := value.(string); ok {
+		if h, port, err := net.SplitHostPort(strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")); err == nil {
+			host, value = h, port
+		}
+	}
+	port, err := parsePort(value)
+	if err != nil {
+		return APIEndpoint{}, false, microerror.Maskf(invalidConfigError, "%s %s: %s", s.GroupVersionKind.Kind, s.Path, err)
+	}
+	return APIEndpoint{Host: host, Port: port}, true, nil
+}
+
+// getClusterObject gets the object named after the cluster from the organization namespace or,
+// if not found there, from the namespace of the dex target.
+// Missing objects and resource types which are not served are reported as not found.
+func getClusterObject(ctx context.Context, c client.Client, cluster APIEndpointCluster, obj client.Object) (bool, error) {
+	namespaces := []string{cluster.OrganizationNamespace}
+	if targetNamespace := cluster.Target.GetNamespace(); targetNamespace != cluster.OrganizationNamespace {
+		namespaces = append(namespaces, targetNamespace)
+	}
+	for _, namespace := range namespaces {
+		err := c.Get(ctx, types.NamespacedName{Name: cluster.Name, Namespace: namespace}, obj)
+		if err == nil {
+			return true, nil
+		}
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return false, microerror.Mask(err)
+		}
+	}
+	return false, nil
+}
+
+func parsePort(value interface{}) (int, error) {
+	var port int
+	switch v := value.(type) {
+	case int:
+		port = v
+	case int64:
+		port = int(v)
+	case float64:
+		port = int(v)
+	case string:
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid port %q", v)
+		}
+		port = p
+	default:
+		return 0, fmt.Errorf("invalid port %v", v)
+	}
+	if port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("port %d out of range", port)
+	}
+	return port, nil
+}
+
+// getAPIEndpoint asks the API endpoint sources in order for the API server endpoint of the cluster.
+func (s *Service) getAPIEndpoint(ctx context.Context, clusterID string, organizationNamespace string) (APIEndpoint, error) {
+	cluster := APIEndpointCluster{
+		Name:                  clusterID,
+		OrganizationNamespace: organizationNamespace,
+		Target:                s.target,
+	}
+	names := []string{}
+	for _, source := range s.apiEndpointSources {
+		endpoint, found, err := source.GetAPIEndpoint(ctx, s.Client, cluster)
+		if IsInvalidConfig(err) {
+			// invalid endpoint data must not block the other sources
+			s.log.Error(err, fmt.Sprintf("Ignoring invalid API endpoint data of cluster %s in %s.", clusterID, source.Name()))
+			found = false
+		} else if err != nil {
+			return APIEndpoint{}, microerror.Mask(err)
+		}
+		if found {
+			s.log.V(1).Info(fmt.Sprintf("Found API server endpoint %s:%d of cluster %s in %s.", endpoint.Host, endpoint.Port, clusterID, source.Name()))
+			return endpoint, nil
+		}
+		names = append(names, source.Name())
+	}
+	return APIEndpoint{}, microerror.Maskf(apiEndpointNotFoundError, "no API endpoint found for cluster %s in sources %s", clusterID, strings.Join(names, ", "))
+}
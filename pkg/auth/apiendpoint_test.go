@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+var testControlPlaneGVK = schema.GroupVersionKind{Group: "controlplane.cluster.x-k8s.io", Version: "v1alpha1", Kind: "KamajiControlPlane"}
+
+func TestGetAPIEndpoint(t *testing.T) {
+	hostedControlPlane := func(namespace string, endpoint interface{}) client.Object {
+		cp := &unstructured.Unstructured{}
+		cp.SetGroupVersionKind(testControlPlaneGVK)
+		cp.SetName("wc")
+		cp.SetNamespace(namespace)
+		_ = unstructured.SetNestedField(cp.Object, endpoint, "status", "controlPlaneEndpoint")
+		return cp
+	}
+	clusterValues := func(values string) client.Object {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "wc-cluster-values", Namespace: "org-example"},
+			Data:       map[string]string{key.ValuesConfigMapKey: values},
+		}
+	}
+	hostedSource := HostedControlPlaneAPIEndpointSource{GroupVersionKind: testControlPlaneGVK, Path: "status.controlPlaneEndpoint"}
+
+	testCases := []struct {
+		name         string
+		sources      []APIEndpointSource
+		annotations  map[string]string
+		namespace    string
+		objects      []client.Object
+		expectedPort int
+		expectedHost string
+		forbidden    bool
+		notFound     bool
+	}{
+		{
+			name:         "case 0: capi cluster",
+			sources:      DefaultAPIEndpointSources(),
+			objects:      []client.Object{getTestCluster()},
+			expectedPort: 443,
+		},
+		{
+			name:         "case 1: annotation takes precedence",
+			sources:      DefaultAPIEndpointSources(),
+			annotations:  map[string]string{key.APIServerPortAnnotation: "6443", key.APIServerHostAnnotation: "api.wc.example.com"},
+			objects:      []client.Object{getTestCluster()},
+			expectedPort: 6443,
+			expectedHost: "api.wc.example.com",
+		},
+		{
+			name:         "case 2: invalid annotation is ignored",
+			sources:      DefaultAPIEndpointSources(),
+			annotations:  map[string]string{key.APIServerPortAnnotation: "https"},
+			objects:      []client.Object{getTestCluster()},
+			expectedPort: 443,
+		},
+		{
+			name:         "case 3: cluster values without capi cluster",
+			sources:      DefaultAPIEndpointSources(),
+			objects:      []client.Object{clusterValues("baseDomain: wc.example.com\nkubernetes:\n  api:\n    port: 8443\n")},
+			expectedPort: 8443,
+			expectedHost: "api.wc.example.com",
+		},
+		{
+			name:         "case 4: cluster values without port",
+			sources:      DefaultAPIEndpointSources(),
+			objects:      []client.Object{clusterValues("baseDomain: example.com\n"), getTestCluster()},
+			expectedPort: 443,
+		},
+		{
+			name:         "case 5: cluster values with custom path",
+			sources:      []APIEndpointSource{ClusterValuesAPIEndpointSource{Path: "apiServer.port"}},
+			objects:      []client.Object{clusterValues("apiServer:\n  port: \"6443\"\n")},
+			expectedPort: 6443,
+		},
+		{
+			name:         "case 6: capi cluster in target namespace",
+			sources:      DefaultAPIEndpointSources(),
+			namespace:    "wc",
+			objects:      []client.Object{&capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "wc", Namespace: "wc"}, Spec: capi.ClusterSpec{ControlPlaneEndpoint: capi.APIEndpoint{Host: "api.wc.example.com", Port: 6443}}}},
+			expectedPort: 6443,
+			expectedHost: "api.wc.example.com",
+		},
+		{
+			name:         "case 7: hosted control plane endpoint",
+			sources:      []APIEndpointSource{hostedSource},
+			objects:      []client.Object{hostedControlPlane("org-example", "10.0.0.1:6443")},
+			expectedPort: 6443,
+			expectedHost: "10.0.0.1",
+		},
+		{
+			name:         "case 8: hosted control plane port",
+			sources:      []APIEndpointSource{hostedSource},
+			objects:      []client.Object{hostedControlPlane("org-example", int64(8443))},
+			expectedPort: 8443,
+		},
+		{
+			name:     "case 9: capi cluster without endpoint",
+			sources:  DefaultAPIEndpointSources(),
+			objects:  []client.Object{&capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "wc", Namespace: "org-example"}}},
+			notFound: true,
+		},
+		{
+			name:     "case 10: no source knows the cluster",
+			sources:  append(DefaultAPIEndpointSources(), hostedSource),
+			notFound: true,
+		},
+		{
+			name:         "case 11: forbidden hosted control plane falls back to the next source",
+			sources:      []APIEndpointSource{hostedSource, CAPIClusterAPIEndpointSource{}},
+			objects:      []client.Object{hostedControlPlane("org-example", "10.0.0.1:6443"), getTestCluster()},
+			forbidden:    true,
+			expectedPort: 443,
+		},
+		{
+			name:         "case 12: hosted control plane which is not served falls back to the next source",
+			sources:      []APIEndpointSource{HostedControlPlaneAPIEndpointSource{GroupVersionKind: schema.GroupVersionKind{Group: "controlplane.cluster.x-k8s.io", Version: "v1alpha1", Kind: "UnknownControlPlane"}, Path: "spec.port"}, CAPIClusterAPIEndpointSource{}},
+			objects:      []client.Object{getTestCluster()},
+			expectedPort: 443,
+		}, {
+			name:         "case 13: capi cluster takes precedence over cluster values",
+			sources:      DefaultAPIEndpointSources(),
+			objects:      []client.Object{clusterValues("baseDomain: wc.example.com\nkubernetes:\n  api:\n    port: 8443\n"), getTestCluster()},
+			expectedPort: 443,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			if err := capi.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			scheme.AddKnownTypeWithName(testControlPlaneGVK, &unstructured.Unstructured{})
+
+			namespace := tc.namespace
+			if namespace == "" {
+				namespace = "org-example"
+			}
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Namespace:   namespace,
+					Labels:      map[string]string{label.Cluster: "wc", label.Organization: "example"},
+					Annotations: tc.annotations,
+				},
+				Spec: v1alpha1.AppSpec{
+					Config: v1alpha1.AppSpecConfig{
+						ConfigMap: v1alpha1.AppSpecConfigConfigMap{Name: "wc-cluster-values", Namespace: "org-example"},
+					},
+				},
+			}
+
+			clientBuilder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...)
+			if tc.forbidden {
+				clientBuilder = clientBuilder.WithInterceptorFuncs(interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, nn client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if obj.GetObjectKind().GroupVersionKind() == testControlPlaneGVK {
+							return apierrors.NewForbidden(schema.GroupResource{Group: testControlPlaneGVK.Group, Resource: "kamajicontrolplanes"}, nn.Name, nil)
+						}
+						return c.Get(ctx, nn, obj, opts...)
+					},
+				})
+			}
+			service := Service{
+				Client:             clientBuilder.Build(),
+				log:                ctrl.Log.WithName("test"),
+				target:             dextarget.NewAppTarget(app),
+				apiEndpointSources: tc.sources,
+			}
+
+			endpoint, err := service.getAPIEndpoint(ctx, "wc", "org-example")
+			if tc.notFound {
+				if !IsAPIEndpointNotFound(err) {
+					t.Fatalf("expected api endpoint not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if endpoint.Port != tc.expectedPort {
+				t.Fatalf("expected port %d, got %d", tc.expectedPort, endpoint.Port)
+			}
+			if endpoint.Host != tc.expectedHost {
+				t.Fatalf("expected host %s, got %s", tc.expectedHost, endpoint.Host)
+			}
+		})
+	}
+}
+
+func TestReconcileWithoutAPIEndpoint(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := capi.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "org-example",
+			Labels:    map[string]string{label.Cluster: "wc", label.Organization: "example"},
+		},
+	}
+	service := Service{
+		Client:                          fake.NewClientBuilder().WithScheme(scheme).Build(),
+		log:                             ctrl.Log.WithName("test"),
+		target:                          dextarget.NewAppTarget(app),
+		managementClusterWriteAllGroups: []string{"group_a"},
+		managementClusterName:           "mc",
+		roleMappings:                    DefaultRoleMappings(),
+		apiEndpointSources:              DefaultAPIEndpointSources(),
+	}
+
+	if err := service.Reconcile(ctx); !IsAPIEndpointNotFound(err) {
+		t.Fatalf("expected api endpoint not found error, got %v", err)
+	}
+	result := &corev1.ConfigMap{}
+	if err := service.Get(ctx, types.NamespacedName{Name: key.GetAuthConfigName("wc"), Namespace: "org-example"}, result); err != nil {
+		t.Fatal(err)
+	}
+	expected := "managementCluster: mc\nbindings:\n    - role: cluster-admin\n      groups:\n        - group_a\n"
+	if result.Data[key.ValuesConfigMapKey] != expected {
+		t.Fatalf("Expected %s, got %s", expected, result.Data[key.ValuesConfigMapKey])
+	}
+}
+
+func TestParseAPIEndpointSources(t *testing.T) {
+	testCases := []struct {
+		name          string
+		sources       string
+		expectedNames []string
+		expectError   bool
+	}{
+		{
+			name:          "case 0: defaults",
+			sources:       "annotation,cluster-values,capi",
+			expectedNames: []string{"annotation", "cluster-values:kubernetes.api.port", "capi"},
+		},
+		{
+			name:          "case 1: custom cluster values path and hosted control plane",
+			sources:       "cluster-values:apiServer.port, controlplane.cluster.x-k8s.io/v1alpha1/KamajiControlPlane:status.controlPlaneEndpoint",
+			expectedNames: []string{"cluster-values:apiServer.port", "controlplane.cluster.x-k8s.io/v1alpha1/KamajiControlPlane:status.controlPlaneEndpoint"},
+		},
+		{
+			name:        "case 2: unknown source",
+			sources:     "annotation,kubeconfig",
+			expectError: true,
+		},
+		{
+			name:        "case 3: hosted control plane without path",
+			sources:     "controlplane.cluster.x-k8s.io/v1alpha1/KamajiControlPlane",
+			expectError: true,
+		},
+		{
+			name:        "case 4: empty",
+			sources:     "",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sources, err := ParseAPIEndpointSources(tc.sources)
+			if tc.expectError {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, source := range sources {
+				names = append(names, source.Name())
+			}
+			if strings.Join(names, ",") != strings.Join(tc.expectedNames, ",") {
+				t.Fatalf("expected %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
@@ -3,20 +3,18 @@ package auth
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 
 	"github.com/giantswarm/dex-operator/pkg/dextarget"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 
 	"github.com/giantswarm/microerror"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
-	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -27,6 +25,25 @@ type Config struct {
 	Target                          dextarget.DexTarget
 	ManagementClusterName           string
 	ManagementClusterWriteAllGroups []string
+	// Bindings are added to the auth config of every workload cluster in addition to
+	// the cluster-admin binding for the write all groups.
+	Bindings []Binding
+	// RoleMappings derive auth bindings from the role bindings of the organization.
+	// Defaults to DefaultRoleMappings.
+	RoleMappings []RoleMapping
+	// APIEndpointSources are asked in order for the API server port of the workload cluster.
+	// Defaults to DefaultAPIEndpointSources.
+	APIEndpointSources []APIEndpointSource
+	// AppConfigGetter provides the issuer of the dex target for the OIDC settings in the auth config.
+	// The OIDC settings are omitted if it is nil.
+	AppConfigGetter AppConfigGetter
+	// OIDC configures the OIDC settings in the auth config. Defaults to DefaultOIDCConfig.
+	OIDC *OIDCConfig
+	// KubeconfigClientID is the id of the public dex client used by the OIDC kubeconfig configmap of workload
+	// clusters. The kubeconfig configmap is only generated if it is set. It needs AppConfigGetter.
+	KubeconfigClientID string
+	// DryRun makes the service report changes to the auth configmap instead of applying them.
+	DryRun bool
 
 	// Deprecated: Use Target instead. App is kept for backward compatibility.
 	// If Target is nil and App is set, App will be wrapped in an AppTarget.
@@ -39,6 +56,13 @@ type Service struct {
 	target                          dextarget.DexTarget
 	managementClusterName           string
 	managementClusterWriteAllGroups []string
+	bindings                        []Binding
+	roleMappings                    []RoleMapping
+	apiEndpointSources              []APIEndpointSource
+	appConfigGetter                 AppConfigGetter
+	oidc                            OIDCConfig
+	kubeconfigClientID              string
+	dryRun                          bool
 }
 
 func New(c Config) (*Service, error) {
@@ -63,18 +87,56 @@ func New(c Config) (*Service, error) {
 	if len(c.ManagementClusterWriteAllGroups) == 0 {
 		return nil, microerror.Maskf(invalidConfigError, "no write all groups given")
 	}
+	if err := validateBindings(c.Bindings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	roleMappings := c.RoleMappings
+	if roleMappings == nil {
+		roleMappings = DefaultRoleMappings()
+	}
+	if err := validateRoleMappings(roleMappings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	apiEndpointSources := c.APIEndpointSources
+	if len(apiEndpointSources) == 0 {
+		apiEndpointSources = DefaultAPIEndpointSources()
+	}
+	oidc := DefaultOIDCConfig()
+	if c.OIDC != nil {
+		oidc = c.OIDC.withDefaults()
+	}
+	if err := validateOIDCConfig(oidc); err != nil {
+		return nil, microerror.Mask(err)
+	}
 	s := &Service{
 		Client:                          c.Client,
 		target:                          target,
 		log:                             c.Log,
 		managementClusterName:           c.ManagementClusterName,
 		managementClusterWriteAllGroups: c.ManagementClusterWriteAllGroups,
+		bindings:                        c.Bindings,
+		roleMappings:                    roleMappings,
+		apiEndpointSources:              apiEndpointSources,
+		appConfigGetter:                 c.AppConfigGetter,
+		oidc:                            oidc,
+		kubeconfigClientID:              c.KubeconfigClientID,
+		dryRun:                          c.DryRun,
 	}
 
 	return s, nil
 }
 
+// Reconcile creates or updates the auth configmap of a workload cluster dex target.
+// If the API server port of the cluster is not found in any API endpoint source, the auth configmap
+// is written without it and an error asserted by IsAPIEndpointNotFound is returned.
 func (s *Service) Reconcile(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.Reconcile", tracing.TargetAttributes(s.target.GetTargetType(), s.target.GetNamespacedName())...)
+	err := s.reconcile(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) reconcile(ctx context.Context) error {
 	cluster := s.target.GetClusterLabel()
 	nn := s.target.GetNamespacedName()
 
@@ -83,30 +145,71 @@ func (s *Service) Reconcile(ctx context.Context) error {
 		return nil
 	}
 
-	apiServerPort, err := s.getAPIServerPort(cluster, nn.Namespace, ctx)
+	organizationNamespace := s.getOrganizationNamespace(nn.Namespace)
+
+	apiEndpoint, apiEndpointErr := s.getAPIEndpoint(ctx, cluster, organizationNamespace)
+	if apiEndpointErr != nil && !IsAPIEndpointNotFound(apiEndpointErr) {
+		return apiEndpointErr
+	}
+
+	mappedBindings, err := s.getMappedBindings(ctx, organizationNamespace)
 	if err != nil {
 		return err
 	}
 
-	writeAllGroups, err := s.getWriteAllGroups(nn.Namespace, ctx)
+	organizationBindings, err := s.getOrganizationBindings(ctx, organizationNamespace)
 	if err != nil {
 		return err
 	}
 
+	var oidc *OIDC
+	if s.appConfigGetter != nil {
+		appConfig, err := s.appConfigGetter.GetAppConfig(ctx)
+		if err != nil {
+			return err
+		}
+		oidc, err = getOIDC(s.oidc, appConfig)
+		if err != nil {
+			return err
+		}
+	}
+
 	config := authConfig{
 		cluster:           cluster,
 		name:              key.GetAuthConfigName(cluster),
 		namespace:         nn.Namespace,
 		managementCluster: s.managementClusterName,
-		adminGroups:       writeAllGroups,
-		apiServerPort:     apiServerPort,
+		adminGroups:       s.managementClusterWriteAllGroups,
+		bindings:          mergeBindings(mappedBindings, s.bindings, organizationBindings),
+		apiServerPort:     apiEndpoint.Port,
+		oidc:              oidc,
+	}
+
+	kubeconfigCluster := APIEndpointCluster{
+		Name:                  cluster,
+		OrganizationNamespace: organizationNamespace,
+		Target:                s.target,
 	}
+	kubeconfigEnabled := s.kubeconfigClientID != "" && oidc != nil && apiEndpointErr == nil
 
 	// fetch auth config
 	desired, err := getAuthConfigMap(config)
 	if err != nil {
 		return err
 	}
+	if s.dryRun {
+		if err := s.reportConfigMapChanges(ctx, desired, "auth configmap", key.ValuesConfigMapKey); err != nil {
+			return err
+		}
+		if kubeconfigEnabled {
+			if err := s.reconcileKubeconfig(ctx, kubeconfigCluster, desired, apiEndpoint, oidc); err != nil {
+				return err
+			}
+		} else if err := s.deleteKubeconfig(ctx, cluster, nn.Namespace); err != nil {
+			return err
+		}
+		return apiEndpointErr
+	}
 	current := &corev1.ConfigMap{}
 	if err := s.Get(ctx, types.NamespacedName{
 		Name:      config.name,
@@ -139,19 +242,48 @@ func (s *Service) Reconcile(ctx context.Context) error {
 		}
 		s.log.Info(fmt.Sprintf("Added finalizer to auth configmap %s/%s.", config.namespace, config.name))
 	}
-	return nil
+	// the kubeconfig configmap is owned by the auth configmap and removed once it is disabled
+	if kubeconfigEnabled {
+		if err := s.reconcileKubeconfig(ctx, kubeconfigCluster, current, apiEndpoint, oidc); err != nil {
+			return err
+		}
+	} else if err := s.deleteKubeconfig(ctx, cluster, nn.Namespace); err != nil {
+		return err
+	}
+	return apiEndpointErr
 }
 
 func (s *Service) ReconcileDelete(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "auth.Service.ReconcileDelete", tracing.TargetAttributes(s.target.GetTargetType(), s.target.GetNamespacedName())...)
+	err := s.reconcileDelete(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) reconcileDelete(ctx context.Context) error {
 	cluster := s.target.GetClusterLabel()
 	nn := s.target.GetNamespacedName()
 
+	if s.dryRun {
+		if cluster != "" && cluster != s.managementClusterName {
+			s.log.Info(fmt.Sprintf("Dry run: would delete auth configmap %s/%s.", nn.Namespace, key.GetAuthConfigName(cluster)))
+		}
+		return nil
+	}
+
 	config := authConfig{
 		cluster:   cluster,
 		name:      key.GetAuthConfigName(cluster),
 		namespace: nn.Namespace,
 	}
 
+	// the kubeconfig configmap is deleted together with the auth configmap, even if the auth configmap is gone already
+	if cluster != "" && cluster != s.managementClusterName {
+		if err := s.deleteKubeconfig(ctx, cluster, nn.Namespace); err != nil {
+			return err
+		}
+	}
+
 	cm := &corev1.ConfigMap{}
 	if err := s.Get(ctx, types.NamespacedName{
 		Name:      config.name,
@@ -187,51 +319,9 @@ func (s *Service) ReconcileDelete(ctx context.Context) error {
 	return nil
 }
 
-func (s *Service) getAPIServerPort(clusterID string, targetNamespace string, ctx context.Context) (int, error) {
-	var namespace string
-	{
-		if isOrgNamespace(targetNamespace) {
-			namespace = targetNamespace
-		} else {
-			namespace = "org-" + s.target.GetOrganizationLabel()
-		}
-	}
-	cluster := &capi.Cluster{}
-	if err := s.Get(ctx, types.NamespacedName{
-		Name:      clusterID,
-		Namespace: namespace},
-		cluster); err != nil {
-		return 0, err
-	}
-	return int(cluster.Spec.ControlPlaneEndpoint.Port), nil
-}
-
-func (s *Service) getWriteAllGroups(targetNamespace string, ctx context.Context) ([]string, error) {
-	writeAllGroups := s.managementClusterWriteAllGroups
-
-	// get all additional groups that have cluster-admin role in target namespace
-	roleBindings := &rbacv1.RoleBindingList{}
-	if err := s.List(ctx, roleBindings, client.InNamespace(targetNamespace)); err != nil {
-		return nil, err
-	}
-	for _, roleBinding := range roleBindings.Items {
-		if roleBinding.RoleRef.Name != "cluster-admin" && roleBinding.RoleRef.Kind != "ClusterRole" {
-			continue
-		}
-		for _, subject := range roleBinding.Subjects {
-			if subject.Kind != "Group" {
-				continue
-			}
-			if !contains(writeAllGroups, subject.Name) {
-				writeAllGroups = append(writeAllGroups, subject.Name)
-			}
-		}
-	}
-	return writeAllGroups, nil
-}
-
-func isOrgNamespace(namespace string) bool {
-	return strings.HasPrefix(namespace, "org-")
+// getOrganizationNamespace returns the namespace of the organization the target belongs to.
+func (s *Service) getOrganizationNamespace(targetNamespace string) string {
+	return key.GetOrganizationNamespace(targetNamespace, s.target.GetOrganizationLabel())
 }
 
 func contains(groups []string, group string) bool {
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/giantswarm/dex-operator/pkg/dextarget"
@@ -18,6 +19,7 @@ import (
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -101,6 +103,8 @@ func TestReconcile(t *testing.T) {
 				target:                          target,
 				managementClusterWriteAllGroups: tc.writeAllGroups,
 				managementClusterName:           tc.managementClusterName,
+				roleMappings:                    DefaultRoleMappings(),
+				apiEndpointSources:              DefaultAPIEndpointSources(),
 			}
 
 			err := service.Reconcile(ctx)
@@ -164,3 +168,191 @@ func getTestRoleBindings() *rbacv1.RoleBinding {
 		},
 	}
 }
+
+func TestReconcileBindings(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		bindings             []Binding
+		organizationBindings string
+		expectedConfig       string
+	}{
+		{
+			name: "case 0: management cluster bindings",
+			bindings: []Binding{
+				{Role: "view", Groups: []string{"group_view"}},
+				{Role: "cluster-admin", Groups: []string{"group_e"}},
+			},
+			expectedConfig: "managementCluster: mc\nbindings:\n    - role: cluster-admin\n      groups:\n        - group_a\n        - group_c\n        - group_d\n        - group_e\n    - role: view\n      groups:\n        - group_view\nkubernetes:\n    api:\n        port: 443\n",
+		},
+		{
+			name: "case 1: management cluster and organization bindings",
+			bindings: []Binding{
+				{Role: "view", Groups: []string{"group_view"}},
+			},
+			organizationBindings: "- role: view\n  groups:\n  - org_view\n- role: edit\n  roleKind: Role\n  namespace: apps\n  groups:\n  - org_apps\n",
+			expectedConfig:       "managementCluster: mc\nbindings:\n    - role: cluster-admin\n      groups:\n        - group_a\n        - group_c\n        - group_d\n    - role: view\n      groups:\n        - group_view\n        - org_view\n    - role: edit\n      roleKind: Role\n      namespace: apps\n      groups:\n        - org_apps\nkubernetes:\n    api:\n        port: 443\n",
+		},
+		{
+			name:                 "case 2: invalid organization bindings are ignored",
+			organizationBindings: "- role: edit\n  roleKind: Role\n  groups:\n  - org_apps\n",
+			expectedConfig:       "managementCluster: mc\nbindings:\n    - role: cluster-admin\n      groups:\n        - group_a\n        - group_c\n        - group_d\nkubernetes:\n    api:\n        port: 443\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			if err := capi.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			fakeClientBuilder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(getTestCluster(), getTestRoleBindings())
+			if tc.organizationBindings != "" {
+				fakeClientBuilder = fakeClientBuilder.WithObjects(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      key.AuthBindingsConfigMapName,
+						Namespace: "org-example",
+					},
+					Data: map[string]string{
+						key.AuthBindingsKey: tc.organizationBindings,
+					},
+				})
+			}
+
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "org-example",
+					Labels:    map[string]string{label.Cluster: "wc", label.Organization: "example"},
+				},
+			}
+
+			service := Service{
+				Client:                          fakeClientBuilder.Build(),
+				log:                             ctrl.Log.WithName("test"),
+				target:                          dextarget.NewAppTarget(app),
+				managementClusterWriteAllGroups: []string{"group_a"},
+				managementClusterName:           "mc",
+				bindings:                        tc.bindings,
+				roleMappings:                    DefaultRoleMappings(),
+				apiEndpointSources:              DefaultAPIEndpointSources(),
+			}
+
+			if err := service.Reconcile(ctx); err != nil {
+				t.Fatal(err)
+			}
+			result := &corev1.ConfigMap{}
+			if err := service.Get(ctx, types.NamespacedName{
+				Name:      key.GetAuthConfigName("wc"),
+				Namespace: "org-example"},
+				result); err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Data[key.ValuesConfigMapKey] != tc.expectedConfig {
+				t.Fatalf("Expected %s, got %s", tc.expectedConfig, result.Data[key.ValuesConfigMapKey])
+			}
+		})
+	}
+}
+
+func TestGetMappedBindings(t *testing.T) {
+	roleBinding := func(name string, kind string, role string, groups ...string) *rbacv1.RoleBinding {
+		rb := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "org-example"},
+			RoleRef:    rbacv1.RoleRef{Kind: kind, Name: role},
+		}
+		for _, g := range groups {
+			rb.Subjects = append(rb.Subjects, rbacv1.Subject{Kind: "Group", Name: g})
+		}
+		return rb
+	}
+	clusterRoleBinding := func(name string, organization string, role string, groups ...string) *rbacv1.ClusterRoleBinding {
+		crb := &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{label.Organization: organization}},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: role},
+		}
+		for _, g := range groups {
+			crb.Subjects = append(crb.Subjects, rbacv1.Subject{Kind: "Group", Name: g})
+		}
+		return crb
+	}
+	objects := []client.Object{
+		roleBinding("admins", "ClusterRole", "cluster-admin", "group_admin"),
+		roleBinding("readers", "ClusterRole", "read-all-customer", "group_read"),
+		roleBinding("writers", "ClusterRole", "write-all-customer", "group_write"),
+		// not matched by any default mapping
+		roleBinding("editors", "ClusterRole", "edit", "group_edit"),
+		roleBinding("namespaced-admins", "Role", "cluster-admin", "group_role"),
+		// no group subjects
+		roleBinding("users", "ClusterRole", "cluster-admin"),
+		clusterRoleBinding("org-readers", "example", "read-all-organizations", "group_org_read"),
+		clusterRoleBinding("other-org-readers", "other", "read-all-organizations", "group_other"),
+	}
+
+	testCases := []struct {
+		name             string
+		roleMappings     []RoleMapping
+		expectedBindings []Binding
+	}{
+		{
+			name:         "case 0: default role mappings",
+			roleMappings: DefaultRoleMappings(),
+			expectedBindings: []Binding{
+				{Role: "cluster-admin", Groups: []string{"group_admin", "group_write"}},
+				{Role: "view", Groups: []string{"group_read", "group_org_read"}},
+			},
+		},
+		{
+			name: "case 1: custom role mappings",
+			roleMappings: []RoleMapping{
+				{RoleRef: "edit", Role: "edit", RoleKind: "Role", Namespace: "default"},
+				{RoleRef: "*", RoleRefKind: "Role", Role: "view"},
+			},
+			expectedBindings: []Binding{
+				{Role: "edit", RoleKind: "Role", Namespace: "default", Groups: []string{"group_edit"}},
+				{Role: "view", Groups: []string{"group_role"}},
+			},
+		},
+		{
+			name:             "case 2: no role mappings",
+			roleMappings:     []RoleMapping{},
+			expectedBindings: []Binding{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "org-example",
+				},
+			}
+			service := Service{
+				Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+				log:          ctrl.Log.WithName("test"),
+				target:       dextarget.NewAppTarget(app),
+				roleMappings: tc.roleMappings,
+			}
+
+			bindings, err := service.getMappedBindings(context.Background(), "org-example")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(bindings, tc.expectedBindings) {
+				t.Fatalf("Expected %v, got %v", tc.expectedBindings, bindings)
+			}
+		})
+	}
+}
@@ -15,16 +15,23 @@ type authConfig struct {
 	namespace         string
 	managementCluster string
 	adminGroups       []string
+	bindings          []Binding
 	apiServerPort     int
+	oidc              *OIDC
 }
 type AuthConfigValues struct {
 	ManagementCluster string     `yaml:"managementCluster,omitempty"`
 	Bindings          []Binding  `yaml:"bindings,omitempty"`
 	Kubernetes        Kubernetes `yaml:"kubernetes,omitempty"`
+	OIDC              *OIDC      `yaml:"oidc,omitempty"`
 }
 type Binding struct {
-	Role   string   `yaml:"role,omitempty"`
-	Groups []string `yaml:"groups,omitempty"`
+	Role string `yaml:"role,omitempty"`
+	// RoleKind is either ClusterRole or Role. Defaults to ClusterRole.
+	RoleKind string `yaml:"roleKind,omitempty"`
+	// Namespace restricts the binding to a namespace. Cluster-wide if empty.
+	Namespace string   `yaml:"namespace,omitempty"`
+	Groups    []string `yaml:"groups,omitempty"`
 }
 type Kubernetes struct {
 	API KubernetesAPI `yaml:"api,omitempty"`
@@ -36,17 +43,21 @@ type KubernetesAPI struct {
 func getAuthConfigMap(config authConfig) (*corev1.ConfigMap, error) {
 	values := &AuthConfigValues{
 		ManagementCluster: config.managementCluster,
-		Bindings: []Binding{
-			{
-				Role:   key.AdminRoleName,
-				Groups: config.adminGroups,
+		Bindings: mergeBindings(
+			[]Binding{
+				{
+					Role:   key.AdminRoleName,
+					Groups: config.adminGroups,
+				},
 			},
-		},
+			config.bindings,
+		),
 		Kubernetes: Kubernetes{
 			API: KubernetesAPI{
 				Port: config.apiServerPort,
 			},
 		},
+		OIDC: config.oidc,
 	}
 	data, err := yaml.Marshal(values)
 	if err != nil {
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	RoleKindClusterRole = "ClusterRole"
+	RoleKindRole        = "Role"
+)
+
+// ReadBindings reads additional auth bindings for all workload clusters of the management cluster.
+func ReadBindings(fileLocation string) ([]Binding, error) {
+	bindings := []Binding{}
+
+	file, err := os.ReadFile(fileLocation) //nolint:gosec,G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if err := yaml.Unmarshal(file, &bindings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if err := validateBindings(bindings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return bindings, nil
+}
+
+func validateBindings(bindings []Binding) error {
+	for i, b := range bindings {
+		if b.Role == "" {
+			return microerror.Maskf(invalidConfigError, "binding %d has no role", i)
+		}
+		if len(b.Groups) == 0 {
+			return microerror.Maskf(invalidConfigError, "binding %d for role %s has no groups", i, b.Role)
+		}
+		switch b.RoleKind {
+		case "", RoleKindClusterRole:
+		case RoleKindRole:
+			if b.Namespace == "" {
+				return microerror.Maskf(invalidConfigError, "binding %d for role %s of kind %s needs a namespace", i, b.Role, RoleKindRole)
+			}
+		default:
+			return microerror.Maskf(invalidConfigError, "binding %d for role %s has unknown role kind %s", i, b.Role, b.RoleKind)
+		}
+	}
+	return nil
+}
+
+// getOrganizationBindings reads additional auth bindings for the workload clusters of an organization
+// from the auth bindings configmap in the organization namespace. Invalid bindings are logged and ignored
+// so that they do not block reconciliation of the dex target.
+func (s *Service) getOrganizationBindings(ctx context.Context, namespace string) ([]Binding, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{Name: key.AuthBindingsConfigMapName, Namespace: namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, microerror.Mask(err)
+	}
+
+	bindings := []Binding{}
+	if err := yaml.Unmarshal([]byte(cm.Data[key.AuthBindingsKey]), &bindings); err != nil {
+		s.log.Error(err, fmt.Sprintf("Ignoring invalid auth bindings in configmap %s/%s.", namespace, key.AuthBindingsConfigMapName))
+		return nil, nil
+	}
+	if err := validateBindings(bindings); err != nil {
+		s.log.Error(err, fmt.Sprintf("Ignoring invalid auth bindings in configmap %s/%s.", namespace, key.AuthBindingsConfigMapName))
+		return nil, nil
+	}
+	return bindings, nil
+}
+
+// mergeBindings merges bindings for the same role in the same scope into a single binding.
+// The order of bindings and groups is kept so that the rendered auth config is stable.
+func mergeBindings(bindings ...[]Binding) []Binding {
+	merged := []Binding{}
+	index := map[string]int{}
+	for _, list := range bindings {
+		for _, b := range list {
+			roleKind := b.RoleKind
+			if roleKind == "" {
+				roleKind = RoleKindClusterRole
+			}
+			id := fmt.Sprintf("%s/%s/%s", roleKind, b.Namespace, b.Role)
+			i, ok := index[id]
+			if !ok {
+				index[id] = len(merged)
+				merged = append(merged, Binding{
+					Role:      b.Role,
+					RoleKind:  b.RoleKind,
+					Namespace: b.Namespace,
+				})
+				i = len(merged) - 1
+			}
+			for _, group := range b.Groups {
+				if !contains(merged[i].Groups, group) {
+					merged[i].Groups = append(merged[i].Groups, group)
+				}
+			}
+		}
+	}
+	return merged
+}
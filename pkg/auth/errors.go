@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var apiEndpointNotFoundError = &microerror.Error{
+	Kind: "apiEndpointNotFoundError",
+}
+
+// IsAPIEndpointNotFound asserts apiEndpointNotFoundError.
+func IsAPIEndpointNotFound(err error) bool {
+	return microerror.Cause(err) == apiEndpointNotFoundError
+}
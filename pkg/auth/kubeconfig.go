@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	kubeconfigDescription = "kubeconfig configmap"
+	execAPIVersion        = "client.authentication.k8s.io/v1beta1"
+)
+
+// kubeconfigScopes are requested by oidc-login so that dex issues the username and groups claims and a refresh token.
+var kubeconfigScopes = []string{"email", "groups", "profile", "offline_access"}
+
+type kubeconfigConfig struct {
+	name      string
+	namespace string
+	cluster   string
+	server    string
+	caData    []byte
+	clientID  string
+	oidc      *OIDC
+}
+
+// getKubeconfigConfigMap renders a kubeconfig which logs in to the workload cluster
+// through its dex with the oidc-login kubectl plugin.
+// The public kubectl client has no secret, so if it is not the client the API server trusts,
+// the token is requested for the audience of the API server client. The API server client has to list
+// the kubectl client in its trustedPeers for dex to issue it.
+func getKubeconfigConfigMap(config kubeconfigConfig) (*corev1.ConfigMap, error) {
+	user := fmt.Sprintf("oidc-%s", config.cluster)
+	context := fmt.Sprintf("%s@%s", user, config.cluster)
+
+	args := []string{
+		"oidc-login",
+		"get-token",
+		fmt.Sprintf("--oidc-issuer-url=%s", config.oidc.IssuerURL),
+		fmt.Sprintf("--oidc-client-id=%s", config.clientID),
+	}
+	for _, scope := range kubeconfigScopes {
+		args = append(args, fmt.Sprintf("--oidc-extra-scope=%s", scope))
+	}
+	if config.oidc.ClientID != "" && config.oidc.ClientID != config.clientID {
+		args = append(args, fmt.Sprintf("--oidc-extra-scope=audience:server:client_id:%s", config.oidc.ClientID))
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[config.cluster] = &clientcmdapi.Cluster{
+		Server:                   config.server,
+		CertificateAuthorityData: config.caData,
+	}
+	kubeconfig.AuthInfos[user] = &clientcmdapi.AuthInfo{
+		Exec: &clientcmdapi.ExecConfig{
+			APIVersion:      execAPIVersion,
+			Command:         "kubectl",
+			Args:            args,
+			InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+		},
+	}
+	kubeconfig.Contexts[context] = &clientcmdapi.Context{
+		Cluster:  config.cluster,
+		AuthInfo: user,
+	}
+	kubeconfig.CurrentContext = context
+
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      config.name,
+			Namespace: config.namespace,
+			Labels: map[string]string{
+				label.ManagedBy: key.DexOperatorLabelValue,
+				label.Cluster:   config.cluster,
+			},
+		},
+		Data: map[string]string{
+			key.KubeconfigKey: string(data),
+		},
+	}, nil
+}
+
+// reconcileKubeconfig creates or updates the kubeconfig configmap of the workload cluster.
+// The configmap is owned by the auth configmap of the cluster. It is skipped if the host of the API server is not known.
+func (s *Service) reconcileKubeconfig(ctx context.Context, cluster APIEndpointCluster, owner *corev1.ConfigMap, endpoint APIEndpoint, oidc *OIDC) error {
+	if endpoint.Host == "" {
+		s.log.Info(fmt.Sprintf("Skipping kubeconfig of cluster %s since the API server host is not known.", cluster.Name))
+		return nil
+	}
+
+	caData, err := s.getClusterCA(ctx, cluster)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if caData == nil {
+		s.log.V(1).Info(fmt.Sprintf("No CA found for cluster %s, the kubeconfig relies on the system trust store.", cluster.Name))
+	}
+
+	desired, err := getKubeconfigConfigMap(kubeconfigConfig{
+		name:      key.GetKubeconfigName(cluster.Name),
+		namespace: owner.Namespace,
+		cluster:   cluster.Name,
+		server:    fmt.Sprintf("https://%s", net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))),
+		caData:    caData,
+		clientID:  s.kubeconfigClientID,
+		oidc:      oidc,
+	})
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if s.dryRun {
+		return s.reportConfigMapChanges(ctx, desired, kubeconfigDescription, key.KubeconfigKey)
+	}
+	if err := controllerutil.SetOwnerReference(owner, desired, s.Scheme()); err != nil {
+		return microerror.Mask(err)
+	}
+
+	current := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+		if err := s.Create(ctx, desired); err != nil {
+			return microerror.Mask(err)
+		}
+		s.log.Info(fmt.Sprintf("Created kubeconfig configmap %s/%s.", desired.Namespace, desired.Name))
+		return nil
+	}
+	owned, err := controllerutil.HasOwnerReference(current.OwnerReferences, owner, s.Scheme())
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if current.Data[key.KubeconfigKey] != desired.Data[key.KubeconfigKey] || !owned {
+		current.Data = desired.Data
+		if err := controllerutil.SetOwnerReference(owner, current, s.Scheme()); err != nil {
+			return microerror.Mask(err)
+		}
+		if err := s.Update(ctx, current); err != nil {
+			return microerror.Mask(err)
+		}
+		s.log.Info(fmt.Sprintf("Updated kubeconfig configmap %s/%s.", desired.Namespace, desired.Name))
+	}
+	return nil
+}
+
+// getClusterCA returns the CA certificate of the workload cluster from the CAPI cluster CA secret.
+func (s *Service) getClusterCA(ctx context.Context, cluster APIEndpointCluster) ([]byte, error) {
+	secret := &corev1.Secret{}
+	found, err := getClusterObject(ctx, s.Client, APIEndpointCluster{
+		Name:                  key.GetClusterCASecretName(cluster.Name),
+		OrganizationNamespace: cluster.OrganizationNamespace,
+		Target:                cluster.Target,
+	}, secret)
+	if err != nil || !found {
+		return nil, err
+	}
+	return secret.Data[corev1.TLSCertKey], nil
+}
+
+// deleteKubeconfig removes the kubeconfig configmap of the workload cluster if it exists.
+func (s *Service) deleteKubeconfig(ctx context.Context, cluster string, namespace string) error {
+	name := key.GetKubeconfigName(cluster)
+	cm := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return microerror.Mask(err)
+	}
+	if s.dryRun {
+		s.log.Info(fmt.Sprintf("Dry run: would delete kubeconfig configmap %s/%s.", namespace, name))
+		return nil
+	}
+	if err := s.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+	s.log.Info(fmt.Sprintf("Deleted kubeconfig configmap %s/%s.", namespace, name))
+	return nil
+}
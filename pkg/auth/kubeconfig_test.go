@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestReconcileKubeconfig(t *testing.T) {
+	cluster := func(host string) client.Object {
+		return &capi.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "wc", Namespace: "org-example"},
+			Spec:       capi.ClusterSpec{ControlPlaneEndpoint: capi.APIEndpoint{Host: host, Port: 6443}},
+		}
+	}
+	caSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key.GetClusterCASecretName("wc"), Namespace: "org-example"},
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("ca-data"), corev1.TLSPrivateKeyKey: []byte("private")},
+	}
+
+	staleKubeconfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: key.GetKubeconfigName("wc"), Namespace: "org-example"},
+		Data:       map[string]string{key.KubeconfigKey: "stale"},
+	}
+
+	testCases := []struct {
+		name           string
+		objects        []client.Object
+		clientID       string
+		expectedServer string
+		expectedCA     string
+		expectMissing  bool
+	}{
+		{
+			name:           "case 0: kubeconfig with CA",
+			objects:        []client.Object{cluster("api.wc.example.com"), caSecret},
+			clientID:       "kubectl",
+			expectedServer: "https://api.wc.example.com:6443",
+			expectedCA:     "ca-data",
+		},
+		{
+			name:           "case 1: kubeconfig without CA",
+			objects:        []client.Object{cluster("api.wc.example.com")},
+			clientID:       "kubectl",
+			expectedServer: "https://api.wc.example.com:6443",
+		},
+		{
+			name:          "case 2: unknown API server host",
+			objects:       []client.Object{cluster(""), caSecret},
+			clientID:      "kubectl",
+			expectMissing: true,
+		},
+		{
+			name:           "case 3: existing kubeconfig is adopted by the auth configmap",
+			objects:        []client.Object{cluster("api.wc.example.com"), caSecret, staleKubeconfig.DeepCopy()},
+			clientID:       "kubectl",
+			expectedServer: "https://api.wc.example.com:6443",
+			expectedCA:     "ca-data",
+		},
+		{
+			name:          "case 4: disabled kubeconfig is removed",
+			objects:       []client.Object{cluster("api.wc.example.com"), caSecret, staleKubeconfig.DeepCopy()},
+			expectMissing: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			if err := capi.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "org-example",
+					Labels:    map[string]string{label.Cluster: "wc", label.Organization: "example"},
+				},
+			}
+
+			service := Service{
+				Client:                          fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build(),
+				log:                             ctrl.Log.WithName("test"),
+				target:                          dextarget.NewAppTarget(app),
+				managementClusterWriteAllGroups: []string{"group_a"},
+				managementClusterName:           "mc",
+				roleMappings:                    DefaultRoleMappings(),
+				apiEndpointSources:              DefaultAPIEndpointSources(),
+				appConfigGetter:                 testAppConfigGetter{appConfig: provider.AppConfig{IssuerURI: "https://dex.wc.example.com"}},
+				oidc:                            DefaultOIDCConfig(),
+				kubeconfigClientID:              tc.clientID,
+			}
+
+			if err := service.Reconcile(ctx); err != nil {
+				t.Fatal(err)
+			}
+			result := &corev1.ConfigMap{}
+			err := service.Get(ctx, types.NamespacedName{Name: key.GetKubeconfigName("wc"), Namespace: "org-example"}, result)
+			if tc.expectMissing {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected no kubeconfig configmap, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			authConfigMap := &corev1.ConfigMap{}
+			if err := service.Get(ctx, types.NamespacedName{Name: key.GetAuthConfigName("wc"), Namespace: "org-example"}, authConfigMap); err != nil {
+				t.Fatal(err)
+			}
+			if len(result.OwnerReferences) != 1 || result.OwnerReferences[0].UID != authConfigMap.UID {
+				t.Fatalf("expected kubeconfig configmap to be owned by the auth configmap, got %v", result.OwnerReferences)
+			}
+
+			kubeconfig, err := clientcmd.Load([]byte(result.Data[key.KubeconfigKey]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			context := kubeconfig.Contexts[kubeconfig.CurrentContext]
+			if context == nil {
+				t.Fatalf("expected current context %s", kubeconfig.CurrentContext)
+			}
+			if server := kubeconfig.Clusters[context.Cluster].Server; server != tc.expectedServer {
+				t.Fatalf("expected server %s, got %s", tc.expectedServer, server)
+			}
+			if ca := string(kubeconfig.Clusters[context.Cluster].CertificateAuthorityData); ca != tc.expectedCA {
+				t.Fatalf("expected CA %s, got %s", tc.expectedCA, ca)
+			}
+			expectedArgs := []string{
+				"oidc-login",
+				"get-token",
+				"--oidc-issuer-url=https://dex.wc.example.com",
+				"--oidc-client-id=kubectl",
+				"--oidc-extra-scope=email",
+				"--oidc-extra-scope=groups",
+				"--oidc-extra-scope=profile",
+				"--oidc-extra-scope=offline_access",
+				"--oidc-extra-scope=audience:server:client_id:dex-k8s-authenticator",
+			}
+			if args := kubeconfig.AuthInfos[context.AuthInfo].Exec.Args; !reflect.DeepEqual(args, expectedArgs) {
+				t.Fatalf("expected exec args %v, got %v", expectedArgs, args)
+			}
+
+			// the kubeconfig is removed together with the auth config
+			if err := service.ReconcileDelete(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := service.Get(ctx, types.NamespacedName{Name: key.GetKubeconfigName("wc"), Namespace: "org-example"}, result); !apierrors.IsNotFound(err) {
+				t.Fatalf("expected kubeconfig configmap to be deleted, got %v", err)
+			}
+		})
+	}
+}
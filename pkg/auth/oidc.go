@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"os"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+)
+
+const (
+	DefaultOIDCClientID      = "dex-k8s-authenticator"
+	DefaultOIDCUsernameClaim = "email"
+	DefaultOIDCGroupsClaim   = "groups"
+
+	AuthenticationConfigurationAPIVersion = "apiserver.config.k8s.io/v1beta1"
+	AuthenticationConfigurationKind       = "AuthenticationConfiguration"
+)
+
+// AppConfigGetter returns the app config used for the dex connectors of a dex target.
+type AppConfigGetter interface {
+	GetAppConfig(ctx context.Context) (provider.AppConfig, error)
+}
+
+// OIDCConfig configures how the kube-apiserver of workload clusters validates tokens issued by their dex.
+type OIDCConfig struct {
+	ClientID       string `yaml:"clientID,omitempty"`
+	UsernameClaim  string `yaml:"usernameClaim,omitempty"`
+	UsernamePrefix string `yaml:"usernamePrefix,omitempty"`
+	GroupsClaim    string `yaml:"groupsClaim,omitempty"`
+	GroupsPrefix   string `yaml:"groupsPrefix,omitempty"`
+	// StructuredAuthentication renders the structured authentication configuration, which needs
+	// Kubernetes 1.30 or newer on all workload clusters.
+	StructuredAuthentication bool `yaml:"structuredAuthentication,omitempty"`
+	// ClaimValidationRules are only rendered into the structured authentication configuration.
+	ClaimValidationRules []ClaimValidationRule `yaml:"claimValidationRules,omitempty"`
+}
+
+// ClaimValidationRule validates a claim of the token either by a required value or by a CEL expression.
+type ClaimValidationRule struct {
+	Claim         string `yaml:"claim,omitempty"`
+	RequiredValue string `yaml:"requiredValue,omitempty"`
+	Expression    string `yaml:"expression,omitempty"`
+	Message       string `yaml:"message,omitempty"`
+}
+
+// DefaultOIDCConfig is used if no OIDC config is given.
+func DefaultOIDCConfig() OIDCConfig {
+	return OIDCConfig{
+		ClientID:      DefaultOIDCClientID,
+		UsernameClaim: DefaultOIDCUsernameClaim,
+		GroupsClaim:   DefaultOIDCGroupsClaim,
+	}
+}
+
+// ReadOIDCConfig reads the OIDC config from a file. Unset fields are defaulted.
+func ReadOIDCConfig(fileLocation string) (OIDCConfig, error) {
+	file, err := os.ReadFile(fileLocation) //nolint:gosec,G304
+	if err != nil {
+		return OIDCConfig{}, microerror.Mask(err)
+	}
+
+	config := OIDCConfig{}
+	if err := yaml.Unmarshal(file, &config); err != nil {
+		return OIDCConfig{}, microerror.Mask(err)
+	}
+	config = config.withDefaults()
+	if err := validateOIDCConfig(config); err != nil {
+		return OIDCConfig{}, microerror.Mask(err)
+	}
+
+	return config, nil
+}
+
+func (c OIDCConfig) withDefaults() OIDCConfig {
+	defaults := DefaultOIDCConfig()
+	if c.ClientID == "" {
+		c.ClientID = defaults.ClientID
+	}
+	if c.UsernameClaim == "" {
+		c.UsernameClaim = defaults.UsernameClaim
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = defaults.GroupsClaim
+	}
+	return c
+}
+
+func validateOIDCConfig(config OIDCConfig) error {
+	if len(config.ClaimValidationRules) > 0 && !config.StructuredAuthentication {
+		return microerror.Maskf(invalidConfigError, "claim validation rules need structured authentication to be enabled")
+	}
+	for i, rule := range config.ClaimValidationRules {
+		if (rule.Claim == "") == (rule.Expression == "") {
+			return microerror.Maskf(invalidConfigError, "claim validation rule %d needs either a claim or an expression", i)
+		}
+		if rule.Expression != "" && rule.RequiredValue != "" {
+			return microerror.Maskf(invalidConfigError, "claim validation rule %d can not have a required value for an expression", i)
+		}
+		if rule.Expression != "" && rule.Message == "" {
+			return microerror.Maskf(invalidConfigError, "claim validation rule %d needs a message for its expression", i)
+		}
+	}
+	return nil
+}
+
+// OIDC holds the kube-apiserver OIDC settings of a workload cluster.
+type OIDC struct {
+	IssuerURL      string `yaml:"issuerURL"`
+	ClientID       string `yaml:"clientID"`
+	UsernameClaim  string `yaml:"usernameClaim"`
+	UsernamePrefix string `yaml:"usernamePrefix,omitempty"`
+	GroupsClaim    string `yaml:"groupsClaim"`
+	GroupsPrefix   string `yaml:"groupsPrefix,omitempty"`
+	// AuthenticationConfiguration is the structured authentication configuration
+	// for the kube-apiserver of Kubernetes 1.30 and newer. Empty unless enabled.
+	AuthenticationConfiguration string `yaml:"authenticationConfiguration,omitempty"`
+}
+
+type authenticationConfiguration struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	JWT        []jwtAuthenticator `yaml:"jwt"`
+}
+
+type jwtAuthenticator struct {
+	Issuer               jwtIssuer             `yaml:"issuer"`
+	ClaimValidationRules []ClaimValidationRule `yaml:"claimValidationRules,omitempty"`
+	ClaimMappings        claimMappings         `yaml:"claimMappings"`
+}
+
+type jwtIssuer struct {
+	URL       string   `yaml:"url"`
+	Audiences []string `yaml:"audiences"`
+}
+
+type claimMappings struct {
+	Username prefixedClaim `yaml:"username"`
+	Groups   prefixedClaim `yaml:"groups"`
+}
+
+type prefixedClaim struct {
+	Claim string `yaml:"claim"`
+	// Prefix is always rendered since the kube-apiserver requires it to be set explicitly.
+	Prefix string `yaml:"prefix"`
+}
+
+// getOIDC renders the OIDC settings for the issuer of the dex target.
+func getOIDC(config OIDCConfig, appConfig provider.AppConfig) (*OIDC, error) {
+	oidc := &OIDC{
+		IssuerURL:      appConfig.IssuerURI,
+		ClientID:       config.ClientID,
+		UsernameClaim:  config.UsernameClaim,
+		UsernamePrefix: config.UsernamePrefix,
+		GroupsClaim:    config.GroupsClaim,
+		GroupsPrefix:   config.GroupsPrefix,
+	}
+	// kube-apiservers before 1.30 do not know the structured authentication configuration
+	if !config.StructuredAuthentication {
+		return oidc, nil
+	}
+
+	authentication := authenticationConfiguration{
+		APIVersion: AuthenticationConfigurationAPIVersion,
+		Kind:       AuthenticationConfigurationKind,
+		JWT: []jwtAuthenticator{
+			{
+				Issuer: jwtIssuer{
+					URL:       appConfig.IssuerURI,
+					Audiences: []string{config.ClientID},
+				},
+				ClaimValidationRules: config.ClaimValidationRules,
+				ClaimMappings: claimMappings{
+					Username: prefixedClaim{Claim: config.UsernameClaim, Prefix: config.UsernamePrefix},
+					Groups:   prefixedClaim{Claim: config.GroupsClaim, Prefix: config.GroupsPrefix},
+				},
+			},
+		},
+	}
+	data, err := yaml.Marshal(authentication)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	oidc.AuthenticationConfiguration = string(data)
+
+	return oidc, nil
+}
@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+type testAppConfigGetter struct {
+	appConfig provider.AppConfig
+}
+
+func (g testAppConfigGetter) GetAppConfig(ctx context.Context) (provider.AppConfig, error) {
+	return g.appConfig, nil
+}
+
+func TestReconcileOIDC(t *testing.T) {
+	testCases := []struct {
+		name           string
+		oidc           OIDCConfig
+		expectedOIDC   string
+		expectedConfig string
+	}{
+		{
+			name: "case 0: defaults for clusters before Kubernetes 1.30",
+			oidc: DefaultOIDCConfig(),
+			expectedOIDC: `issuerURL: https://dex.wc.example.com
+clientID: dex-k8s-authenticator
+usernameClaim: email
+groupsClaim: groups
+`,
+		},
+		{
+			name: "case 1: structured authentication",
+			oidc: OIDCConfig{
+				ClientID:                 DefaultOIDCClientID,
+				UsernameClaim:            DefaultOIDCUsernameClaim,
+				GroupsClaim:              DefaultOIDCGroupsClaim,
+				StructuredAuthentication: true,
+			},
+			expectedOIDC: `issuerURL: https://dex.wc.example.com
+clientID: dex-k8s-authenticator
+usernameClaim: email
+groupsClaim: groups
+`,
+			expectedConfig: `apiVersion: apiserver.config.k8s.io/v1beta1
+kind: AuthenticationConfiguration
+jwt:
+    - issuer:
+        url: https://dex.wc.example.com
+        audiences:
+            - dex-k8s-authenticator
+      claimMappings:
+        username:
+            claim: email
+            prefix: ""
+        groups:
+            claim: groups
+            prefix: ""
+`,
+		},
+		{
+			name: "case 2: prefixes and claim validation rules",
+			oidc: OIDCConfig{
+				ClientID:                 "kubernetes",
+				UsernameClaim:            "preferred_username",
+				UsernamePrefix:           "oidc:",
+				GroupsClaim:              "groups",
+				GroupsPrefix:             "oidc:",
+				StructuredAuthentication: true,
+				ClaimValidationRules: []ClaimValidationRule{
+					{Claim: "hd", RequiredValue: "example.com"},
+					{Expression: "claims.email_verified == true", Message: "email must be verified"},
+				},
+			},
+			expectedOIDC: `issuerURL: https://dex.wc.example.com
+clientID: kubernetes
+usernameClaim: preferred_username
+usernamePrefix: 'oidc:'
+groupsClaim: groups
+groupsPrefix: 'oidc:'
+`,
+			expectedConfig: `apiVersion: apiserver.config.k8s.io/v1beta1
+kind: AuthenticationConfiguration
+jwt:
+    - issuer:
+        url: https://dex.wc.example.com
+        audiences:
+            - kubernetes
+      claimValidationRules:
+        - claim: hd
+          requiredValue: example.com
+        - expression: claims.email_verified == true
+          message: email must be verified
+      claimMappings:
+        username:
+            claim: preferred_username
+            prefix: 'oidc:'
+        groups:
+            claim: groups
+            prefix: 'oidc:'
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			if err := capi.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+
+			app := &v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "org-example",
+					Labels:    map[string]string{label.Cluster: "wc", label.Organization: "example"},
+				},
+			}
+
+			service := Service{
+				Client:                          fake.NewClientBuilder().WithScheme(scheme).WithObjects(getTestCluster()).Build(),
+				log:                             ctrl.Log.WithName("test"),
+				target:                          dextarget.NewAppTarget(app),
+				managementClusterWriteAllGroups: []string{"group_a"},
+				managementClusterName:           "mc",
+				roleMappings:                    DefaultRoleMappings(),
+				apiEndpointSources:              DefaultAPIEndpointSources(),
+				appConfigGetter:                 testAppConfigGetter{appConfig: provider.AppConfig{IssuerURI: "https://dex.wc.example.com"}},
+				oidc:                            tc.oidc,
+			}
+
+			if err := service.Reconcile(ctx); err != nil {
+				t.Fatal(err)
+			}
+			result := &corev1.ConfigMap{}
+			if err := service.Get(ctx, types.NamespacedName{Name: key.GetAuthConfigName("wc"), Namespace: "org-example"}, result); err != nil {
+				t.Fatal(err)
+			}
+
+			values := AuthConfigValues{}
+			if err := yaml.Unmarshal([]byte(result.Data[key.ValuesConfigMapKey]), &values); err != nil {
+				t.Fatal(err)
+			}
+			if values.OIDC == nil {
+				t.Fatal("expected oidc settings in auth config")
+			}
+			if values.OIDC.AuthenticationConfiguration != tc.expectedConfig {
+				t.Fatalf("Expected authentication configuration %s, got %s", tc.expectedConfig, values.OIDC.AuthenticationConfiguration)
+			}
+			values.OIDC.AuthenticationConfiguration = ""
+			oidc, err := yaml.Marshal(values.OIDC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(oidc) != tc.expectedOIDC {
+				t.Fatalf("Expected oidc settings %s, got %s", tc.expectedOIDC, oidc)
+			}
+		})
+	}
+}
+
+func TestValidateOIDCConfig(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rules        []ClaimValidationRule
+		unstructured bool
+		expectError  bool
+	}{
+		{
+			name:  "case 0: required value",
+			rules: []ClaimValidationRule{{Claim: "hd", RequiredValue: "example.com"}},
+		},
+		{
+			name:  "case 1: expression",
+			rules: []ClaimValidationRule{{Expression: "claims.email_verified == true", Message: "email must be verified"}},
+		},
+		{
+			name:        "case 2: neither claim nor expression",
+			rules:       []ClaimValidationRule{{RequiredValue: "example.com"}},
+			expectError: true,
+		},
+		{
+			name:        "case 3: claim and expression",
+			rules:       []ClaimValidationRule{{Claim: "hd", Expression: "claims.hd == 'example.com'", Message: "wrong domain"}},
+			expectError: true,
+		},
+		{
+			name:        "case 4: expression without message",
+			rules:       []ClaimValidationRule{{Expression: "claims.email_verified == true"}},
+			expectError: true,
+		},
+		{
+			name:         "case 5: rules without structured authentication",
+			rules:        []ClaimValidationRule{{Claim: "hd", RequiredValue: "example.com"}},
+			unstructured: true,
+			expectError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateOIDCConfig(OIDCConfig{ClaimValidationRules: tc.rules, StructuredAuthentication: !tc.unstructured})
+			if tc.expectError && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+			if !tc.expectError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
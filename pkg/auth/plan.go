@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reportConfigMapChanges logs the difference between the current and the desired data of a configmap in dry-run mode.
+func (s *Service) reportConfigMapChanges(ctx context.Context, desired *corev1.ConfigMap, description string, dataKey string) error {
+	current := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{
+		Name:      desired.Name,
+		Namespace: desired.Namespace},
+		current); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("Dry run: would create %s %s/%s.", description, desired.Namespace, desired.Name),
+			dataKey, desired.Data[dataKey])
+		return nil
+	}
+	if diff := cmp.Diff(current.Data[dataKey], desired.Data[dataKey]); diff != "" {
+		s.log.Info(fmt.Sprintf("Dry run: would update %s %s/%s.", description, desired.Namespace, desired.Name),
+			"diff", diff)
+		return nil
+	}
+	s.log.Info(fmt.Sprintf("Dry run: no changes to %s %s/%s.", description, desired.Namespace, desired.Name))
+	return nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+// RoleMapping maps role bindings of an organization on the management cluster
+// to a binding in the auth config of its workload clusters.
+type RoleMapping struct {
+	// RoleRef is matched against the name of the role referenced by the role binding.
+	// Shell patterns like read-all-* are supported.
+	RoleRef string `yaml:"roleRef"`
+	// RoleRefKind restricts the mapping to role bindings referencing a ClusterRole or a Role.
+	// Matches both if empty.
+	RoleRefKind string `yaml:"roleRefKind,omitempty"`
+	// Role, RoleKind and Namespace describe the binding in the workload cluster.
+	Role      string `yaml:"role"`
+	RoleKind  string `yaml:"roleKind,omitempty"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+// DefaultRoleMappings are used if no role mappings are configured.
+func DefaultRoleMappings() []RoleMapping {
+	return []RoleMapping{
+		{RoleRef: key.AdminRoleName, RoleRefKind: RoleKindClusterRole, Role: key.AdminRoleName},
+		{RoleRef: "write-all-*", Role: key.AdminRoleName},
+		{RoleRef: "read-all-*", Role: "view"},
+	}
+}
+
+// ReadRoleMappings reads the role mapping table from a file.
+func ReadRoleMappings(fileLocation string) ([]RoleMapping, error) {
+	mappings := []RoleMapping{}
+
+	file, err := os.ReadFile(fileLocation) //nolint:gosec,G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if err := yaml.Unmarshal(file, &mappings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if err := validateRoleMappings(mappings); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return mappings, nil
+}
+
+func validateRoleMappings(mappings []RoleMapping) error {
+	for i, m := range mappings {
+		if m.RoleRef == "" {
+			return microerror.Maskf(invalidConfigError, "role mapping %d has no roleRef", i)
+		}
+		if _, err := path.Match(m.RoleRef, ""); err != nil {
+			return microerror.Maskf(invalidConfigError, "role mapping %d has invalid roleRef pattern %s", i, m.RoleRef)
+		}
+		switch m.RoleRefKind {
+		case "", RoleKindClusterRole, RoleKindRole:
+		default:
+			return microerror.Maskf(invalidConfigError, "role mapping %d has unknown roleRefKind %s", i, m.RoleRefKind)
+		}
+		// The target binding is validated like any other binding.
+		if err := validateBindings([]Binding{m.binding([]string{"placeholder"})}); err != nil {
+			return microerror.Maskf(invalidConfigError, "role mapping %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+func (m RoleMapping) matches(ref rbacv1.RoleRef) bool {
+	if m.RoleRefKind != "" && m.RoleRefKind != ref.Kind {
+		return false
+	}
+	matched, err := path.Match(m.RoleRef, ref.Name)
+	return err == nil && matched
+}
+
+func (m RoleMapping) binding(groups []string) Binding {
+	return Binding{
+		Role:      m.Role,
+		RoleKind:  m.RoleKind,
+		Namespace: m.Namespace,
+		Groups:    groups,
+	}
+}
+
+// getMappedBindings derives auth bindings from the group subjects of all role bindings in the
+// organization namespace and of all cluster role bindings labelled with the organization.
+// Each role binding is mapped by the first role mapping matching its role reference.
+func (s *Service) getMappedBindings(ctx context.Context, organizationNamespace string) ([]Binding, error) {
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := s.List(ctx, roleBindings, client.InNamespace(organizationNamespace)); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	organization := strings.TrimPrefix(organizationNamespace, key.OrganizationNamespacePrefix)
+	if err := s.List(ctx, clusterRoleBindings, client.MatchingLabels{label.Organization: organization}); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	bindings := []Binding{}
+	add := func(ref rbacv1.RoleRef, subjects []rbacv1.Subject) {
+		groups := []string{}
+		for _, subject := range subjects {
+			if subject.Kind == rbacv1.GroupKind {
+				groups = append(groups, subject.Name)
+			}
+		}
+		if len(groups) == 0 {
+			return
+		}
+		for _, m := range s.roleMappings {
+			if m.matches(ref) {
+				bindings = append(bindings, m.binding(groups))
+				return
+			}
+		}
+	}
+	for _, rb := range roleBindings.Items {
+		add(rb.RoleRef, rb.Subjects)
+	}
+	for _, crb := range clusterRoleBindings.Items {
+		add(crb.RoleRef, crb.Subjects)
+	}
+	return mergeBindings(bindings), nil
+}
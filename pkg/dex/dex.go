@@ -1,15 +1,27 @@
 package dex
 
+import (
+	"encoding/json"
+	"sort"
+)
+
+const staticClientsKey = "staticClients"
+
 type DexConfig struct {
 	Oidc DexOidc `json:"oidc"`
 }
 
+// DexOidc holds the connectors grouped by owner and the static clients of a dex instance.
+// Owners are rendered inline next to the static clients, e.g. oidc.giantswarm.connectors.
 type DexOidc struct {
-	Giantswarm *DexOidcOwner `json:"giantswarm,omitempty"`
-	Customer   *DexOidcOwner `json:"customer,omitempty"`
+	Owners        map[string]*DexOidcOwner `json:"-"`
+	StaticClients []StaticClient           `json:"-"`
 }
 
+// DexOidcOwner holds the connectors of an owner. Priority orders the owners on the login page,
+// owners with higher priorities come first.
 type DexOidcOwner struct {
+	Priority   int         `json:"priority,omitempty"`
 	Connectors []Connector `json:"connectors,omitempty"`
 }
 
@@ -19,4 +31,92 @@ type Connector struct {
 	ID   string `json:"id"`
 
 	Config string `json:"connectorConfig"`
+
+	Priority int    `json:"priority,omitempty"`
+	Icon     string `json:"icon,omitempty"`
+	Hidden   bool   `json:"hidden,omitempty"`
+}
+
+// StaticClient is an OAuth2 client registered in dex itself, e.g. for kubectl or internal tools.
+type StaticClient struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name,omitempty"`
+	Secret       string   `json:"secret,omitempty"`
+	Public       bool     `json:"public,omitempty"`
+	RedirectURIs []string `json:"redirectURIs,omitempty"`
+	TrustedPeers []string `json:"trustedPeers,omitempty"`
+}
+
+// IsReservedOwner returns true if the owner name clashes with other fields of the oidc config.
+func IsReservedOwner(owner string) bool {
+	return owner == staticClientsKey
+}
+
+// GetOwnerNames returns the names of all owners with a connector configuration in sorted order.
+func (o DexOidc) GetOwnerNames() []string {
+	names := []string{}
+	for name, owner := range o.Owners {
+		if owner != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetOrderedOwnerNames returns the names of all owners with a connector configuration by descending priority.
+// Owners with the same priority are in alphabetical order.
+func (o DexOidc) GetOrderedOwnerNames() []string {
+	names := o.GetOwnerNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		return o.Owners[names[i]].Priority > o.Owners[names[j]].Priority
+	})
+	return names
+}
+
+// GetOwner returns the connector configuration of the owner or nil if there is none.
+func (o DexOidc) GetOwner(name string) *DexOidcOwner {
+	return o.Owners[name]
+}
+
+func (o DexOidc) MarshalJSON() ([]byte, error) {
+	fields := map[string]interface{}{}
+	for name, owner := range o.Owners {
+		if owner != nil && !IsReservedOwner(name) {
+			fields[name] = owner
+		}
+	}
+	if len(o.StaticClients) > 0 {
+		fields[staticClientsKey] = o.StaticClients
+	}
+	return json.Marshal(fields)
+}
+
+func (o *DexOidc) UnmarshalJSON(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	o.Owners = nil
+	o.StaticClients = nil
+	for name, value := range fields {
+		if IsReservedOwner(name) {
+			if err := json.Unmarshal(value, &o.StaticClients); err != nil {
+				return err
+			}
+			continue
+		}
+		var owner *DexOidcOwner
+		if err := json.Unmarshal(value, &owner); err != nil {
+			return err
+		}
+		if owner == nil {
+			continue
+		}
+		if o.Owners == nil {
+			o.Owners = map[string]*DexOidcOwner{}
+		}
+		o.Owners[name] = owner
+	}
+	return nil
 }
@@ -1,7 +1,63 @@
 package dex
 
-import "testing"
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
 
 func TestPlaceholder(t *testing.T) {
 	// Placeholder test for coverage tooling compatibility
 }
+
+func TestDexOidcJSON(t *testing.T) {
+	testCases := []struct {
+		name           string
+		config         DexConfig
+		expected       string
+		expectedOwners []string
+	}{
+		{
+			name:           "case 0: empty",
+			config:         DexConfig{},
+			expected:       `{"oidc":{}}`,
+			expectedOwners: []string{},
+		},
+		{
+			name: "case 1: owners and static clients",
+			config: DexConfig{
+				Oidc: DexOidc{
+					Owners: map[string]*DexOidcOwner{
+						"giantswarm": {Connectors: []Connector{{ID: "giantswarm-github", Type: "github", Name: "GitHub", Config: "a"}}},
+						"partner":    {Priority: 10, Connectors: []Connector{{ID: "partner-azure", Type: "microsoft", Name: "Azure", Config: "b", Priority: 10}}},
+					},
+					StaticClients: []StaticClient{{ID: "kubectl", Public: true}},
+				},
+			},
+			expected:       `{"oidc":{"giantswarm":{"connectors":[{"connectorType":"github","connectorName":"GitHub","id":"giantswarm-github","connectorConfig":"a"}]},"partner":{"priority":10,"connectors":[{"connectorType":"microsoft","connectorName":"Azure","id":"partner-azure","connectorConfig":"b","priority":10}]},"staticClients":[{"id":"kubectl","public":true}]}}`,
+			expectedOwners: []string{"partner", "giantswarm"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, data)
+			}
+			result := DexConfig{}
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, tc.config) {
+				t.Fatalf("expected %v, got %v", tc.config, result)
+			}
+			if owners, expected := result.Oidc.GetOrderedOwnerNames(), tc.expectedOwners; !reflect.DeepEqual(owners, expected) {
+				t.Fatalf("expected owners %v, got %v", expected, owners)
+			}
+		})
+	}
+}
@@ -118,7 +118,7 @@ func (a *AppTarget) IsBeingDeleted() bool {
 }
 
 func (a *AppTarget) GetTargetType() string {
-	return "App"
+	return AppTargetType
 }
 
 func (a *AppTarget) GetObject() client.Object {
@@ -137,3 +137,22 @@ func (a *AppTarget) AttachSecretConfig(ctx context.Context, c client.Client) (bo
 func (a *AppTarget) ManagesSecretConfig() bool {
 	return true
 }
+
+// GetRelease returns the helm release of the app, which the app platform names after the App CR.
+func (a *AppTarget) GetRelease() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      a.Name,
+		Namespace: a.Spec.Namespace,
+	}
+}
+
+// GetKubeConfigSecret returns the kubeconfig secret of apps which are not deployed in-cluster.
+func (a *AppTarget) GetKubeConfigSecret() (types.NamespacedName, string, bool) {
+	if a.Spec.KubeConfig.InCluster || a.Spec.KubeConfig.Secret.Name == "" {
+		return types.NamespacedName{}, "", false
+	}
+	return types.NamespacedName{
+		Name:      a.Spec.KubeConfig.Secret.Name,
+		Namespace: a.Spec.KubeConfig.Secret.Namespace,
+	}, key.AppKubeConfigSecretKey, true
+}
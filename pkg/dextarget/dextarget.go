@@ -7,6 +7,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	AppTargetType         = "App"
+	HelmReleaseTargetType = "HelmRelease"
+)
+
 // DexTarget is an interface that abstracts the common functionality between
 // Giant Swarm App CRs and Flux HelmReleases for dex-operator configuration injection.
 type DexTarget interface {
@@ -65,4 +70,11 @@ type DexTarget interface {
 	// (no Flux Kustomization labels) — Flux-managed HelmReleases must declare the
 	// entry in their Git manifest to avoid SSA ownership conflicts.
 	ManagesSecretConfig() bool
+
+	// GetRelease returns the namespace and name of the helm release deployed by the target.
+	GetRelease() types.NamespacedName
+
+	// GetKubeConfigSecret returns the secret and the key of the kubeconfig of the cluster the
+	// target is deployed to. Returns false if the target is deployed to the management cluster.
+	GetKubeConfigSecret() (types.NamespacedName, string, bool)
 }
@@ -1,7 +1,207 @@
 package dextarget
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/fluxcd/pkg/apis/meta"
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
 
 func TestDexTarget(t *testing.T) {
 	// Placeholder test to ensure package is included in coverage
 }
+
+func TestGetSecretConfigPatch(t *testing.T) {
+	testCases := []struct {
+		name       string
+		valuesFrom []helmv2.ValuesReference
+		expected   string
+	}{
+		{
+			name: "case 0: no valuesFrom",
+			expected: `- target:
+    group: helm.toolkit.fluxcd.io
+    version: v2
+    kind: HelmRelease
+    name: dex-app
+    namespace: example
+  patch: |
+    - op: add
+      path: /spec/valuesFrom
+      value:
+        - kind: Secret
+          name: dex-app-default-dex-config
+          valuesKey: default
+`,
+		},
+		{
+			name: "case 1: existing valuesFrom",
+			valuesFrom: []helmv2.ValuesReference{
+				{Kind: "ConfigMap", Name: "cluster-values"},
+			},
+			expected: `- target:
+    group: helm.toolkit.fluxcd.io
+    version: v2
+    kind: HelmRelease
+    name: dex-app
+    namespace: example
+  patch: |
+    - op: add
+      path: /spec/valuesFrom/-
+      value:
+        kind: Secret
+        name: dex-app-default-dex-config
+        valuesKey: default
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := NewHelmReleaseTarget(&helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "dex-app", Namespace: "example"},
+				Spec:       helmv2.HelmReleaseSpec{ValuesFrom: tc.valuesFrom},
+			})
+			patch, err := target.GetSecretConfigPatch("dex-app-default-dex-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patch != tc.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tc.expected, patch)
+			}
+		})
+	}
+}
+
+func TestListHelmReleasesMissingSecretConfig(t *testing.T) {
+	fluxLabels := func(extra map[string]string) map[string]string {
+		labels := map[string]string{
+			"kustomize.toolkit.fluxcd.io/name":      "flux",
+			"kustomize.toolkit.fluxcd.io/namespace": "flux-system",
+		}
+		for k, v := range extra {
+			labels[k] = v
+		}
+		return labels
+	}
+	dexLabel := map[string]string{key.AppLabel: key.DexAppLabelValue}
+
+	scheme := runtime.NewScheme()
+	_ = helmv2.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		// Flux-managed dex HelmRelease without secret reference
+		&helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "org-a", Labels: fluxLabels(dexLabel)}},
+		// Flux-managed dex HelmRelease with secret reference
+		&helmv2.HelmRelease{
+			ObjectMeta: metav1.ObjectMeta{Name: "present", Namespace: "org-a", Labels: fluxLabels(dexLabel)},
+			Spec: helmv2.HelmReleaseSpec{ValuesFrom: []helmv2.ValuesReference{
+				{Kind: "Secret", Name: key.GetDexConfigName("present"), ValuesKey: "default"},
+			}},
+		},
+		// Self-managed dex HelmRelease
+		&helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "self-managed", Namespace: "org-a", Labels: dexLabel}},
+		// Flux-managed HelmRelease of another app
+		&helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "org-a", Labels: fluxLabels(nil)}},
+		// Flux-managed management cluster dex HelmRelease without secret reference
+		&helmv2.HelmRelease{ObjectMeta: metav1.ObjectMeta{Name: key.MCDexHelmReleaseDefaultName, Namespace: key.MCDexAppDefaultNamespace, Labels: fluxLabels(nil)}},
+	).Build()
+
+	targets, err := ListHelmReleasesMissingSecretConfig(context.Background(), fakeClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, target := range targets {
+		found[target.GetNamespacedName().String()] = true
+	}
+	expected := map[string]bool{
+		"org-a/missing":      true,
+		"giantswarm/dex-app": true,
+	}
+	if len(found) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, found)
+	}
+	for nn := range expected {
+		if !found[nn] {
+			t.Fatalf("expected %s to be listed, got %v", nn, found)
+		}
+	}
+}
+
+func TestGetKubeConfigSecret(t *testing.T) {
+	testCases := []struct {
+		name            string
+		target          DexTarget
+		expectedRelease types.NamespacedName
+		expectedSecret  types.NamespacedName
+		expectedKey     string
+		expectedRemote  bool
+	}{
+		{
+			name: "case 0: in-cluster app",
+			target: NewAppTarget(&v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{Name: "dex-app", Namespace: "giantswarm"},
+				Spec:       v1alpha1.AppSpec{Namespace: "giantswarm", KubeConfig: v1alpha1.AppSpecKubeConfig{InCluster: true}},
+			}),
+			expectedRelease: types.NamespacedName{Name: "dex-app", Namespace: "giantswarm"},
+		},
+		{
+			name: "case 1: workload cluster app",
+			target: NewAppTarget(&v1alpha1.App{
+				ObjectMeta: metav1.ObjectMeta{Name: "wc-dex-app", Namespace: "org-example"},
+				Spec: v1alpha1.AppSpec{
+					Namespace:  "kube-system",
+					KubeConfig: v1alpha1.AppSpecKubeConfig{Secret: v1alpha1.AppSpecKubeConfigSecret{Name: "wc-kubeconfig", Namespace: "org-example"}},
+				},
+			}),
+			expectedRelease: types.NamespacedName{Name: "wc-dex-app", Namespace: "kube-system"},
+			expectedSecret:  types.NamespacedName{Name: "wc-kubeconfig", Namespace: "org-example"},
+			expectedKey:     key.AppKubeConfigSecretKey,
+			expectedRemote:  true,
+		},
+		{
+			name: "case 2: in-cluster helmrelease",
+			target: NewHelmReleaseTarget(&helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "dex", Namespace: "giantswarm"},
+			}),
+			expectedRelease: types.NamespacedName{Name: "dex", Namespace: "giantswarm"},
+		},
+		{
+			name: "case 3: workload cluster helmrelease",
+			target: NewHelmReleaseTarget(&helmv2.HelmRelease{
+				ObjectMeta: metav1.ObjectMeta{Name: "wc-dex", Namespace: "org-example"},
+				Spec: helmv2.HelmReleaseSpec{
+					TargetNamespace: "kube-system",
+					KubeConfig:      &meta.KubeConfigReference{SecretRef: &meta.SecretKeyReference{Name: "wc-kubeconfig"}},
+				},
+			}),
+			expectedRelease: types.NamespacedName{Name: "kube-system-wc-dex", Namespace: "kube-system"},
+			expectedSecret:  types.NamespacedName{Name: "wc-kubeconfig", Namespace: "org-example"},
+			expectedKey:     key.HelmReleaseKubeConfigSecretKey,
+			expectedRemote:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if release := tc.target.GetRelease(); release != tc.expectedRelease {
+				t.Fatalf("expected release %s, got %s", tc.expectedRelease, release)
+			}
+			secret, secretKey, remote := tc.target.GetKubeConfigSecret()
+			if remote != tc.expectedRemote {
+				t.Fatalf("expected remote %v, got %v", tc.expectedRemote, remote)
+			}
+			if secret != tc.expectedSecret || secretKey != tc.expectedKey {
+				t.Fatalf("expected kubeconfig secret %s key %s, got %s key %s", tc.expectedSecret, tc.expectedKey, secret, secretKey)
+			}
+		})
+	}
+}
@@ -158,7 +158,7 @@ func (h *HelmReleaseTarget) IsBeingDeleted() bool {
 }
 
 func (h *HelmReleaseTarget) GetTargetType() string {
-	return "HelmRelease"
+	return HelmReleaseTargetType
 }
 
 func (h *HelmReleaseTarget) GetObject() client.Object {
@@ -186,6 +186,30 @@ func (h *HelmReleaseTarget) ManagesSecretConfig() bool {
 	return !h.isFluxManaged()
 }
 
+// GetRelease returns the helm release of the HelmRelease, by default named after target namespace and name.
+func (h *HelmReleaseTarget) GetRelease() types.NamespacedName {
+	return types.NamespacedName{
+		Name:      h.GetReleaseName(),
+		Namespace: h.GetReleaseNamespace(),
+	}
+}
+
+// GetKubeConfigSecret returns the kubeconfig secret of HelmReleases deploying to a remote cluster.
+// The secret is in the namespace of the HelmRelease.
+func (h *HelmReleaseTarget) GetKubeConfigSecret() (types.NamespacedName, string, bool) {
+	if h.Spec.KubeConfig == nil || h.Spec.KubeConfig.SecretRef == nil {
+		return types.NamespacedName{}, "", false
+	}
+	secretKey := h.Spec.KubeConfig.SecretRef.Key
+	if secretKey == "" {
+		secretKey = key.HelmReleaseKubeConfigSecretKey
+	}
+	return types.NamespacedName{
+		Name:      h.Spec.KubeConfig.SecretRef.Name,
+		Namespace: h.Namespace,
+	}, secretKey, true
+}
+
 // isFluxManaged returns true if this HelmRelease is reconciled by a Flux
 // Kustomization, identified by the presence of Flux management labels.
 func (h *HelmReleaseTarget) isFluxManaged() bool {
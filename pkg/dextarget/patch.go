@@ -0,0 +1,98 @@
+package dextarget
+
+import (
+	"context"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2"
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+type kustomizationPatch struct {
+	Target kustomizationPatchTarget `yaml:"target"`
+	Patch  string                   `yaml:"patch"`
+}
+
+type kustomizationPatchTarget struct {
+	Group     string `yaml:"group"`
+	Version   string `yaml:"version"`
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type jsonPatchOperation struct {
+	Op    string      `yaml:"op"`
+	Path  string      `yaml:"path"`
+	Value interface{} `yaml:"value"`
+}
+
+type valuesReference struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	ValuesKey string `yaml:"valuesKey"`
+}
+
+// GetSecretConfigPatch returns the kustomize patch adding the dex config secret to
+// spec.valuesFrom of the HelmRelease. It is meant to be added to the patches of the
+// Kustomization in Git which manages a Flux HelmRelease.
+func (h *HelmReleaseTarget) GetSecretConfigPatch(secretName string) (string, error) {
+	ref := valuesReference{
+		Kind:      "Secret",
+		Name:      secretName,
+		ValuesKey: "default",
+	}
+
+	// Appending to a list requires the list to exist, so an empty valuesFrom is added as a whole.
+	operation := jsonPatchOperation{Op: "add", Path: "/spec/valuesFrom/-", Value: ref}
+	if len(h.Spec.ValuesFrom) == 0 {
+		operation = jsonPatchOperation{Op: "add", Path: "/spec/valuesFrom", Value: []valuesReference{ref}}
+	}
+	operations, err := yaml.Marshal([]jsonPatchOperation{operation})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+
+	patch, err := yaml.Marshal([]kustomizationPatch{
+		{
+			Target: kustomizationPatchTarget{
+				Group:     helmv2.GroupVersion.Group,
+				Version:   helmv2.GroupVersion.Version,
+				Kind:      helmv2.HelmReleaseKind,
+				Name:      h.Name,
+				Namespace: h.Namespace,
+			},
+			Patch: string(operations),
+		},
+	})
+	if err != nil {
+		return "", microerror.Mask(err)
+	}
+	return string(patch), nil
+}
+
+// ListHelmReleasesMissingSecretConfig returns all dex HelmReleases managed by Flux
+// which do not reference their dex config secret in spec.valuesFrom.
+func ListHelmReleasesMissingSecretConfig(ctx context.Context, c client.Client) ([]*HelmReleaseTarget, error) {
+	helmReleases := &helmv2.HelmReleaseList{}
+	if err := c.List(ctx, helmReleases); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	targets := []*HelmReleaseTarget{}
+	for i := range helmReleases.Items {
+		hr := &helmReleases.Items[i]
+		if hr.Labels[key.AppLabel] != key.DexAppLabelValue && !key.IsManagementClusterDexHelmRelease(hr.Name, hr.Namespace) {
+			continue
+		}
+		target := NewHelmReleaseTarget(hr)
+		if target.ManagesSecretConfig() || target.HasSecretConfig(key.GetDexConfigName(hr.Name)) {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
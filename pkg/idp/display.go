@@ -0,0 +1,118 @@
+package idp
+
+import (
+	"sort"
+
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+// ConnectorDisplayOverride overrides the display settings of a single connector for one dex target.
+// Unset fields keep the settings from the credentials file.
+type ConnectorDisplayOverride struct {
+	Priority *int    `yaml:"priority,omitempty"`
+	Icon     *string `yaml:"icon,omitempty"`
+	Hidden   *bool   `yaml:"hidden,omitempty"`
+	Disabled *bool   `yaml:"disabled,omitempty"`
+}
+
+// getConnectorDisplayOverrides parses the connector display annotation of the target.
+func (s *Service) getConnectorDisplayOverrides() (map[string]ConnectorDisplayOverride, error) {
+	overrides := map[string]ConnectorDisplayOverride{}
+	value, ok := s.target.GetObject().GetAnnotations()[key.ConnectorDisplayAnnotation]
+	if !ok {
+		return overrides, nil
+	}
+	if err := yaml.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, microerror.Maskf(invalidConfigError, "annotation %s is not valid: %s", key.ConnectorDisplayAnnotation, err)
+	}
+	return overrides, nil
+}
+
+// getConnectorDisplay returns the display settings of the connector of the provider
+// with the overrides of the target applied.
+func getConnectorDisplay(p provider.Provider, overrides map[string]ConnectorDisplayOverride) provider.ConnectorDisplay {
+	display := p.GetConnectorDisplay()
+	override, ok := overrides[p.GetName()]
+	if !ok {
+		return display
+	}
+	if override.Priority != nil {
+		display.Priority = *override.Priority
+	}
+	if override.Icon != nil {
+		display.Icon = *override.Icon
+	}
+	if override.Hidden != nil {
+		display.Hidden = *override.Hidden
+	}
+	if override.Disabled != nil {
+		display.Disabled = *override.Disabled
+	}
+	return display
+}
+
+func applyConnectorDisplay(connector dex.Connector, display provider.ConnectorDisplay) dex.Connector {
+	connector.Priority = display.Priority
+	connector.Icon = display.Icon
+	connector.Hidden = display.Hidden
+	return connector
+}
+
+// sortConnectors orders connectors by descending priority.
+// Connectors with the same priority keep the order of the providers in the credentials file.
+func sortConnectors(connectors []dex.Connector) {
+	sort.SliceStable(connectors, func(i, j int) bool {
+		return connectors[i].Priority > connectors[j].Priority
+	})
+}
+
+// sortOwner orders the connectors of the owner and sets the priority of the owner to the highest priority
+// of its connectors, so that the owner groups on the login page follow the connector priorities across owners.
+func sortOwner(owner *dex.DexOidcOwner) {
+	sortConnectors(owner.Connectors)
+	owner.Priority = 0
+	if len(owner.Connectors) > 0 {
+		owner.Priority = owner.Connectors[0].Priority
+	}
+}
+
+// getReorderedOwners returns the owners whose connectors, while otherwise unchanged, are in a different order
+// or whose priority among the owners changed.
+func getReorderedOwners(oldOidc dex.DexOidc, newOidc dex.DexOidc) []string {
+	owners := []string{}
+	for _, name := range newOidc.GetOwnerNames() {
+		oldOwner := oldOidc.GetOwner(name)
+		if oldOwner == nil {
+			continue
+		}
+		if oldOwner.Priority != newOidc.GetOwner(name).Priority {
+			owners = append(owners, name)
+			continue
+		}
+		oldIDs := getConnectorIDs(oldOwner.Connectors)
+		newIDs := getConnectorIDs(newOidc.GetOwner(name).Connectors)
+		if len(oldIDs) != len(newIDs) {
+			continue
+		}
+		for i := range newIDs {
+			if oldIDs[i] != newIDs[i] {
+				owners = append(owners, name)
+				break
+			}
+		}
+	}
+	return owners
+}
+
+func getConnectorIDs(connectors []dex.Connector) []string {
+	ids := []string{}
+	for _, connector := range connectors {
+		ids = append(ids, connector.ID)
+	}
+	return ids
+}
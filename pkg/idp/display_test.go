@@ -0,0 +1,195 @@
+package idp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestCreateProviderAppsDisplay(t *testing.T) {
+	testCases := []struct {
+		name               string
+		credentials        []provider.ProviderCredential
+		annotation         string
+		expectedConnectors map[string][]dex.Connector
+		expectedOwners     []string
+		expectError        bool
+	}{
+		{
+			name: "case 0: connectors ordered by priority",
+			credentials: []provider.ProviderCredential{
+				{Name: mockprovider.ProviderName, Owner: key.OwnerGiantswarm},
+				{Name: mockprovider.ProviderName, Owner: key.OwnerCustomer, Display: provider.ConnectorDisplay{Priority: 10, Icon: "sso.svg"}},
+				{Name: mockprovider.ProviderName, Owner: key.OwnerCustomer, Description: "Other"},
+			},
+			expectedConnectors: map[string][]dex.Connector{
+				key.OwnerGiantswarm: {{ID: "giantswarm-mock"}},
+				key.OwnerCustomer:   {{ID: "customer-mock", Priority: 10, Icon: "sso.svg"}, {ID: "customer-mock"}},
+			},
+		},
+		{
+			name: "case 1: hidden and disabled connectors",
+			credentials: []provider.ProviderCredential{
+				{Name: mockprovider.ProviderName, Owner: key.OwnerGiantswarm, Display: provider.ConnectorDisplay{Hidden: true}},
+				{Name: mockprovider.ProviderName, Owner: key.OwnerCustomer, Display: provider.ConnectorDisplay{Disabled: true}},
+			},
+			expectedConnectors: map[string][]dex.Connector{
+				key.OwnerGiantswarm: {{ID: "giantswarm-mock", Hidden: true}},
+			},
+		},
+		{
+			name: "case 2: overrides by annotation",
+			credentials: []provider.ProviderCredential{
+				{Name: mockprovider.ProviderName, Owner: key.OwnerGiantswarm, Display: provider.ConnectorDisplay{Hidden: true, Icon: "gs.svg"}},
+				{Name: mockprovider.ProviderName, Owner: key.OwnerCustomer, Display: provider.ConnectorDisplay{Disabled: true}},
+			},
+			annotation: `giantswarm-mock:
+  hidden: false
+  priority: 5
+customer-mock:
+  disabled: false
+`,
+			expectedConnectors: map[string][]dex.Connector{
+				key.OwnerGiantswarm: {{ID: "giantswarm-mock", Priority: 5, Icon: "gs.svg"}},
+				key.OwnerCustomer:   {{ID: "customer-mock"}},
+			},
+		},
+		{
+			name: "case 3: invalid annotation",
+			credentials: []provider.ProviderCredential{
+				{Name: mockprovider.ProviderName, Owner: key.OwnerGiantswarm},
+			},
+			annotation:  "giantswarm-mock: [",
+			expectError: true,
+		},
+		{
+			name: "case 4: owners ordered by the priorities of their connectors",
+			credentials: []provider.ProviderCredential{
+				{Name: mockprovider.ProviderName, Owner: key.OwnerGiantswarm, Display: provider.ConnectorDisplay{Priority: 100}},
+				{Name: mockprovider.ProviderName, Owner: key.OwnerCustomer, Display: provider.ConnectorDisplay{Priority: -10}},
+			},
+			expectedConnectors: map[string][]dex.Connector{
+				key.OwnerGiantswarm: {{ID: "giantswarm-mock", Priority: 100}},
+				key.OwnerCustomer:   {{ID: "customer-mock", Priority: -10}},
+			},
+			expectedOwners: []string{key.OwnerGiantswarm, key.OwnerCustomer},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := getExampleApp()
+			if tc.annotation != "" {
+				app.Annotations = map[string]string{key.ConnectorDisplayAnnotation: tc.annotation}
+			}
+			providers := []provider.Provider{}
+			for _, c := range tc.credentials {
+				p, err := mockprovider.New(provider.ProviderConfig{Credential: c})
+				if err != nil {
+					t.Fatal(err)
+				}
+				providers = append(providers, p)
+			}
+			s := Service{
+				providers: providers,
+				log:       ctrl.Log.WithName("test"),
+				target:    dextarget.NewAppTarget(app),
+			}
+			config, err := s.CreateOrUpdateProviderApps(provider.GetTestConfig(), context.Background(), map[string]dex.Connector{})
+			if tc.expectError {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := map[string][]dex.Connector{}
+			for _, name := range config.Oidc.GetOwnerNames() {
+				for _, c := range config.Oidc.GetOwner(name).Connectors {
+					result[name] = append(result[name], dex.Connector{ID: c.ID, Priority: c.Priority, Icon: c.Icon, Hidden: c.Hidden})
+				}
+			}
+			if !reflect.DeepEqual(result, tc.expectedConnectors) {
+				t.Fatalf("expected %v, got %v", tc.expectedConnectors, result)
+			}
+			if owners := config.Oidc.GetOrderedOwnerNames(); tc.expectedOwners != nil && !reflect.DeepEqual(owners, tc.expectedOwners) {
+				t.Fatalf("expected owners %v, got %v", tc.expectedOwners, owners)
+			}
+		})
+	}
+}
+
+func TestGetReorderedOwners(t *testing.T) {
+	oidc := func(ids ...string) dex.DexOidc {
+		connectors := []dex.Connector{}
+		for _, id := range ids {
+			connectors = append(connectors, dex.Connector{ID: id})
+		}
+		return dex.DexOidc{Owners: map[string]*dex.DexOidcOwner{key.OwnerCustomer: {Connectors: connectors}}}
+	}
+
+	testCases := []struct {
+		name      string
+		oldOidc   dex.DexOidc
+		newOidc   dex.DexOidc
+		reordered []string
+	}{
+		{
+			name:      "case 0: same order",
+			oldOidc:   oidc("a", "b"),
+			newOidc:   oidc("a", "b"),
+			reordered: []string{},
+		},
+		{
+			name:      "case 1: reordered",
+			oldOidc:   oidc("a", "b"),
+			newOidc:   oidc("b", "a"),
+			reordered: []string{key.OwnerCustomer},
+		},
+		{
+			name:      "case 2: connector added",
+			oldOidc:   oidc("a"),
+			newOidc:   oidc("b", "a"),
+			reordered: []string{},
+		},
+		{
+			name:      "case 3: new owner",
+			oldOidc:   dex.DexOidc{},
+			newOidc:   oidc("a", "b"),
+			reordered: []string{},
+		},
+		{
+			name:    "case 4: owner priority changed",
+			oldOidc: oidc("a", "b"),
+			newOidc: func() dex.DexOidc {
+				o := oidc("a", "b")
+				o.Owners[key.OwnerCustomer].Priority = 10
+				return o
+			}(),
+			reordered: []string{key.OwnerCustomer},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reordered := getReorderedOwners(tc.oldOidc, tc.newOidc)
+			if !reflect.DeepEqual(reordered, tc.reordered) {
+				t.Fatalf("expected %v, got %v", tc.reordered, reordered)
+			}
+			s := Service{log: ctrl.Log.WithName("test")}
+			if len(tc.reordered) > 0 && !s.secretDataNeedsUpdate(dex.DexConfig{Oidc: tc.oldOidc}, dex.DexConfig{Oidc: tc.newOidc}) {
+				t.Fatal("expected reordering to require an update")
+			}
+		})
+	}
+}
@@ -12,3 +12,12 @@ var invalidConfigError = &microerror.Error{
 func IsInvalidConfig(err error) bool {
 	return microerror.Cause(err) == invalidConfigError
 }
+
+var sweepFailedError = &microerror.Error{
+	Kind: "sweepFailedError",
+}
+
+// IsSweepFailed asserts sweepFailedError.
+func IsSweepFailed(err error) bool {
+	return microerror.Cause(err) == sweepFailedError
+}
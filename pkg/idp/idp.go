@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 
@@ -12,6 +14,8 @@ import (
 	"github.com/giantswarm/dex-operator/pkg/dextarget"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 
 	"github.com/giantswarm/k8smetadata/pkg/label"
 	"github.com/giantswarm/microerror"
@@ -21,6 +25,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -41,6 +46,28 @@ type Config struct {
 	// Scheme is required for setting OwnerReferences.
 	Scheme *runtime.Scheme
 
+	// DryRun makes the service compute and report changes without writing to
+	// Kubernetes. Providers are expected to be configured in read-only mode as well.
+	DryRun bool
+
+	// DeletionPolicy decides whether identity provider app registrations are deleted together
+	// with the dex target. It can be overridden per target with the deletion policy annotation.
+	DeletionPolicy DeletionPolicy
+	// RetentionNamespace is the namespace in which retained app registrations are recorded,
+	// usually the namespace of dex-operator. Defaults to giantswarm.
+	RetentionNamespace string
+
+	// StaticClients are added to the dex config of every target with generated client secrets.
+	StaticClients []StaticClient
+
+	// RotationPolicy restricts scheduled rotations of client secrets to rotation windows and caps
+	// concurrent rotations. It is shared by all reconcilers. Rotations are not restricted if nil.
+	RotationPolicy *rotation.Policy
+
+	// Recorder records events on the dex target, e.g. if a dex config is not rolled out in time.
+	// Events are not recorded if nil.
+	Recorder record.EventRecorder
+
 	// Deprecated: Use Target instead. App is kept for backward compatibility.
 	// If Target is nil and App is set, App will be wrapped in an AppTarget.
 	App *v1alpha1.App
@@ -56,6 +83,15 @@ type Service struct {
 	managementClusterIssuerAddress string
 	owner                          client.Object
 	scheme                         *runtime.Scheme
+	dryRun                         bool
+	deletionPolicy                 DeletionPolicy
+	retentionNamespace             string
+	staticClients                  []StaticClient
+	rotationPolicy                 *rotation.Policy
+	recorder                       record.EventRecorder
+
+	// secretCreated is set once a provider created a client secret which dex still needs to pick up.
+	secretCreated bool
 }
 
 func New(c Config) (*Service, error) {
@@ -89,6 +125,27 @@ func New(c Config) (*Service, error) {
 	if c.Scheme == nil {
 		return nil, microerror.Maskf(invalidConfigError, "scheme cannot be nil")
 	}
+	for _, p := range c.Providers {
+		if _, err := parseProviderSelector(p.GetSelector()); err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "provider %s: %s", p.GetName(), err)
+		}
+		var maxJitter time.Duration
+		if c.RotationPolicy != nil {
+			maxJitter = c.RotationPolicy.MaxJitter()
+		}
+		if _, err := parseSecretLifetime(p.GetSecretLifetime(), maxJitter); err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "provider %s: %s", p.GetName(), err)
+		}
+	}
+	// static clients are parsed on a copy since the configuration is shared between reconciliations
+	staticClients := append([]StaticClient(nil), c.StaticClients...)
+	if err := parseStaticClients(staticClients); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	retentionNamespace := c.RetentionNamespace
+	if retentionNamespace == "" {
+		retentionNamespace = key.MCDexAppDefaultNamespace
+	}
 	s := &Service{
 		Client:                         c.Client,
 		target:                         target,
@@ -99,44 +156,66 @@ func New(c Config) (*Service, error) {
 		managementClusterIssuerAddress: c.ManagementClusterIssuerAddress,
 		owner:                          c.Owner,
 		scheme:                         c.Scheme,
+		dryRun:                         c.DryRun,
+		deletionPolicy:                 c.DeletionPolicy,
+		retentionNamespace:             retentionNamespace,
+		staticClients:                  staticClients,
+		rotationPolicy:                 c.RotationPolicy,
+		recorder:                       c.Recorder,
 	}
 
 	return s, nil
 }
 
 func (s *Service) Reconcile(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "idp.Service.Reconcile", tracing.TargetAttributes(s.target.GetTargetType(), s.target.GetNamespacedName())...)
+	err := s.reconcile(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) reconcile(ctx context.Context) error {
 	// We do not handle targets that have connectors in user configs set up due to a bug where configuration in secrets can be overwritten
 	//TODO: solve this gracefully
 	hasUserConnectors, err := s.target.HasUserConfigWithConnectors(ctx, s.Client)
 	if err != nil {
 		return microerror.Mask(err)
 	}
+	nn := s.target.GetNamespacedName()
 	if hasUserConnectors {
 		s.log.Info(fmt.Sprintf("Dex %s has user config with connector configuration. Cancelling reconciliation. We recommend to move configuration to a managed secret.", s.target.GetTargetType()))
-		return s.ReconcileDelete(ctx)
+		if err := s.ReconcileDelete(ctx); err != nil {
+			return microerror.Mask(err)
+		}
+		UserConfigConnectors.WithLabelValues(nn.Name, nn.Namespace).Set(1)
+		return nil
 	}
+	UserConfigConnectors.DeleteLabelValues(nn.Name, nn.Namespace)
 
-	nn := s.target.GetNamespacedName()
 	secretName := key.GetDexConfigName(nn.Name)
 
 	// For App CR targets, inject the secret reference into the target if not present.
 	// For HelmRelease targets the entry is declared in the Git-managed manifest upfront,
 	// so dex-operator must not touch spec.valuesFrom.
 	if s.target.ManagesSecretConfig() && !s.target.HasSecretConfig(secretName) {
-		if err := s.target.AddSecretConfig(secretName, nn.Namespace); err != nil {
-			return microerror.Mask(err)
-		}
-		if _, err := s.target.AttachSecretConfig(ctx, s.Client); err != nil {
-			return microerror.Mask(err)
+		if s.dryRun {
+			s.log.Info(fmt.Sprintf("Dry run: would add secret config %s to dex %s instance.", secretName, s.target.GetTargetType()))
+		} else {
+			if err := s.target.AddSecretConfig(secretName, nn.Namespace); err != nil {
+				return microerror.Mask(err)
+			}
+			if _, err := s.target.AttachSecretConfig(ctx, s.Client); err != nil {
+				return microerror.Mask(err)
+			}
+			s.log.Info(fmt.Sprintf("Added secret config to dex %s instance.", s.target.GetTargetType()))
 		}
-		s.log.Info(fmt.Sprintf("Added secret config to dex %s instance.", s.target.GetTargetType()))
 	}
 
 	// Warn if a HelmRelease does not reference the dex config secret. dex-operator
 	// will still create and update the secret, but dex-app will not load it until
 	// the reference is added to the HelmRelease manifest.
 	if !s.target.ManagesSecretConfig() && !s.target.HasSecretConfig(secretName) {
-		s.log.Info(fmt.Sprintf("WARNING: dex %s does not reference secret %s in its config. dex-operator will manage the secret contents but dex-app will not load connectors until the reference is added to the HelmRelease manifest.", s.target.GetTargetType(), secretName))
+		s.log.Info(fmt.Sprintf("WARNING: dex %s does not reference secret %s in its config. dex-operator will manage the secret contents but dex-app will not load connectors until the reference is added to the HelmRelease manifest. The patch adding it is published in configmap %s.", s.target.GetTargetType(), secretName, key.GetSecretConfigPatchName(nn.Name)))
 	}
 
 	// Fetch secret
@@ -144,6 +223,9 @@ func (s *Service) Reconcile(ctx context.Context) error {
 	if err := s.Get(ctx, types.NamespacedName{Name: secretName, Namespace: nn.Namespace}, secret); err != nil {
 		if !apierrors.IsNotFound(err) {
 			return microerror.Mask(err)
+		} else if s.dryRun {
+			secret = GetDefaultDexConfigSecret(secretName, nn.Namespace)
+			s.log.Info(fmt.Sprintf("Dry run: would create default dex config secret %s/%s for dex %s instance.", nn.Namespace, secretName, s.target.GetTargetType()))
 		} else {
 			secret = GetDefaultDexConfigSecret(secretName, nn.Namespace)
 			// Set OwnerReference so that the controller watches this secret
@@ -157,6 +239,14 @@ func (s *Service) Reconcile(ctx context.Context) error {
 			s.log.Info(fmt.Sprintf("Created default dex config secret for dex %s instance.", s.target.GetTargetType()))
 		}
 	}
+	// A previous dex target with the same name may have retained its app registrations.
+	// They are reused from now on, so they must not be removed by the retention sweeper.
+	if !s.dryRun {
+		if err := s.releaseRetainedProviderApps(ctx); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+
 	{
 		// Get existing connectors from the dex config secret
 		oldConfig, err := getDexConfigFromSecret(secret)
@@ -169,11 +259,37 @@ func (s *Service) Reconcile(ctx context.Context) error {
 		if err != nil {
 			return microerror.Mask(err)
 		}
+		appConfig.DexConfigRolledOut, err = s.isDexConfigRolledOut(ctx, secret)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		reserved := s.prepareRotation(&appConfig)
 		newConfig, err := s.CreateOrUpdateProviderApps(appConfig, ctx, oldConnectors)
+		s.finishRotation(reserved)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		// providers which are selected again reuse their retained app registrations
+		if !s.dryRun {
+			for name := range getConnectorsFromConfig(newConfig) {
+				if _, ok := oldConnectors[name]; ok {
+					continue
+				}
+				if err := s.releaseRetainedProviderApp(ctx, name); err != nil {
+					return microerror.Mask(err)
+				}
+			}
+		}
+		newConfig.Oidc.StaticClients, err = s.reconcileStaticClients(ctx, appConfig)
 		if err != nil {
 			return microerror.Mask(err)
 		}
 
+		if s.dryRun {
+			s.reportDexConfigChanges(oldConfig, newConfig, nn.Namespace, secretName)
+			return nil
+		}
+
 		if updateSecret := s.secretDataNeedsUpdate(oldConfig, newConfig); updateSecret {
 			data, err := json.Marshal(newConfig)
 			if err != nil {
@@ -184,6 +300,16 @@ func (s *Service) Reconcile(ctx context.Context) error {
 				return microerror.Mask(err)
 			}
 			secret.Data = map[string][]byte{"default": data}
+			if secret.Annotations == nil {
+				secret.Annotations = map[string]string{}
+			}
+			secret.Annotations[key.DexConfigUpdatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+			delete(secret.Annotations, key.DexConfigRolledOutAtAnnotation)
+			if checksum := s.getDexConfigChecksum(ctx); checksum != "" {
+				secret.Annotations[key.DexConfigPreviousChecksumAnnotation] = checksum
+			} else {
+				delete(secret.Annotations, key.DexConfigPreviousChecksumAnnotation)
+			}
 			if err := s.Update(ctx, secret); err != nil {
 				return microerror.Mask(err)
 			}
@@ -203,8 +329,22 @@ func (s *Service) Reconcile(ctx context.Context) error {
 }
 
 func (s *Service) ReconcileDelete(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "idp.Service.ReconcileDelete", tracing.TargetAttributes(s.target.GetTargetType(), s.target.GetNamespacedName())...)
+	err := s.reconcileDelete(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) reconcileDelete(ctx context.Context) error {
 	nn := s.target.GetNamespacedName()
 	secretName := key.GetDexConfigName(nn.Name)
+	UserConfigConnectors.DeleteLabelValues(nn.Name, nn.Namespace)
+	s.releaseRotation()
+
+	if s.dryRun {
+		s.reportDeletion(secretName)
+		return nil
+	}
 
 	// Check if secret config is present
 	if s.target.HasSecretConfig(secretName) {
@@ -214,9 +354,20 @@ func (s *Service) ReconcileDelete(ctx context.Context) error {
 				return microerror.Mask(err)
 			}
 		} else {
-			if err := s.DeleteProviderApps(key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name), ctx); err != nil {
+			policy, err := s.getDeletionPolicy()
+			if err != nil {
 				return microerror.Mask(err)
 			}
+			appName := key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name)
+			if policy.Retain {
+				if err := s.retainProviderApps(ctx, appName, policy); err != nil {
+					return microerror.Mask(err)
+				}
+			} else {
+				if err := s.DeleteProviderApps(appName, ctx); err != nil {
+					return microerror.Mask(err)
+				}
+			}
 			// remove finalizer
 			if controllerutil.ContainsFinalizer(secret, key.DexOperatorFinalizer) {
 				controllerutil.RemoveFinalizer(secret, key.DexOperatorFinalizer)
@@ -256,57 +407,149 @@ func (s *Service) ReconcileDelete(ctx context.Context) error {
 
 func (s *Service) CreateOrUpdateProviderApps(appConfig provider.AppConfig, ctx context.Context, oldConnectors map[string]dex.Connector) (dex.DexConfig, error) {
 	dexConfig := dex.DexConfig{}
-	customerOidcOwner := dex.DexOidcOwner{}
-	giantswarmOidcOwner := dex.DexOidcOwner{}
+	owners := map[string]*dex.DexOidcOwner{}
 	nn := s.target.GetNamespacedName()
-	for _, provider := range s.providers {
+	overrides, err := s.getConnectorDisplayOverrides()
+	if err != nil {
+		return dexConfig, microerror.Mask(err)
+	}
+	providers, err := s.getSelectedProviders(ctx)
+	if err != nil {
+		return dexConfig, microerror.Mask(err)
+	}
+	if err := s.removeDeselectedProviderApps(ctx, appConfig.Name, providers, oldConnectors); err != nil {
+		return dexConfig, microerror.Mask(err)
+	}
+	for _, provider := range providers {
+		if !key.IsValidOwner(provider.GetOwner()) || dex.IsReservedOwner(provider.GetOwner()) {
+			return dexConfig, microerror.Maskf(invalidConfigError, "Owner %s of provider %s is not valid.", provider.GetOwner(), provider.GetName())
+		}
+		display := getConnectorDisplay(provider, overrides)
+		if display.Disabled {
+			// the app registration is kept so that the connector can be enabled again without changes in the identity provider
+			s.log.Info(fmt.Sprintf("Skipping disabled connector %s.", provider.GetName()))
+			AppInfo.DeleteLabelValues(nn.Name, nn.Namespace, provider.GetOwner(), provider.GetType(), provider.GetName(), appConfig.Name)
+			continue
+		}
+		lifetime, err := s.getSecretLifetime(provider)
+		if err != nil {
+			return dexConfig, microerror.Mask(err)
+		}
 		// Create the app on the identity provider
-		providerApp, err := provider.CreateOrUpdateApp(appConfig, ctx, oldConnectors[provider.GetName()])
+		providerCtx, span := tracing.Start(ctx, "provider.CreateOrUpdateApp", tracing.ProviderAttributes(provider.GetType(), provider.GetName(), provider.GetOwner())...)
+		providerApp, err := provider.CreateOrUpdateApp(lifetime.apply(appConfig), providerCtx, oldConnectors[provider.GetName()])
+		tracing.End(span, err)
 		if err != nil {
 			return dexConfig, err
 		}
 		// Add connector configuration to config
-		switch provider.GetOwner() {
-		case key.OwnerGiantswarm:
-			giantswarmOidcOwner.Connectors = append(giantswarmOidcOwner.Connectors, providerApp.Connector)
-		case key.OwnerCustomer:
-			customerOidcOwner.Connectors = append(customerOidcOwner.Connectors, providerApp.Connector)
-		default:
-			return dexConfig, microerror.Maskf(invalidConfigError, "Owner %s is not known.", provider.GetOwner())
+		owner, ok := owners[provider.GetOwner()]
+		if !ok {
+			owner = &dex.DexOidcOwner{}
+			owners[provider.GetOwner()] = owner
+		}
+		owner.Connectors = append(owner.Connectors, applyConnectorDisplay(providerApp.Connector, display))
+		if providerApp.SecretCreated {
+			s.secretCreated = true
 		}
 		AppInfo.WithLabelValues(nn.Name, nn.Namespace, provider.GetOwner(), provider.GetType(), provider.GetName(), appConfig.Name).Set(float64(providerApp.SecretEndDateTime.Unix()))
 	}
-	if len(customerOidcOwner.Connectors) > 0 {
-		dexConfig.Oidc.Customer = &customerOidcOwner
+	for _, owner := range owners {
+		sortOwner(owner)
 	}
-	if len(giantswarmOidcOwner.Connectors) > 0 {
-		dexConfig.Oidc.Giantswarm = &giantswarmOidcOwner
+	if len(owners) > 0 {
+		dexConfig.Oidc.Owners = owners
 	}
 	return dexConfig, nil
 }
 
 func (s *Service) DeleteProviderApps(appName string, ctx context.Context) error {
-	nn := s.target.GetNamespacedName()
 	for _, provider := range s.providers {
+		if err := s.deleteProviderApp(ctx, appName, provider); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	return nil
+}
 
-		if err := provider.DeleteApp(appName, ctx); err != nil {
+func (s *Service) deleteProviderApp(ctx context.Context, appName string, p provider.Provider) error {
+	nn := s.target.GetNamespacedName()
+	if err := p.DeleteApp(appName, ctx); err != nil {
+		return microerror.Mask(err)
+	}
+	s.log.Info(fmt.Sprintf("Deleted app %s of type %s for %s.", p.GetName(), p.GetType(), p.GetOwner()))
+	AppInfo.DeleteLabelValues(nn.Name, nn.Namespace, p.GetOwner(), p.GetType(), p.GetName(), appName)
+	return nil
+}
+
+// removeDeselectedProviderApps applies the deletion policy of the target to the app registrations of providers
+// whose connector is in the dex config but which are no longer selected for the target, e.g. since their
+// selector no longer matches or they are excluded by annotation.
+func (s *Service) removeDeselectedProviderApps(ctx context.Context, appName string, selected []provider.Provider, oldConnectors map[string]dex.Connector) error {
+	selectedNames := map[string]bool{}
+	for _, p := range selected {
+		selectedNames[p.GetName()] = true
+	}
+	for _, p := range s.providers {
+		if _, ok := oldConnectors[p.GetName()]; !ok || selectedNames[p.GetName()] {
+			continue
+		}
+		policy, err := s.getDeletionPolicy()
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		if s.dryRun {
+			s.log.Info(fmt.Sprintf("Dry run: would apply deletion policy %s to app %s of type %s for %s which is no longer selected.", policy, p.GetName(), p.GetType(), p.GetOwner()))
+			continue
+		}
+		if policy.Retain {
+			if err := s.retainProviderApp(ctx, appName, p, policy); err != nil {
+				return microerror.Mask(err)
+			}
+		} else if err := s.deleteProviderApp(ctx, appName, p); err != nil {
 			return microerror.Mask(err)
 		}
-		s.log.Info(fmt.Sprintf("Deleted app %s of type %s for %s.", provider.GetName(), provider.GetType(), provider.GetOwner()))
-		AppInfo.DeleteLabelValues(nn.Name, nn.Namespace, provider.GetOwner(), provider.GetType(), provider.GetName(), appName)
 	}
 	return nil
 }
 
 func (s *Service) secretDataNeedsUpdate(oldData dex.DexConfig, newData dex.DexConfig) bool {
-	if !s.oidcOwnerNeedsUpdate(oldData.Oidc.Giantswarm, newData.Oidc.Giantswarm) && !s.oidcOwnerNeedsUpdate(oldData.Oidc.Customer, newData.Oidc.Customer) {
+	if !reflect.DeepEqual(oldData.Oidc.StaticClients, newData.Oidc.StaticClients) {
+		s.log.Info("Static clients changed.")
+		return true
+	}
+	if !s.oidcOwnersNeedUpdate(oldData.Oidc, newData.Oidc) {
 		oldConnectors := getConnectorsFromConfig(oldData)
 		newConnectors := getConnectorsFromConfig(newData)
-		return s.connectorsNeedUpdate(oldConnectors, newConnectors)
+		if s.connectorsNeedUpdate(oldConnectors, newConnectors) {
+			return true
+		}
+		// the connectors are unchanged but the login page would show them in a different order
+		if reordered := getReorderedOwners(oldData.Oidc, newData.Oidc); len(reordered) > 0 {
+			s.log.Info(fmt.Sprintf("Changed order of connectors of %v.", reordered))
+			return true
+		}
+		return false
 	}
 	return true
 }
 
+func (s *Service) oidcOwnersNeedUpdate(oldOidc dex.DexOidc, newOidc dex.DexOidc) bool {
+	owners := map[string]bool{}
+	for _, name := range oldOidc.GetOwnerNames() {
+		owners[name] = true
+	}
+	for _, name := range newOidc.GetOwnerNames() {
+		owners[name] = true
+	}
+	for name := range owners {
+		if s.oidcOwnerNeedsUpdate(oldOidc.GetOwner(name), newOidc.GetOwner(name)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Service) oidcOwnerNeedsUpdate(oldOwner *dex.DexOidcOwner, newOwner *dex.DexOidcOwner) bool {
 	return (oldOwner != nil && newOwner == nil) ||
 		(oldOwner == nil && newOwner != nil) ||
@@ -321,11 +564,13 @@ func (s *Service) connectorsNeedUpdate(oldConnectors map[string]dex.Connector, n
 		if !exists {
 			needsUpdate = true
 			s.log.Info(fmt.Sprintf("Created app %s of type %s.", connector.Name, connector.Type))
+			s.countConnectorChange(connector, connectorAdded)
 		} else {
 			// connector has changed
 			if !reflect.DeepEqual(oldConnector, connector) {
 				needsUpdate = true
 				s.log.Info(fmt.Sprintf("Updated app %s of type %s.", connector.Name, connector.Type))
+				s.countConnectorChange(connector, connectorUpdated)
 			}
 		}
 	}
@@ -335,11 +580,20 @@ func (s *Service) connectorsNeedUpdate(oldConnectors map[string]dex.Connector, n
 		if !exists {
 			needsUpdate = true
 			s.log.Info(fmt.Sprintf("App %s of type %s was removed. Please check provider for possible leftovers", connector.Name, connector.Type))
+			s.countConnectorChange(connector, connectorRemoved)
 		}
 	}
 	return needsUpdate
 }
 
+// countConnectorChange counts changes of connectors. Changes are not counted in dry-run mode since they are not applied.
+func (s *Service) countConnectorChange(connector dex.Connector, change string) {
+	if s.dryRun {
+		return
+	}
+	ConnectorChanges.WithLabelValues(connector.Type, change).Inc()
+}
+
 func (s *Service) GetAppConfig(ctx context.Context) (provider.AppConfig, error) {
 	var baseDomain string
 	nn := s.target.GetNamespacedName()
@@ -358,12 +612,19 @@ func (s *Service) GetAppConfig(ctx context.Context) (provider.AppConfig, error)
 		baseDomain = getBaseDomainFromClusterValues(clusterValuesConfigmap)
 	}
 	issuerAddress := GetIssuerAddress(baseDomain, s.managementClusterIssuerAddress, s.managementClusterBaseDomain)
+	// Without cluster values, the base domain is the domain of the issuer, e.g. g8s.example.com for dex.g8s.example.com.
+	if baseDomain == "" {
+		_, baseDomain, _ = strings.Cut(issuerAddress, ".")
+	}
 
 	return provider.AppConfig{
-		Name:                 key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name),
-		RedirectURI:          key.GetRedirectURI(issuerAddress),
-		IdentifierURI:        key.GetIdentifierURI(key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name)),
-		SecretValidityMonths: key.SecretValidityMonths,
+		Name:              key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name),
+		RedirectURI:       key.GetRedirectURI(issuerAddress),
+		IssuerURI:         key.GetIssuerURI(issuerAddress),
+		BaseDomain:        baseDomain,
+		IdentifierURI:     key.GetIdentifierURI(key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name)),
+		SecretValidity:    key.DefaultSecretValidity,
+		SecretRenewBefore: key.DefaultSecretRenewBefore,
 	}, nil
 }
 
@@ -29,4 +29,97 @@ var (
 		},
 		infoLabels,
 	)
+
+	serviceCredentialLabels = []string{
+		"app_owner",
+		"provider_type",
+		"provider_name",
+	}
+
+	// ServiceCredentialCreated tracks the age of the credentials of dex-operator itself, e.g. the private key of its github app.
+	ServiceCredentialCreated = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "service_credential_created_time",
+			Help:      "Gives the creation time of the credentials dex-operator uses to manage identity providers.",
+		},
+		serviceCredentialLabels,
+	)
+
+	// ServiceCredentialExpiry tracks when the credentials of dex-operator itself expire, e.g. its azure client secret.
+	ServiceCredentialExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "service_credential_expiry_time",
+			Help:      "Gives the expiry time of the credentials dex-operator uses to manage identity providers.",
+		},
+		serviceCredentialLabels,
+	)
+
+	// ServiceCredentialLastRotation tracks the last successful and the last failed self-renewal per provider.
+	ServiceCredentialLastRotation = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "service_credential_last_rotation_time",
+			Help:      "Gives the time of the last successful or failed rotation of the credentials dex-operator uses to manage identity providers.",
+		},
+		append(serviceCredentialLabels, "outcome"),
+	)
+
+	// ServiceCredentialRotations counts self-renewal attempts per provider by outcome.
+	ServiceCredentialRotations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "service_credential_rotations_total",
+			Help:      "Counts rotations of the credentials dex-operator uses to manage identity providers by outcome.",
+		},
+		append(serviceCredentialLabels, "outcome"),
+	)
+
+	// ConnectorChanges counts connectors added, updated and removed in dex config secrets.
+	ConnectorChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "connector_changes_total",
+			Help:      "Counts connectors added, updated and removed in dex config secrets by connector type.",
+		},
+		[]string{
+			"connector_type",
+			"change",
+		},
+	)
+
+	// UserConfigConnectors is set for dex targets which are not reconciled since their user config contains connectors.
+	UserConfigConnectors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "user_config_connectors",
+			Help:      "Set to 1 for dex targets which are not reconciled since connectors are configured in their user config.",
+		},
+		[]string{
+			"app_name",
+			"app_namespace",
+		},
+	)
+
+	// MissingSecretConfig is set for Flux-managed HelmReleases which do not reference
+	// their dex config secret in spec.valuesFrom.
+	MissingSecretConfig = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "helmrelease_missing_secret_config",
+			Help:      "Set to 1 for Flux-managed dex HelmReleases which do not reference their dex config secret.",
+		},
+		[]string{
+			"app_name",
+			"app_namespace",
+		},
+	)
 )
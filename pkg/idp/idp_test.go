@@ -15,8 +15,13 @@ import (
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -45,7 +50,28 @@ func TestCreateProviderApps(t *testing.T) {
 			name: "case 2",
 			providers: []provider.Provider{
 				getExampleProvider(key.OwnerGiantswarm),
-				getExampleProvider("somethingelse")},
+				getExampleProvider("partner")},
+			appConfig: provider.GetTestConfig(),
+		},
+		{
+			name: "case 3",
+			providers: []provider.Provider{
+				getExampleProvider(key.OwnerGiantswarm),
+				getExampleProvider("Some_Thing")},
+			appConfig:   provider.GetTestConfig(),
+			expectError: true,
+		},
+		{
+			name: "case 4",
+			providers: []provider.Provider{
+				getExampleProvider("staticClients")},
+			appConfig:   provider.GetTestConfig(),
+			expectError: true,
+		},
+		{
+			name: "case 5",
+			providers: []provider.Provider{
+				getExampleProvider("")},
 			appConfig:   provider.GetTestConfig(),
 			expectError: true,
 		},
@@ -296,28 +322,32 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 0: No changes",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+							},
 						},
 					},
 				},
 			},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+							},
 						},
 					},
 				},
@@ -328,29 +358,33 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 1: New connector",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+							},
 						},
 					},
 				},
 			},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
-							{ID: "third"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+								{ID: "third"},
+							},
 						},
 					},
 				},
@@ -361,29 +395,33 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 2: Connector removed",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
-							{ID: "third"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+								{ID: "third"},
+							},
 						},
 					},
 				},
 			},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+							},
 						},
 					},
 				},
@@ -394,28 +432,32 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 3: Updated config",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+							},
 						},
 					},
 				},
 			},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second", Config: "something"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second", Config: "something"},
+							},
 						},
 					},
 				},
@@ -426,30 +468,34 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 4: Updated various things",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "second", Config: "something"},
-							{ID: "fourth", Config: "somethingelse"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "second", Config: "something"},
+								{ID: "fourth", Config: "somethingelse"},
+							},
 						},
 					},
 				},
 			},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "first", Config: "something"},
-							{ID: "third"},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {
+							Connectors: []dex.Connector{
+								{ID: "first", Config: "something"},
+								{ID: "third"},
+							},
 						},
-					},
-					Giantswarm: &dex.DexOidcOwner{
-						Connectors: []dex.Connector{
-							{ID: "fourth", Config: "something"},
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "fourth", Config: "something"},
+							},
 						},
 					},
 				},
@@ -466,7 +512,9 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			name: "case 6: Update triggering empty case 1",
 			oldConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Customer: &dex.DexOidcOwner{},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerCustomer: {},
+					},
 				},
 			},
 			newConfig:    dex.DexConfig{},
@@ -477,7 +525,61 @@ func TestSecretDataNeedsUpdate(t *testing.T) {
 			oldConfig: dex.DexConfig{},
 			newConfig: dex.DexConfig{
 				Oidc: dex.DexOidc{
-					Giantswarm: &dex.DexOidcOwner{},
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerGiantswarm: {},
+					},
+				},
+			},
+			updateNeeded: true,
+		},
+		{
+			name: "case 8: Connector moved to another owner",
+			oldConfig: dex.DexConfig{
+				Oidc: dex.DexOidc{
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+								{ID: "second"},
+							},
+						},
+						"partner": {
+							Connectors: []dex.Connector{
+								{ID: "third"},
+							},
+						},
+					},
+				},
+			},
+			newConfig: dex.DexConfig{
+				Oidc: dex.DexOidc{
+					Owners: map[string]*dex.DexOidcOwner{
+						key.OwnerGiantswarm: {
+							Connectors: []dex.Connector{
+								{ID: "first"},
+							},
+						},
+						"partner": {
+							Connectors: []dex.Connector{
+								{ID: "second"},
+								{ID: "third"},
+							},
+						},
+					},
+				},
+			},
+			updateNeeded: true,
+		},
+		{
+			name: "case 9: Rotated static client secret",
+			oldConfig: dex.DexConfig{
+				Oidc: dex.DexOidc{
+					StaticClients: []dex.StaticClient{{ID: "grafana", Secret: "old"}},
+				},
+			},
+			newConfig: dex.DexConfig{
+				Oidc: dex.DexOidc{
+					StaticClients: []dex.StaticClient{{ID: "grafana", Secret: "new"}},
 				},
 			},
 			updateNeeded: true,
@@ -515,10 +617,13 @@ func TestGetAppConfig(t *testing.T) {
 			app:                            getExampleApp(),
 			clusterValuesConfigMap:         getClusterValuesConfigMap("baseDomain: wc.cluster.domain.io"),
 			expectedAppConfig: provider.AppConfig{
-				Name:                 "testcluster-example-test",
-				RedirectURI:          "https://dex.wc.cluster.domain.io/callback",
-				IdentifierURI:        "https://dex.giantswarm.io/testcluster-example-test",
-				SecretValidityMonths: key.SecretValidityMonths,
+				Name:              "testcluster-example-test",
+				RedirectURI:       "https://dex.wc.cluster.domain.io/callback",
+				IssuerURI:         "https://dex.wc.cluster.domain.io",
+				BaseDomain:        "wc.cluster.domain.io",
+				IdentifierURI:     "https://dex.giantswarm.io/testcluster-example-test",
+				SecretValidity:    key.DefaultSecretValidity,
+				SecretRenewBefore: key.DefaultSecretRenewBefore,
 			},
 		},
 		{
@@ -528,10 +633,13 @@ func TestGetAppConfig(t *testing.T) {
 			managementClusterIssuerAddress: "issuer.cluster.domain.io",
 			app:                            getExampleApp(),
 			expectedAppConfig: provider.AppConfig{
-				Name:                 "testcluster-example-test",
-				RedirectURI:          "https://issuer.cluster.domain.io/callback",
-				IdentifierURI:        "https://dex.giantswarm.io/testcluster-example-test",
-				SecretValidityMonths: key.SecretValidityMonths,
+				Name:              "testcluster-example-test",
+				RedirectURI:       "https://issuer.cluster.domain.io/callback",
+				IssuerURI:         "https://issuer.cluster.domain.io",
+				BaseDomain:        "cluster.domain.io",
+				IdentifierURI:     "https://dex.giantswarm.io/testcluster-example-test",
+				SecretValidity:    key.DefaultSecretValidity,
+				SecretRenewBefore: key.DefaultSecretRenewBefore,
 			},
 		},
 		{
@@ -540,10 +648,13 @@ func TestGetAppConfig(t *testing.T) {
 			managementClusterBaseDomain: "base.domain.io",
 			app:                         getExampleApp(),
 			expectedAppConfig: provider.AppConfig{
-				Name:                 "testcluster-example-test",
-				RedirectURI:          "https://dex.g8s.base.domain.io/callback",
-				IdentifierURI:        "https://dex.giantswarm.io/testcluster-example-test",
-				SecretValidityMonths: key.SecretValidityMonths,
+				Name:              "testcluster-example-test",
+				RedirectURI:       "https://dex.g8s.base.domain.io/callback",
+				IssuerURI:         "https://dex.g8s.base.domain.io",
+				BaseDomain:        "g8s.base.domain.io",
+				IdentifierURI:     "https://dex.giantswarm.io/testcluster-example-test",
+				SecretValidity:    key.DefaultSecretValidity,
+				SecretRenewBefore: key.DefaultSecretRenewBefore,
 			},
 		},
 	}
@@ -597,6 +708,114 @@ func getExampleProvider(owner string) provider.Provider {
 	return p
 }
 
+func TestConnectorsNeedUpdateCountsChanges(t *testing.T) {
+	connectorType := "count-test"
+	oldConnectors := map[string]dex.Connector{
+		"updated": {ID: "updated", Type: connectorType, Config: "old"},
+		"removed": {ID: "removed", Type: connectorType},
+		"same":    {ID: "same", Type: connectorType},
+	}
+	newConnectors := map[string]dex.Connector{
+		"updated": {ID: "updated", Type: connectorType, Config: "new"},
+		"added":   {ID: "added", Type: connectorType},
+		"same":    {ID: "same", Type: connectorType},
+	}
+	s := Service{
+		log: ctrl.Log.WithName("test"),
+	}
+
+	if !s.connectorsNeedUpdate(oldConnectors, newConnectors) {
+		t.Fatalf("Expected connectors to need an update.")
+	}
+	for _, change := range []string{connectorAdded, connectorUpdated, connectorRemoved} {
+		if value := testutil.ToFloat64(ConnectorChanges.WithLabelValues(connectorType, change)); value != 1 {
+			t.Errorf("Expected one %s change, got %v", change, value)
+		}
+	}
+
+	// changes are not applied in dry-run mode
+	s.dryRun = true
+	s.connectorsNeedUpdate(oldConnectors, newConnectors)
+	if value := testutil.ToFloat64(ConnectorChanges.WithLabelValues(connectorType, connectorAdded)); value != 1 {
+		t.Errorf("Expected changes not to be counted in dry-run mode, got %v", value)
+	}
+}
+
+func TestReconcileUserConfigConnectors(t *testing.T) {
+	app := getExampleApp()
+	app.Spec.UserConfig.ConfigMap = v1alpha1.AppSpecUserConfigConfigMap{
+		Name:      "test-user-values",
+		Namespace: "example",
+	}
+	userConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-user-values",
+			Namespace: "example",
+		},
+		Data: map[string]string{
+			key.ValuesConfigMapKey: "oidc:\n  connectors: []\n",
+		},
+	}
+	s := Service{
+		Client:    fake.NewClientBuilder().WithObjects(userConfig).Build(),
+		providers: []provider.Provider{getExampleProvider(key.OwnerGiantswarm)},
+		log:       ctrl.Log.WithName("test"),
+		target:    dextarget.NewAppTarget(app),
+	}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if value := testutil.ToFloat64(UserConfigConnectors.WithLabelValues("test", "example")); value != 1 {
+		t.Fatalf("Expected user config connectors to be reported, got %v", value)
+	}
+
+	if err := s.ReconcileDelete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if UserConfigConnectors.DeleteLabelValues("test", "example") {
+		t.Fatalf("Expected user config connectors metric to be removed on deletion.")
+	}
+}
+
+func TestCreateProviderAppsTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	s := Service{
+		providers: []provider.Provider{
+			getExampleProvider(key.OwnerGiantswarm),
+			getExampleProvider(key.OwnerCustomer)},
+		log:    ctrl.Log.WithName("test"),
+		target: dextarget.NewAppTarget(getExampleApp()),
+	}
+	ctx, parent := tracing.Start(context.Background(), "test")
+	if _, err := s.CreateOrUpdateProviderApps(provider.GetTestConfig(), ctx, map[string]dex.Connector{}); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	owners := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		if span.Name() != "provider.CreateOrUpdateApp" {
+			continue
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("Expected provider span to be a child of the reconciliation span.")
+		}
+		for _, attribute := range span.Attributes() {
+			if attribute.Key == tracing.ProviderOwnerKey {
+				owners[attribute.Value.AsString()] = true
+			}
+		}
+	}
+	if !reflect.DeepEqual(owners, map[string]bool{key.OwnerGiantswarm: true, key.OwnerCustomer: true}) {
+		t.Fatalf("Expected a provider span per owner, got %v", owners)
+	}
+}
+
 func getExampleApp() *v1alpha1.App {
 	return &v1alpha1.App{
 		ObjectMeta: metav1.ObjectMeta{
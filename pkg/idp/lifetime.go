@@ -0,0 +1,111 @@
+package idp
+
+import (
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+// secretLifetime holds the validity and renewal thresholds of client secrets of a provider.
+type secretLifetime struct {
+	validity              time.Duration
+	renewBefore           time.Duration
+	credentialRenewBefore time.Duration
+}
+
+// parseSecretLifetime parses the secret lifetime of a provider credential. Defaults apply to empty fields.
+// The renewal threshold needs to leave room for the rotation jitter, see validate.
+func parseSecretLifetime(l provider.SecretLifetime, maxJitter time.Duration) (secretLifetime, error) {
+	lifetime := secretLifetime{
+		validity:              key.DefaultSecretValidity,
+		renewBefore:           key.DefaultSecretRenewBefore,
+		credentialRenewBefore: key.DefaultCredentialRenewBefore,
+	}
+	fields := []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{name: "secretValidity", value: l.Validity, target: &lifetime.validity},
+		{name: "secretRenewBefore", value: l.RenewBefore, target: &lifetime.renewBefore},
+		{name: "credentialRenewBefore", value: l.CredentialRenewBefore, target: &lifetime.credentialRenewBefore},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		d, err := parseLifetimeDuration(f.value)
+		if err != nil {
+			return secretLifetime{}, microerror.Maskf(invalidConfigError, "%s %s", f.name, err)
+		}
+		*f.target = d
+	}
+	if lifetime.credentialRenewBefore >= lifetime.validity {
+		return secretLifetime{}, microerror.Maskf(invalidConfigError, "credentialRenewBefore %s needs to be shorter than secretValidity %s", lifetime.credentialRenewBefore, lifetime.validity)
+	}
+	if err := lifetime.validate(maxJitter); err != nil {
+		return secretLifetime{}, microerror.Mask(err)
+	}
+	return lifetime, nil
+}
+
+// getSecretLifetime returns the secret lifetime of the provider with the overrides of the target applied.
+func (s *Service) getSecretLifetime(p provider.Provider) (secretLifetime, error) {
+	lifetime, err := parseSecretLifetime(p.GetSecretLifetime(), s.maxRotationJitter())
+	if err != nil {
+		return secretLifetime{}, microerror.Maskf(invalidConfigError, "provider %s: %s", p.GetName(), err)
+	}
+	annotations := s.target.GetObject().GetAnnotations()
+	for annotation, target := range map[string]*time.Duration{
+		key.SecretValidityAnnotation:    &lifetime.validity,
+		key.SecretRenewBeforeAnnotation: &lifetime.renewBefore,
+	} {
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		d, err := parseLifetimeDuration(value)
+		if err != nil {
+			return secretLifetime{}, microerror.Maskf(invalidConfigError, "annotation %s %s", annotation, err)
+		}
+		*target = d
+	}
+	if err := lifetime.validate(s.maxRotationJitter()); err != nil {
+		return secretLifetime{}, microerror.Maskf(invalidConfigError, "provider %s: %s", p.GetName(), err)
+	}
+	return lifetime, nil
+}
+
+// validate makes sure that scheduled rotations happen before secrets are rotated urgently. Otherwise every new
+// secret would be due for urgent rotation right away, or the rotation jitter would postpone scheduled rotations
+// until they become urgent, and a new secret would be created in every reconciliation.
+func (l secretLifetime) validate(maxJitter time.Duration) error {
+	if l.validity <= l.renewBefore+key.UrgentRotationThreshold {
+		return microerror.Maskf(invalidConfigError, "secretValidity %s needs to be longer than secretRenewBefore %s plus %s", l.validity, l.renewBefore, key.UrgentRotationThreshold)
+	}
+	if l.renewBefore <= key.UrgentRotationThreshold {
+		return microerror.Maskf(invalidConfigError, "secretRenewBefore %s needs to be longer than %s", l.renewBefore, key.UrgentRotationThreshold)
+	}
+	if maxJitter > l.renewBefore-key.UrgentRotationThreshold {
+		return microerror.Maskf(invalidConfigError, "rotation jitter %s must not exceed secretRenewBefore %s minus %s", maxJitter, l.renewBefore, key.UrgentRotationThreshold)
+	}
+	return nil
+}
+
+// apply sets validity and renewal threshold of client secrets of dex apps in the app config.
+func (l secretLifetime) apply(appConfig provider.AppConfig) provider.AppConfig {
+	appConfig.SecretValidity = l.validity
+	appConfig.SecretRenewBefore = l.renewBefore
+	return appConfig
+}
+
+func parseLifetimeDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, microerror.Maskf(invalidConfigError, "%q is not a positive duration", value)
+	}
+	return d, nil
+}
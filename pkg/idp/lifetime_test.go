@@ -0,0 +1,134 @@
+package idp
+
+import (
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+)
+
+func TestGetSecretLifetime(t *testing.T) {
+	testCases := []struct {
+		name             string
+		lifetime         provider.SecretLifetime
+		annotations      map[string]string
+		maxJitter        time.Duration
+		expectedLifetime secretLifetime
+		expectError      bool
+	}{
+		{
+			name: "case 0: defaults",
+			expectedLifetime: secretLifetime{
+				validity:              key.DefaultSecretValidity,
+				renewBefore:           key.DefaultSecretRenewBefore,
+				credentialRenewBefore: key.DefaultCredentialRenewBefore,
+			},
+		},
+		{
+			name:     "case 1: configured per credential",
+			lifetime: provider.SecretLifetime{Validity: "8760h", RenewBefore: "720h", CredentialRenewBefore: "1440h"},
+			expectedLifetime: secretLifetime{
+				validity:              8760 * time.Hour,
+				renewBefore:           720 * time.Hour,
+				credentialRenewBefore: 1440 * time.Hour,
+			},
+		},
+		{
+			name:     "case 2: overridden per target",
+			lifetime: provider.SecretLifetime{Validity: "8760h", RenewBefore: "720h"},
+			annotations: map[string]string{
+				key.SecretValidityAnnotation:    "720h",
+				key.SecretRenewBeforeAnnotation: "168h",
+			},
+			expectedLifetime: secretLifetime{
+				validity:              720 * time.Hour,
+				renewBefore:           168 * time.Hour,
+				credentialRenewBefore: key.DefaultCredentialRenewBefore,
+			},
+		},
+		{
+			name:        "case 3: invalid credential duration",
+			lifetime:    provider.SecretLifetime{Validity: "12 months"},
+			expectError: true,
+		},
+		{
+			name:        "case 4: renewal threshold exceeds validity",
+			lifetime:    provider.SecretLifetime{Validity: "720h", RenewBefore: "720h", CredentialRenewBefore: "168h"},
+			expectError: true,
+		},
+		{
+			name:        "case 5: renewal threshold of the target exceeds validity",
+			annotations: map[string]string{key.SecretValidityAnnotation: "168h"},
+			expectError: true,
+		},
+		{
+			name:        "case 6: negative annotation",
+			annotations: map[string]string{key.SecretRenewBeforeAnnotation: "-24h"},
+			expectError: true,
+		},
+		{
+			name:        "case 7: validity leaves no time before urgent rotation",
+			lifetime:    provider.SecretLifetime{Validity: "12h", RenewBefore: "1h", CredentialRenewBefore: "1h"},
+			expectError: true,
+		},
+		{
+			name:        "case 8: renewal threshold within the urgent rotation threshold",
+			lifetime:    provider.SecretLifetime{Validity: "720h", RenewBefore: "12h", CredentialRenewBefore: "168h"},
+			expectError: true,
+		},
+		{
+			name:        "case 9: rotation jitter exceeds the renewal threshold of the target",
+			annotations: map[string]string{key.SecretRenewBeforeAnnotation: "48h"},
+			maxJitter:   48 * time.Hour,
+			expectError: true,
+		},
+		{
+			name:      "case 10: rotation jitter within the renewal threshold",
+			maxJitter: 48 * time.Hour,
+			expectedLifetime: secretLifetime{
+				validity:              key.DefaultSecretValidity,
+				renewBefore:           key.DefaultSecretRenewBefore,
+				credentialRenewBefore: key.DefaultCredentialRenewBefore,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := getExampleApp()
+			app.Annotations = tc.annotations
+			p := &mockprovider.MockProvider{Name: "mock", Lifetime: tc.lifetime}
+			rotationPolicy, err := rotation.NewPolicy(rotation.Config{MaxJitter: tc.maxJitter})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s := Service{
+				log:            ctrl.Log.WithName("test"),
+				target:         dextarget.NewAppTarget(app),
+				rotationPolicy: rotationPolicy,
+			}
+			lifetime, err := s.getSecretLifetime(p)
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectError {
+				t.Fatal("expected an error")
+			}
+			if err != nil {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if lifetime != tc.expectedLifetime {
+				t.Fatalf("expected %+v, got %+v", tc.expectedLifetime, lifetime)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package idp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	connectorAdded   = "add"
+	connectorUpdated = "update"
+	connectorRemoved = "remove"
+)
+
+// connectorChange describes a change to a single connector in the dex config secret.
+// Changed fields are tracked by name only so that secrets never end up in logs.
+type connectorChange struct {
+	id     string
+	action string
+	fields []string
+}
+
+func (c connectorChange) String() string {
+	if len(c.fields) == 0 {
+		return fmt.Sprintf("%s connector %s", c.action, c.id)
+	}
+	return fmt.Sprintf("%s connector %s (changed: %s)", c.action, c.id, strings.Join(c.fields, ", "))
+}
+
+// planConnectorChanges computes the changes needed to get from the old to the new connectors.
+func planConnectorChanges(oldConnectors map[string]dex.Connector, newConnectors map[string]dex.Connector) []connectorChange {
+	changes := []connectorChange{}
+	for id, connector := range newConnectors {
+		oldConnector, exists := oldConnectors[id]
+		if !exists {
+			changes = append(changes, connectorChange{id: id, action: connectorAdded})
+			continue
+		}
+		if fields := changedConnectorFields(oldConnector, connector); len(fields) > 0 {
+			changes = append(changes, connectorChange{id: id, action: connectorUpdated, fields: fields})
+		}
+	}
+	for id := range oldConnectors {
+		if _, exists := newConnectors[id]; !exists {
+			changes = append(changes, connectorChange{id: id, action: connectorRemoved})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].id < changes[j].id
+	})
+	return changes
+}
+
+func changedConnectorFields(oldConnector dex.Connector, newConnector dex.Connector) []string {
+	fields := []string{}
+	if oldConnector.Type != newConnector.Type {
+		fields = append(fields, "connectorType")
+	}
+	if oldConnector.Name != newConnector.Name {
+		fields = append(fields, "connectorName")
+	}
+	if oldConnector.Priority != newConnector.Priority {
+		fields = append(fields, "priority")
+	}
+	if oldConnector.Icon != newConnector.Icon {
+		fields = append(fields, "icon")
+	}
+	if oldConnector.Hidden != newConnector.Hidden {
+		fields = append(fields, "hidden")
+	}
+	if oldConnector.Config != newConnector.Config {
+		oldConfig := map[string]interface{}{}
+		newConfig := map[string]interface{}{}
+		if yaml.Unmarshal([]byte(oldConnector.Config), &oldConfig) != nil || yaml.Unmarshal([]byte(newConnector.Config), &newConfig) != nil {
+			return append(fields, "connectorConfig")
+		}
+		keys := map[string]bool{}
+		for k := range oldConfig {
+			keys[k] = true
+		}
+		for k := range newConfig {
+			keys[k] = true
+		}
+		configFields := []string{}
+		for k := range keys {
+			if !reflect.DeepEqual(oldConfig[k], newConfig[k]) {
+				configFields = append(configFields, fmt.Sprintf("connectorConfig.%s", k))
+			}
+		}
+		if len(configFields) == 0 {
+			configFields = append(configFields, "connectorConfig")
+		}
+		sort.Strings(configFields)
+		fields = append(fields, configFields...)
+	}
+	return fields
+}
+
+// reportDexConfigChanges logs the changes Reconcile would apply to the dex config secret in dry-run mode.
+func (s *Service) reportDexConfigChanges(oldConfig dex.DexConfig, newConfig dex.DexConfig, namespace string, secretName string) {
+	changes := planConnectorChanges(getConnectorsFromConfig(oldConfig), getConnectorsFromConfig(newConfig))
+	ownersChanged := s.oidcOwnersNeedUpdate(oldConfig.Oidc, newConfig.Oidc)
+	staticClientsChanged := !reflect.DeepEqual(oldConfig.Oidc.StaticClients, newConfig.Oidc.StaticClients)
+	if staticClientsChanged {
+		// client ids only, secrets must not end up in the logs
+		ids := []string{}
+		for _, c := range newConfig.Oidc.StaticClients {
+			ids = append(ids, c.ID)
+		}
+		s.log.Info(fmt.Sprintf("Dry run: would update static clients %v in dex config secret %s/%s.", ids, namespace, secretName))
+	}
+	reordered := getReorderedOwners(oldConfig.Oidc, newConfig.Oidc)
+	if len(reordered) > 0 {
+		s.log.Info(fmt.Sprintf("Dry run: would change order of connectors of %v in dex config secret %s/%s.", reordered, namespace, secretName))
+	}
+	if len(changes) == 0 && !ownersChanged {
+		if staticClientsChanged || len(reordered) > 0 {
+			return
+		}
+		s.log.Info(fmt.Sprintf("Dry run: no changes to dex config secret %s/%s.", namespace, secretName))
+		return
+	}
+	for _, change := range changes {
+		s.log.Info(fmt.Sprintf("Dry run: would %s in dex config secret %s/%s.", change, namespace, secretName))
+	}
+	if len(changes) == 0 {
+		s.log.Info(fmt.Sprintf("Dry run: would update connector owner groups in dex config secret %s/%s.", namespace, secretName))
+	}
+}
+
+// reportDeletion logs the changes ReconcileDelete would apply in dry-run mode.
+func (s *Service) reportDeletion(secretName string) {
+	nn := s.target.GetNamespacedName()
+	if !s.target.HasSecretConfig(secretName) {
+		s.log.Info(fmt.Sprintf("Dry run: no dex config secret referenced by dex %s instance, nothing to delete.", s.target.GetTargetType()))
+		return
+	}
+	appName := key.GetIdpAppName(s.managementClusterName, nn.Namespace, nn.Name)
+	if policy, err := s.getDeletionPolicy(); err != nil {
+		s.log.Error(err, "Dry run: invalid deletion policy, deletion would fail.")
+	} else if policy.Retain {
+		s.log.Info(fmt.Sprintf("Dry run: would retain app registrations %s according to deletion policy %s.", appName, policy))
+	} else {
+		for _, provider := range s.providers {
+			s.log.Info(fmt.Sprintf("Dry run: would delete app %s of type %s for %s.", appName, provider.GetType(), provider.GetOwner()))
+		}
+	}
+	s.log.Info(fmt.Sprintf("Dry run: would delete default dex config secret %s/%s.", nn.Namespace, secretName))
+	if s.target.ManagesSecretConfig() {
+		s.log.Info(fmt.Sprintf("Dry run: would remove dex config secret reference from dex %s instance.", s.target.GetTargetType()))
+	}
+}
@@ -0,0 +1,61 @@
+package idp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+)
+
+func TestPlanConnectorChanges(t *testing.T) {
+	testCases := []struct {
+		name            string
+		oldConnectors   map[string]dex.Connector
+		newConnectors   map[string]dex.Connector
+		expectedChanges []string
+	}{
+		{
+			name:            "case 0: No changes",
+			oldConnectors:   map[string]dex.Connector{"first": {ID: "first", Config: "clientID: a\n"}},
+			newConnectors:   map[string]dex.Connector{"first": {ID: "first", Config: "clientID: a\n"}},
+			expectedChanges: []string{},
+		},
+		{
+			name:          "case 1: Connector added and removed",
+			oldConnectors: map[string]dex.Connector{"first": {ID: "first"}},
+			newConnectors: map[string]dex.Connector{"second": {ID: "second"}},
+			expectedChanges: []string{
+				"remove connector first",
+				"add connector second",
+			},
+		},
+		{
+			name: "case 2: Secret changed, value is not reported",
+			oldConnectors: map[string]dex.Connector{
+				"first": {ID: "first", Config: "clientID: a\nclientSecret: old\nredirectURI: x\n"},
+			},
+			newConnectors: map[string]dex.Connector{
+				"first": {ID: "first", Config: "clientID: a\nclientSecret: new\nredirectURI: x\n"},
+			},
+			expectedChanges: []string{"update connector first (changed: connectorConfig.clientSecret)"},
+		},
+		{
+			name:            "case 3: Name changed",
+			oldConnectors:   map[string]dex.Connector{"first": {ID: "first", Name: "a"}},
+			newConnectors:   map[string]dex.Connector{"first": {ID: "first", Name: "b"}},
+			expectedChanges: []string{"update connector first (changed: connectorName)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes := []string{}
+			for _, c := range planConnectorChanges(tc.oldConnectors, tc.newConnectors) {
+				changes = append(changes, c.String())
+			}
+			if !reflect.DeepEqual(changes, tc.expectedChanges) {
+				t.Fatalf("Expected %v, got %v", tc.expectedChanges, changes)
+			}
+		})
+	}
+}
@@ -3,13 +3,16 @@ package azure
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/giantswarm/dex-operator/pkg/dex"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/dexidp/dex/connector/microsoft"
 	"github.com/giantswarm/backoff"
@@ -18,6 +21,7 @@ import (
 	"github.com/google/uuid"
 	azauth "github.com/microsoft/kiota-authentication-azure-go"
 	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
+	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
 	"github.com/microsoftgraph/msgraph-sdk-go/applications"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	"github.com/skratchdot/open-golang/open"
@@ -26,6 +30,35 @@ import (
 
 var _ provider.Provider = (*Azure)(nil)
 
+const (
+	verifyRetries  = 6
+	verifyInterval = 10 * time.Second
+)
+
+func newGraphClient(tenantID, clientID, clientSecret string) (*msgraphsdk.GraphServiceClient, *azidentity.ClientSecretCredential, error) {
+	// token and graph requests are traced as part of the reconciliation they belong to
+	cred, err := azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret, &azidentity.ClientSecretCredentialOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: &http.Client{Transport: tracing.NewTransport(http.DefaultTransport)},
+		},
+	})
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	auth, err := azauth.NewAzureIdentityAuthenticationProviderWithScopes(cred, ProviderScope())
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	clientOptions := msgraphsdk.GetDefaultClientOptions()
+	httpClient := msgraphcore.GetDefaultClient(&clientOptions)
+	httpClient.Transport = tracing.NewTransport(httpClient.Transport)
+	adapter, err := msgraphsdk.NewGraphRequestAdapterWithParseNodeFactoryAndSerializationWriterFactoryAndHttpClient(auth, nil, nil, httpClient)
+	if err != nil {
+		return nil, nil, microerror.Mask(err)
+	}
+	return msgraphsdk.NewGraphServiceClient(adapter), cred, nil
+}
+
 func (a *Azure) SupportsServiceCredentialRenewal() bool {
 	return true
 }
@@ -46,7 +79,7 @@ func (a *Azure) ShouldRotateServiceCredentials(ctx context.Context, config provi
 		"expiry", expiryTime,
 		"time_until_expiry", timeUntilExpiry)
 
-	return timeUntilExpiry < key.CredentialRenewalThreshold, nil
+	return timeUntilExpiry < config.SecretRenewBefore, nil
 }
 
 func (a *Azure) RotateServiceCredentials(ctx context.Context, config provider.AppConfig) (map[string]string, error) {
@@ -61,16 +94,96 @@ func (a *Azure) RotateServiceCredentials(ctx context.Context, config provider.Ap
 	return credentials, nil
 }
 
+// VerifyServiceCredentials requests a graph token with the credentials and reads the app of dex-operator with it.
+// New secrets can take a while to propagate in the tenant, so failed attempts are retried.
+func (a *Azure) VerifyServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	tenantID, clientID, clientSecret := credentials[TenantIDKey], credentials[ClientIDKey], credentials[ClientSecretKey]
+	if tenantID == "" {
+		tenantID = a.TenantID
+	}
+	if clientID == "" || clientSecret == "" {
+		return microerror.Maskf(invalidConfigError, "%s and %s must not be empty.", ClientIDKey, ClientSecretKey)
+	}
+	client, cred, err := newGraphClient(tenantID, clientID, clientSecret)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	o := func() error {
+		if _, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: ProviderScope()}); err != nil {
+			return microerror.Maskf(requestFailedError, "Failed to request graph token: %v", err)
+		}
+		result, err := client.Applications().Get(ctx, GetAppGetRequestConfig(config.Name))
+		if err != nil {
+			return microerror.Maskf(requestFailedError, "Failed to get applications: %s", PrintOdataError(err))
+		}
+		if count := result.GetOdataCount(); count == nil || *count != 1 {
+			return microerror.Maskf(notFoundError, "Could not read application %s.", config.Name)
+		}
+		return nil
+	}
+	b := backoff.NewMaxRetries(verifyRetries, verifyInterval)
+	if err := backoff.Retry(o, b); err != nil {
+		return microerror.Mask(err)
+	}
+	a.Log.Info("Verified Azure service credentials", "app", config.Name)
+	return nil
+}
+
+// RevertServiceCredentials removes the secret created by RotateServiceCredentials from the app of dex-operator.
+func (a *Azure) RevertServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	clientSecret := credentials[ClientSecretKey]
+	if clientSecret == "" || clientSecret == a.clientSecret {
+		return nil
+	}
+	app, err := a.GetApp(config.Name, ctx)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	id := app.GetId()
+	if id == nil {
+		return microerror.Maskf(notFoundError, "Could not find ID of app %s.", config.Name)
+	}
+	current, _ := splitSecrets(app, config.Name, clientSecret)
+	if current == nil {
+		return nil
+	}
+	if err := a.DeleteSecret(ctx, current.GetKeyId(), *id); err != nil {
+		return microerror.Mask(err)
+	}
+	a.Log.Info(fmt.Sprintf("Removed unverified secret %v of %s app %s in microsoft ad tenant %s", current.GetKeyId(), a.Type, config.Name, a.TenantID))
+	return nil
+}
+
+func (a *Azure) GetServiceCredentialCreatedAt() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (a *Azure) GetMissingServiceCredentialMetadata() map[string]string {
+	return nil
+}
+
+func (a *Azure) GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error) {
+	expiryTime, err := a.GetCredentialExpiry(ctx)
+	if err != nil {
+		return time.Time{}, false, microerror.Mask(err)
+	}
+	return expiryTime, true, nil
+}
+
 type Azure struct {
 	Name                  string
 	Description           string
 	Client                *msgraphsdk.GraphServiceClient
 	Log                   logr.Logger
 	Owner                 string
+	Display               provider.ConnectorDisplay
+	Selector              provider.ProviderSelector
+	Lifetime              provider.SecretLifetime
 	TenantID              string
 	Type                  string
 	clientSecret          string
 	managementClusterName string
+	dryRun                bool
 }
 
 type Config struct {
@@ -86,24 +199,9 @@ func New(config provider.ProviderConfig) (*Azure, error) {
 		return nil, microerror.Mask(err)
 	}
 
-	var client *msgraphsdk.GraphServiceClient
-	{
-		cred, err := azidentity.NewClientSecretCredential(c.TenantID, c.ClientID, c.ClientSecret, nil)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		auth, err := azauth.NewAzureIdentityAuthenticationProviderWithScopes(cred, ProviderScope())
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		adapter, err := msgraphsdk.NewGraphRequestAdapter(auth)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
-		client = msgraphsdk.NewGraphServiceClient(adapter)
-		if err != nil {
-			return nil, microerror.Mask(err)
-		}
+	client, _, err := newGraphClient(c.TenantID, c.ClientID, c.ClientSecret)
+	if err != nil {
+		return nil, microerror.Mask(err)
 	}
 	return &Azure{
 		Name:                  key.GetProviderName(config.Credential.Owner, config.Credential.Name),
@@ -112,9 +210,13 @@ func New(config provider.ProviderConfig) (*Azure, error) {
 		Type:                  ProviderConnectorType,
 		Client:                client,
 		Owner:                 config.Credential.Owner,
+		Display:               config.Credential.Display,
+		Selector:              config.Credential.Selector,
+		Lifetime:              config.Credential.Lifetime,
 		TenantID:              c.TenantID,
 		clientSecret:          c.ClientSecret,
 		managementClusterName: config.ManagementClusterName,
+		dryRun:                config.DryRun,
 	}, nil
 }
 
@@ -165,7 +267,23 @@ func (a *Azure) GetOwner() string {
 	return a.Owner
 }
 
+func (a *Azure) GetConnectorDisplay() provider.ConnectorDisplay {
+	return a.Display
+}
+
+func (a *Azure) GetSelector() provider.ProviderSelector {
+	return a.Selector
+}
+
+func (a *Azure) GetSecretLifetime() provider.SecretLifetime {
+	return a.Lifetime
+}
+
 func (a *Azure) CreateOrUpdateApp(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderApp, error) {
+	if a.dryRun {
+		return a.planApp(config, ctx, oldConnector)
+	}
+
 	// Create or update application registration
 	id, err := a.createOrUpdateApplication(config, ctx)
 	if err != nil {
@@ -203,17 +321,20 @@ func (a *Azure) CreateOrUpdateApp(config provider.AppConfig, ctx context.Context
 			Config: string(data[:]),
 		},
 		SecretEndDateTime: secret.EndDateTime,
+		SecretCreated:     secret.Created,
 	}, nil
 }
 
 func (a *Azure) createOrUpdateApplication(config provider.AppConfig, ctx context.Context) (string, error) {
-	app, err := a.GetApp(config.Name)
+	app, err := a.GetApp(config.Name, ctx)
 	if err != nil {
 		if !IsNotFound(err) {
 			return "", microerror.Mask(err)
 		}
 		// Create app if it does not exist
+		start := time.Now()
 		app, err = a.Client.Applications().Post(ctx, getAppCreateRequestBody(config), nil)
+		a.observeRequest(provider.OperationCreateApp, start, err)
 		if err != nil {
 			return "", microerror.Maskf(requestFailedError, "Failed to create application: %s", PrintOdataError(err))
 		}
@@ -224,7 +345,7 @@ func (a *Azure) createOrUpdateApplication(config provider.AppConfig, ctx context
 	// Because microsoft graph api does not allow for checking the permissions scope (in human readable form) of a given app or setting the scope via anything else than
 	// hardcoding the permissions ids, we instead set them based on an existing app in the tenant.
 	// This way permissions can be set and revoked for child apps easily and we ensure that the right permissions are set.
-	parentApp, err := a.GetApp(DefaultName)
+	parentApp, err := a.GetApp(DefaultName, ctx)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
@@ -235,7 +356,9 @@ func (a *Azure) createOrUpdateApplication(config provider.AppConfig, ctx context
 
 	//Update if needed
 	if needsUpdate, patch := a.computeAppUpdatePatch(config, app, parentApp); needsUpdate {
+		start := time.Now()
 		_, err = a.Client.Applications().ByApplicationId(*id).Patch(ctx, patch, nil)
+		a.observeRequest(provider.OperationPatchApp, start, err)
 		if err != nil {
 			return "", microerror.Maskf(requestFailedError, "Failed to update application: %s", PrintOdataError(err))
 		}
@@ -244,56 +367,77 @@ func (a *Azure) createOrUpdateApplication(config provider.AppConfig, ctx context
 	return *id, nil
 }
 
+// CreateOrUpdateSecret returns the client secret of the app which matches the old secret.
+// Secrets are rotated in two phases so that dex never serves a revoked secret: a new secret is created while
+// the previous one is kept, and previous secrets are only deleted once the dex config with the new secret
+// has been rolled out or once they expired.
 func (a *Azure) CreateOrUpdateSecret(id string, config provider.AppConfig, ctx context.Context, oldSecret string, skipDelete bool) (provider.ProviderSecret, error) {
 
+	start := time.Now()
 	app, err := a.Client.Applications().ByApplicationId(id).Get(ctx, nil)
+	a.observeRequest(provider.OperationGetApp, start, err)
 	if err != nil {
 		return provider.ProviderSecret{}, microerror.Maskf(requestFailedError, "Failed to get application: %s", PrintOdataError(err))
 	}
 
-	var needsCreation bool
-
-	// Secret needs to be created if no secret can be found
-	secret, err := GetSecret(app, config.Name)
-	if err != nil {
-		if !IsNotFound(err) {
-			return provider.ProviderSecret{}, microerror.Mask(err)
-		}
-		needsCreation = true
-	}
-
-	// Check if we already have a key
-	keyPresent := oldSecret != ""
+	current, previous := splitSecrets(app, config.Name, oldSecret)
 
-	// We delete the secret in case it exists and is expired or in case we do not have the key anymore
-	if !needsCreation && (!keyPresent || secretExpired(secret) || secretChanged(secret, oldSecret)) {
-		if skipDelete {
-			a.Log.Info(fmt.Sprintf("Skipped deletion of secret %v of app %s in microsoft ad tenant %s", secret.GetKeyId(), id, a.TenantID))
+	// A new secret is needed if we do not have the key of any secret anymore or the current secret is about to expire.
+	// Scheduled rotations wait for a rotation window and slot unless the secret expires soon.
+	rotate := current == nil || rotationUrgent(current)
+	if !rotate && rotationDue(current, config.SecretRenewBefore, config.RotationJitter) {
+		if config.RotationAllowed {
+			rotate = true
 		} else {
-			if err = a.DeleteSecret(ctx, secret.GetKeyId(), id); err != nil {
-				return provider.ProviderSecret{}, microerror.Mask(err)
-			}
-			a.Log.Info(fmt.Sprintf("Removed secret %v of %s app %s for %s in microsoft ad tenant %s", secret.GetKeyId(), a.Type, config.Name, a.Owner, a.TenantID))
+			a.Log.V(1).Info(fmt.Sprintf("Postponed rotation of secret %v of %s app %s until the next rotation window", current.GetKeyId(), a.Type, config.Name))
 		}
-		needsCreation = true
 	}
-
-	// Create secret if it does not exist
-	if needsCreation {
-		secret, err = a.Client.Applications().ByApplicationId(id).AddPassword().Post(ctx, GetSecretCreateRequestBody(config), nil)
+	if rotate {
+		if current != nil {
+			previous = append(previous, current)
+		}
+		start := time.Now()
+		secret, err := a.Client.Applications().ByApplicationId(id).AddPassword().Post(ctx, GetSecretCreateRequestBody(config), nil)
+		a.observeRequest(provider.OperationCreateSecret, start, err)
 		if err != nil {
 			return provider.ProviderSecret{}, microerror.Maskf(requestFailedError, "Failed to create secret: %s", PrintOdataError(err))
 		}
 		a.Log.Info(fmt.Sprintf("Created secret %v of %s app %s for %s in microsoft ad tenant %s", secret.GetKeyId(), a.Type, config.Name, a.Owner, a.TenantID))
+		if len(previous) > 0 {
+			a.Log.Info(fmt.Sprintf("Keeping %d previous secrets of %s app %s until the new secret is rolled out", len(previous), a.Type, config.Name))
+		}
+		providerSecret, err := getAzureSecret(secret, app, oldSecret)
+		if err != nil {
+			return provider.ProviderSecret{}, microerror.Mask(err)
+		}
+		providerSecret.Created = true
+		return providerSecret, nil
+	}
+
+	for _, p := range previous {
+		if skipDelete {
+			a.Log.Info(fmt.Sprintf("Skipped deletion of secret %v of app %s in microsoft ad tenant %s", p.GetKeyId(), id, a.TenantID))
+			continue
+		}
+		if !config.DexConfigRolledOut && !secretEnded(p) {
+			a.Log.V(1).Info(fmt.Sprintf("Keeping previous secret %v of %s app %s until the dex config is rolled out", p.GetKeyId(), a.Type, config.Name))
+			continue
+		}
+		if err = a.DeleteSecret(ctx, p.GetKeyId(), id); err != nil {
+			return provider.ProviderSecret{}, microerror.Mask(err)
+		}
+		a.Log.Info(fmt.Sprintf("Removed previous secret %v of %s app %s for %s in microsoft ad tenant %s", p.GetKeyId(), a.Type, config.Name, a.Owner, a.TenantID))
 	}
-	return getAzureSecret(secret, app, oldSecret)
+	return getAzureSecret(current, app, oldSecret)
 }
 
 func (a *Azure) DeleteSecret(ctx context.Context, secretID *uuid.UUID, appID string) error {
 	requestBody := applications.NewItemRemovePasswordPostRequestBody()
 	requestBody.SetKeyId(secretID)
 
+	start := time.Now()
 	err := a.Client.Applications().ByApplicationId(appID).RemovePassword().Post(ctx, requestBody, nil)
+	a.observeRequest(provider.OperationDeleteSecret, start, err)
 	if err != nil {
 		return microerror.Maskf(requestFailedError, "Failed to delete secret: %s", PrintOdataError(err))
 	}
@@ -301,22 +445,58 @@ func (a *Azure) DeleteSecret(ctx context.Context, secretID *uuid.UUID, appID str
 }
 
 func (a *Azure) DeleteApp(name string, ctx context.Context) error {
-	appID, err := a.GetAppID(name)
+	appID, err := a.GetAppID(name, ctx)
 	if err != nil {
 		if IsNotFound(err) {
 			return nil
 		}
 		return microerror.Mask(err)
 	}
-	if err := a.Client.Applications().ByApplicationId(appID).Delete(ctx, nil); err != nil {
+	start := time.Now()
+	err = a.Client.Applications().ByApplicationId(appID).Delete(ctx, nil)
+	a.observeRequest(provider.OperationDeleteApp, start, err)
+	if err != nil {
 		return microerror.Maskf(requestFailedError, "Failed to delete application: %s", PrintOdataError(err))
 	}
 	a.Log.Info(fmt.Sprintf("Deleted %s app %s for %s in microsoft ad tenant %s", a.Type, name, a.Owner, a.TenantID))
 	return nil
 }
 
-func (a *Azure) GetAppID(name string) (string, error) {
-	app, err := a.GetApp(name)
+// SetAppRetention tags the application with the retention, so that retained applications and when they are
+// deleted can be seen in the tenant.
+func (a *Azure) SetAppRetention(ctx context.Context, name string, retention *provider.AppRetention) error {
+	app, err := a.GetApp(name, ctx)
+	if IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Mask(err)
+	}
+	id := app.GetId()
+	if id == nil {
+		return microerror.Maskf(notFoundError, "Could not find ID of app %s.", name)
+	}
+	needsUpdate, tags := computeRetentionTagsPatch(app, retention)
+	if !needsUpdate {
+		return nil
+	}
+	if a.dryRun {
+		a.Log.Info(fmt.Sprintf("Dry run: would update retention tags of %s app %s for %s in microsoft ad tenant %s to %v.", a.Type, name, a.Owner, a.TenantID, tags))
+		return nil
+	}
+	patch := models.NewApplication()
+	patch.SetTags(tags)
+	start := time.Now()
+	_, err = a.Client.Applications().ByApplicationId(*id).Patch(ctx, patch, nil)
+	a.observeRequest(provider.OperationPatchApp, start, err)
+	if err != nil {
+		return microerror.Maskf(requestFailedError, "Failed to update application tags: %s", PrintOdataError(err))
+	}
+	a.Log.Info(fmt.Sprintf("Updated retention tags of %s app %s for %s in microsoft ad tenant %s.", a.Type, name, a.Owner, a.TenantID))
+	return nil
+}
+
+func (a *Azure) GetAppID(name string, ctx context.Context) (string, error) {
+	app, err := a.GetApp(name, ctx)
 	if err != nil {
 		return "", microerror.Mask(err)
 	}
@@ -327,11 +507,13 @@ func (a *Azure) GetAppID(name string) (string, error) {
 	return *id, nil
 }
 
-func (a *Azure) GetApp(name string) (models.Applicationable, error) {
+func (a *Azure) GetApp(name string, ctx context.Context) (models.Applicationable, error) {
 	var appList []models.Applicationable
 
 	o := func() error {
-		result, err := a.Client.Applications().Get(context.Background(), GetAppGetRequestConfig(name))
+		start := time.Now()
+		result, err := a.Client.Applications().Get(ctx, GetAppGetRequestConfig(name))
+		a.observeRequest(provider.OperationListApps, start, err)
 		if err != nil {
 			return microerror.Maskf(requestFailedError, "Failed to get applications: %s", PrintOdataError(err))
 		}
@@ -355,6 +537,11 @@ func (a *Azure) GetApp(name string) (models.Applicationable, error) {
 	return appList[0], nil
 }
 
+// observeRequest records the duration and failures of a request to the graph API.
+func (a *Azure) observeRequest(operation string, start time.Time, err error) {
+	provider.ObserveRequest(a.Type, a.Name, operation, start, getStatusCode(err), err)
+}
+
 func (a *Azure) computeAppUpdatePatch(config provider.AppConfig, app models.Applicationable, parentApp models.Applicationable) (bool, models.Applicationable) {
 	appPatch := models.NewApplication()
 	appNeedsUpdate := false
@@ -385,7 +572,7 @@ func (a *Azure) computeAppUpdatePatch(config provider.AppConfig, app models.Appl
 // include new service principal creation
 func (a *Azure) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (map[string]string, error) {
 	ctx := context.Background()
-	app, err := a.GetApp(config.Name)
+	app, err := a.GetApp(config.Name, ctx)
 	if err != nil {
 		if !IsNotFound(err) {
 			return nil, microerror.Mask(err)
@@ -395,7 +582,7 @@ func (a *Azure) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (ma
 		app.SetDisplayName(&config.Name)
 
 		// Set permissions from parent app
-		parentApp, err := a.GetApp(DexOperatorName)
+		parentApp, err := a.GetApp(DexOperatorName, ctx)
 		if err != nil {
 			return nil, microerror.Mask(err)
 		}
@@ -435,7 +622,8 @@ func (a *Azure) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (ma
 }
 
 func (a *Azure) CleanCredentialsForAuthenticatedApp(config provider.AppConfig) error {
-	app, err := a.GetApp(config.Name)
+	ctx := context.Background()
+	app, err := a.GetApp(config.Name, ctx)
 	if err != nil {
 		if !IsNotFound(err) {
 			return microerror.Mask(err)
@@ -449,7 +637,7 @@ func (a *Azure) CleanCredentialsForAuthenticatedApp(config provider.AppConfig) e
 	for _, c := range app.GetPasswordCredentials() {
 		if credentialName := c.GetDisplayName(); credentialName != nil {
 			if *credentialName == config.Name && secretChanged(c, a.clientSecret) {
-				if err = a.DeleteSecret(context.Background(), c.GetKeyId(), *id); err != nil {
+				if err = a.DeleteSecret(ctx, c.GetKeyId(), *id); err != nil {
 					return microerror.Mask(err)
 				}
 				a.Log.Info(fmt.Sprintf("Removed secret %v of %s app %s for %s in microsoft ad tenant %s", c.GetKeyId(), a.Type, config.Name, a.Owner, a.TenantID))
@@ -464,7 +652,7 @@ func (a *Azure) DeleteAuthenticatedApp(config provider.AppConfig) error {
 	installation := strings.TrimPrefix(config.Name, DexOperatorName+"-")
 
 	// get all the dex-apps
-	dexApps, err := a.Client.Applications().Get(context.Background(), GetAllAppsContainingRequestConfig("dex-app"))
+	dexApps, err := a.Client.Applications().Get(ctx, GetAllAppsContainingRequestConfig("dex-app"))
 	if err != nil {
 		return microerror.Maskf(requestFailedError, "Failed to get dex apps: %s", PrintOdataError(err))
 	}
@@ -482,7 +670,7 @@ func (a *Azure) DeleteAuthenticatedApp(config provider.AppConfig) error {
 	}
 
 	// get dex-operator app
-	dexOperator, err := a.GetApp(config.Name)
+	dexOperator, err := a.GetApp(config.Name, ctx)
 	if err == nil {
 		err := a.DeleteApp(*dexOperator.GetDisplayName(), ctx)
 		if err != nil {
@@ -500,15 +688,17 @@ func (a *Azure) DeleteAuthenticatedApp(config provider.AppConfig) error {
 func (a *Azure) GetCredentialExpiry(ctx context.Context) (time.Time, error) {
 	appName := key.GetDexOperatorName(a.managementClusterName)
 
-	app, err := a.GetApp(appName)
+	app, err := a.GetApp(appName, ctx)
 	if err != nil {
 		return time.Time{}, microerror.Mask(err)
 	}
 
-	// Find the current secret for this app
-	secret, err := GetSecret(app, appName)
-	if err != nil {
-		return time.Time{}, microerror.Mask(err)
+	// Find the secret currently used by dex-operator, previous secrets are kept after a rotation
+	secret, _ := splitSecrets(app, appName, a.clientSecret)
+	if secret == nil {
+		if secret, err = GetSecret(app, appName); err != nil {
+			return time.Time{}, microerror.Mask(err)
+		}
 	}
 
 	if endDateTime := secret.GetEndDateTime(); endDateTime != nil {
@@ -1,14 +1,19 @@
 package azure
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
 
 	"github.com/go-logr/logr"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
 )
 
 func TestGetRequestBody(t *testing.T) {
@@ -253,8 +258,59 @@ func TestSecretExpired(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			s := models.NewPasswordCredential()
 			s.SetEndDateTime(&testCases[i].expirationDate)
-			if secretExpired(s) != tc.expired {
-				t.Fatalf("Expected %v, got %v", tc.expired, secretExpired(s))
+			if secretExpired(s, key.DefaultSecretRenewBefore) != tc.expired {
+				t.Fatalf("Expected %v, got %v", tc.expired, secretExpired(s, key.DefaultSecretRenewBefore))
+			}
+		})
+	}
+}
+
+func TestRotationDue(t *testing.T) {
+	testCases := []struct {
+		name           string
+		expirationDate time.Time
+		jitter         time.Duration
+		due            bool
+		urgent         bool
+	}{
+		{
+			name:           "case 0: within threshold without jitter",
+			expirationDate: time.Now().Add(7 * 24 * time.Hour),
+			due:            true,
+		},
+		{
+			name:           "case 1: within threshold but delayed by jitter",
+			expirationDate: time.Now().Add(7 * 24 * time.Hour),
+			jitter:         4 * 24 * time.Hour,
+		},
+		{
+			name:           "case 2: within threshold shifted by jitter",
+			expirationDate: time.Now().Add(5 * 24 * time.Hour),
+			jitter:         4 * 24 * time.Hour,
+			due:            true,
+		},
+		{
+			name:           "case 3: expires within a day",
+			expirationDate: time.Now().Add(12 * time.Hour),
+			jitter:         4 * 24 * time.Hour,
+			due:            true,
+			urgent:         true,
+		},
+		{
+			name:           "case 4: not due",
+			expirationDate: time.Now().Add(14 * 24 * time.Hour),
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := models.NewPasswordCredential()
+			s.SetEndDateTime(&testCases[i].expirationDate)
+			if due := rotationDue(s, key.DefaultSecretRenewBefore, tc.jitter); due != tc.due {
+				t.Fatalf("Expected due %v, got %v", tc.due, due)
+			}
+			if urgent := rotationUrgent(s); urgent != tc.urgent {
+				t.Fatalf("Expected urgent %v, got %v", tc.urgent, urgent)
 			}
 		})
 	}
@@ -304,3 +360,156 @@ func TestComputeURIUpdatePatch(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeRetentionTagsPatch(t *testing.T) {
+	until := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name         string
+		tags         []string
+		retention    *provider.AppRetention
+		updateNeeded bool
+		expectedTags []string
+	}{
+		{
+			name:         "case 0: not retained",
+			tags:         []string{"team-a"},
+			updateNeeded: false,
+			expectedTags: []string{"team-a"},
+		},
+		{
+			name:         "case 1: retained until",
+			tags:         []string{"team-a"},
+			retention:    &provider.AppRetention{Target: "org-example/dex-app", Until: until},
+			updateNeeded: true,
+			expectedTags: []string{"team-a", RetainedTag, RetainedTargetTagPrefix + "org-example/dex-app", RetainUntilTagPrefix + "2026-11-01T12:00:00Z"},
+		},
+		{
+			name:         "case 2: retained indefinitely",
+			tags:         []string{RetainedTag, RetainUntilTagPrefix + "2026-11-01T12:00:00Z"},
+			retention:    &provider.AppRetention{},
+			updateNeeded: true,
+			expectedTags: []string{RetainedTag},
+		},
+		{
+			name:         "case 3: already retained",
+			tags:         []string{RetainedTag, RetainUntilTagPrefix + "2026-11-01T12:00:00Z"},
+			retention:    &provider.AppRetention{Until: until},
+			updateNeeded: false,
+			expectedTags: []string{RetainedTag, RetainUntilTagPrefix + "2026-11-01T12:00:00Z"},
+		},
+		{
+			name:         "case 4: taken over again",
+			tags:         []string{"team-a", RetainedTag, RetainedTargetTagPrefix + "org-example/dex-app"},
+			updateNeeded: true,
+			expectedTags: []string{"team-a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := models.NewApplication()
+			app.SetTags(tc.tags)
+			updateNeeded, tags := computeRetentionTagsPatch(app, tc.retention)
+			if updateNeeded != tc.updateNeeded {
+				t.Fatalf("expected update needed %v, got %v", tc.updateNeeded, updateNeeded)
+			}
+			if strings.Join(tags, ",") != strings.Join(tc.expectedTags, ",") {
+				t.Fatalf("expected tags %v, got %v", tc.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestSplitSecrets(t *testing.T) {
+	credential := func(name string, hint string) models.PasswordCredentialable {
+		c := models.NewPasswordCredential()
+		c.SetDisplayName(&name)
+		c.SetHint(&hint)
+		return c
+	}
+	app := models.NewApplication()
+	app.SetPasswordCredentials([]models.PasswordCredentialable{
+		credential("test", "abc"),
+		credential("other", "xyz"),
+		credential("test", "def"),
+	})
+
+	testCases := []struct {
+		name            string
+		oldSecret       string
+		expectedCurrent string
+		expectedHints   []string
+	}{
+		{
+			name:            "case 0: old secret matches second credential",
+			oldSecret:       "defsecret",
+			expectedCurrent: "def",
+			expectedHints:   []string{"abc"},
+		},
+		{
+			name:          "case 1: unknown old secret",
+			oldSecret:     "ghisecret",
+			expectedHints: []string{"abc", "def"},
+		},
+		{
+			name:          "case 2: no old secret",
+			expectedHints: []string{"abc", "def"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			current, previous := splitSecrets(app, "test", tc.oldSecret)
+			if tc.expectedCurrent == "" && current != nil {
+				t.Fatalf("Expected no current secret, got %s", *current.GetHint())
+			}
+			if tc.expectedCurrent != "" && (current == nil || *current.GetHint() != tc.expectedCurrent) {
+				t.Fatalf("Expected current secret %s, got %v", tc.expectedCurrent, current)
+			}
+			hints := []string{}
+			for _, p := range previous {
+				hints = append(hints, *p.GetHint())
+			}
+			if strings.Join(hints, ",") != strings.Join(tc.expectedHints, ",") {
+				t.Fatalf("Expected previous secrets %v, got %v", tc.expectedHints, hints)
+			}
+		})
+	}
+}
+
+func TestGetStatusCode(t *testing.T) {
+	throttled := odataerrors.NewODataError()
+	throttled.SetStatusCode(429)
+
+	testCases := []struct {
+		name               string
+		err                error
+		expectedStatusCode int
+	}{
+		{
+			name: "case 0: no error",
+		},
+		{
+			name:               "case 1: odata error",
+			err:                throttled,
+			expectedStatusCode: 429,
+		},
+		{
+			name:               "case 2: wrapped odata error",
+			err:                fmt.Errorf("failed to get applications: %w", throttled),
+			expectedStatusCode: 429,
+		},
+		{
+			name: "case 3: error without response",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if statusCode := getStatusCode(tc.err); statusCode != tc.expectedStatusCode {
+				t.Fatalf("expected status code %d, got %d", tc.expectedStatusCode, statusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+
+	"github.com/dexidp/dex/connector/microsoft"
+	"github.com/giantswarm/microerror"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"gopkg.in/yaml.v3"
+)
+
+// planApp computes the connector CreateOrUpdateApp would return and logs the changes
+// it would apply to the tenant. Nothing is written to the tenant.
+func (a *Azure) planApp(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderApp, error) {
+	app, err := a.GetApp(config.Name, ctx)
+	if IsNotFound(err) {
+		app = nil
+	} else if err != nil {
+		return provider.ProviderApp{}, microerror.Mask(err)
+	}
+	var parentApp models.Applicationable
+	if app != nil {
+		parentApp, err = a.GetApp(DefaultName, ctx)
+		if err != nil {
+			return provider.ProviderApp{}, microerror.Mask(err)
+		}
+	}
+
+	providerApp, changes, err := a.computeAppPlan(config, oldConnector, app, parentApp)
+	if err != nil {
+		return provider.ProviderApp{}, microerror.Mask(err)
+	}
+	for _, change := range changes {
+		a.Log.Info(fmt.Sprintf("Dry run: would %s", change))
+	}
+	return providerApp, nil
+}
+
+// computeAppPlan returns the connector CreateOrUpdateApp would return for the app, which is nil if it does
+// not exist yet, and the changes it would apply to the tenant.
+func (a *Azure) computeAppPlan(config provider.AppConfig, oldConnector dex.Connector, app models.Applicationable, parentApp models.Applicationable) (provider.ProviderApp, []string, error) {
+	oldConfig := &microsoft.Config{}
+	if oldConnector.Config != "" {
+		if err := yaml.Unmarshal([]byte(oldConnector.Config), oldConfig); err != nil {
+			return provider.ProviderApp{}, nil, microerror.Mask(err)
+		}
+	}
+
+	clientID := oldConfig.ClientID
+	clientSecret := oldConfig.ClientSecret
+	endDateTime := time.Now().Add(config.SecretValidity)
+	changes := []string{}
+
+	if app == nil {
+		changes = append(changes,
+			fmt.Sprintf("create %s app %s for %s in microsoft ad tenant %s", a.Type, config.Name, a.Owner, a.TenantID),
+			fmt.Sprintf("create secret of %s app %s for %s in microsoft ad tenant %s", a.Type, config.Name, a.Owner, a.TenantID))
+		clientSecret = provider.DryRunSecretPlaceholder
+	} else {
+		if needsUpdate, _ := a.computeAppUpdatePatch(config, app, parentApp); needsUpdate {
+			changes = append(changes, fmt.Sprintf("update %s app %s for %s in microsoft ad tenant %s", a.Type, config.Name, a.Owner, a.TenantID))
+		}
+		if appID := app.GetAppId(); appID != nil {
+			clientID = *appID
+		}
+
+		current, previous := splitSecrets(app, config.Name, clientSecret)
+		switch {
+		case current == nil:
+			changes = append(changes, fmt.Sprintf("create secret of %s app %s for %s in microsoft ad tenant %s", a.Type, config.Name, a.Owner, a.TenantID))
+			clientSecret = provider.DryRunSecretPlaceholder
+		case rotationUrgent(current) || (rotationDue(current, config.SecretRenewBefore, config.RotationJitter) && config.RotationAllowed):
+			changes = append(changes, fmt.Sprintf("create a new secret of %s app %s for %s in microsoft ad tenant %s and keep secret %v until the new secret is rolled out", a.Type, config.Name, a.Owner, a.TenantID, current.GetKeyId()))
+			clientSecret = provider.DryRunSecretPlaceholder
+		default:
+			if rotationDue(current, config.SecretRenewBefore, config.RotationJitter) {
+				changes = append(changes, fmt.Sprintf("postpone rotation of secret %v of %s app %s until the next rotation window", current.GetKeyId(), a.Type, config.Name))
+			}
+			if current.GetEndDateTime() != nil {
+				endDateTime = *current.GetEndDateTime()
+			}
+			for _, p := range previous {
+				if config.DexConfigRolledOut || secretEnded(p) {
+					changes = append(changes, fmt.Sprintf("remove previous secret %v of %s app %s for %s in microsoft ad tenant %s", p.GetKeyId(), a.Type, config.Name, a.Owner, a.TenantID))
+				}
+			}
+		}
+	}
+
+	connectorConfig := &microsoft.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURI:  config.RedirectURI,
+		Tenant:       a.TenantID,
+	}
+	data, err := yaml.Marshal(connectorConfig)
+	if err != nil {
+		return provider.ProviderApp{}, nil, microerror.Mask(err)
+	}
+	return provider.ProviderApp{
+		Connector: dex.Connector{
+			Type:   a.Type,
+			ID:     a.Name,
+			Name:   a.Description,
+			Config: string(data[:]),
+		},
+		SecretEndDateTime: endDateTime,
+	}, changes, nil
+}
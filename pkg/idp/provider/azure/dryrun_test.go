@@ -0,0 +1,110 @@
+package azure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dexidp/dex/connector/microsoft"
+	"github.com/google/uuid"
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"gopkg.in/yaml.v3"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+)
+
+func TestComputeAppPlan(t *testing.T) {
+	config := provider.GetTestConfig()
+	app := func(base models.Applicationable, secretEnd time.Time) models.Applicationable {
+		appID := "client-id"
+		base.SetAppId(&appID)
+		secret := models.NewPasswordCredential()
+		keyID := uuid.New()
+		secret.SetKeyId(&keyID)
+		secret.SetDisplayName(&config.Name)
+		hint := "abc"
+		secret.SetHint(&hint)
+		secret.SetEndDateTime(&secretEnd)
+		base.SetPasswordCredentials([]models.PasswordCredentialable{secret})
+		return base
+	}
+	oldConnectorConfig, err := yaml.Marshal(microsoft.Config{ClientID: "client-id", ClientSecret: "abcsecret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretEnd := time.Now().Add(60 * 24 * time.Hour)
+
+	testCases := []struct {
+		name            string
+		app             models.Applicationable
+		expectedChanges []string
+		expectedSecret  string
+	}{
+		{
+			name:            "case 0: app missing",
+			expectedChanges: []string{"create test-type app", "create secret"},
+			expectedSecret:  provider.DryRunSecretPlaceholder,
+		},
+		{
+			name:            "case 1: app needs a patch",
+			app:             app(models.NewApplication(), secretEnd),
+			expectedChanges: []string{"update test-type app"},
+			expectedSecret:  "abcsecret",
+		},
+		{
+			name:            "case 2: secret expiring",
+			app:             app(getAppCreateRequestBody(config), time.Now().Add(12*time.Hour)),
+			expectedChanges: []string{"create a new secret"},
+			expectedSecret:  provider.DryRunSecretPlaceholder,
+		},
+		{
+			name:            "case 3: secret due outside of a rotation window",
+			app:             app(getAppCreateRequestBody(config), time.Now().Add(5*24*time.Hour)),
+			expectedChanges: []string{"postpone rotation"},
+			expectedSecret:  "abcsecret",
+		},
+		{
+			name:            "case 4: no change",
+			app:             app(getAppCreateRequestBody(config), secretEnd),
+			expectedChanges: []string{},
+			expectedSecret:  "abcsecret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := Azure{
+				Name: "test",
+				Log:  provider.GetTestLogger(),
+				Type: "test-type",
+			}
+			providerApp, changes, err := a.computeAppPlan(config, dex.Connector{Config: string(oldConnectorConfig)}, tc.app, models.NewApplication())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != len(tc.expectedChanges) {
+				t.Fatalf("Expected changes %v, got %v", tc.expectedChanges, changes)
+			}
+			for i, change := range changes {
+				if !strings.HasPrefix(change, tc.expectedChanges[i]) {
+					t.Fatalf("Expected change %d to start with %q, got %q", i, tc.expectedChanges[i], change)
+				}
+			}
+
+			connectorConfig := &microsoft.Config{}
+			if err := yaml.Unmarshal([]byte(providerApp.Connector.Config), connectorConfig); err != nil {
+				t.Fatal(err)
+			}
+			if connectorConfig.ClientSecret != tc.expectedSecret {
+				t.Fatalf("Expected client secret %s, got %s", tc.expectedSecret, connectorConfig.ClientSecret)
+			}
+			if connectorConfig.ClientID != "client-id" {
+				t.Fatalf("Expected client id client-id, got %s", connectorConfig.ClientID)
+			}
+			if tc.expectedSecret != provider.DryRunSecretPlaceholder && tc.app != nil && !providerApp.SecretEndDateTime.Equal(*tc.app.GetPasswordCredentials()[0].GetEndDateTime()) {
+				t.Fatalf("Expected the expiry of the current secret, got %v", providerApp.SecretEndDateTime)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package azure
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/giantswarm/microerror"
@@ -46,3 +47,12 @@ func PrintOdataError(err error) string {
 		return fmt.Sprintf("%T > error: %#v", err, err)
 	}
 }
+
+// getStatusCode returns the HTTP status code of a failed graph API request, 0 if there was no response.
+func getStatusCode(err error) int {
+	var apiErr interface{ GetStatusCode() int }
+	if errors.As(err, &apiErr) {
+		return apiErr.GetStatusCode()
+	}
+	return 0
+}
@@ -3,6 +3,8 @@ package azure
 import (
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
@@ -24,6 +26,11 @@ const (
 	Claim                 = "groups"
 	Audience              = "AzureADMyOrg"
 	DexOperatorName       = "dex-operator"
+
+	// Retention tags mark app registrations which are kept after their dex target was deleted.
+	RetainedTag             = "dex-operator-retained"
+	RetainedTargetTagPrefix = "dex-operator-retained-target:"
+	RetainUntilTagPrefix    = "dex-operator-retain-until:"
 )
 
 func ProviderScope() []string {
@@ -111,6 +118,26 @@ func computeClaimUpdatePatch(claims []models.OptionalClaimable) (bool, []models.
 	return true, append(claims, getClaim())
 }
 
+// computeRetentionTagsPatch replaces the retention tags of the app with those of the retention, keeping other tags.
+func computeRetentionTagsPatch(app models.Applicationable, retention *provider.AppRetention) (bool, []string) {
+	tags := []string{}
+	for _, tag := range app.GetTags() {
+		if tag != RetainedTag && !strings.HasPrefix(tag, RetainedTargetTagPrefix) && !strings.HasPrefix(tag, RetainUntilTagPrefix) {
+			tags = append(tags, tag)
+		}
+	}
+	if retention != nil {
+		tags = append(tags, RetainedTag)
+		if retention.Target != "" {
+			tags = append(tags, RetainedTargetTagPrefix+retention.Target)
+		}
+		if !retention.Until.IsZero() {
+			tags = append(tags, RetainUntilTagPrefix+retention.Until.UTC().Format(time.RFC3339))
+		}
+	}
+	return !slices.Equal(tags, app.GetTags()), tags
+}
+
 func GetAppGetRequestConfig(name string) *applications.ApplicationsRequestBuilderGetRequestConfiguration {
 	headers := abstractions.NewRequestHeaders()
 	headers.Add("ConsistencyLevel", "eventual")
@@ -196,7 +223,7 @@ func GetSecretCreateRequestBody(config provider.AppConfig) *applications.ItemAdd
 	keyCredential := models.NewPasswordCredential()
 	keyCredential.SetDisplayName(&config.Name)
 
-	validUntil := time.Now().AddDate(0, config.SecretValidityMonths, 0)
+	validUntil := time.Now().Add(config.SecretValidity)
 	keyCredential.SetEndDateTime(&validUntil)
 
 	secret := applications.NewItemAddPasswordPostRequestBody()
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
 
 	"github.com/dexidp/dex/connector/microsoft"
 	"github.com/giantswarm/microerror"
@@ -39,15 +40,33 @@ func getAzureSecret(secret models.PasswordCredentialable, app models.Application
 	}, nil
 }
 
-func secretExpired(secret models.PasswordCredentialable) bool {
+// secretExpired returns true if the secret expires within the renewal threshold.
+func secretExpired(secret models.PasswordCredentialable, renewBefore time.Duration) bool {
+	return secretExpiresWithin(secret, renewBefore)
+}
+
+// rotationDue returns true once the secret is within the renewal threshold, shifted by the rotation jitter of the target.
+func rotationDue(secret models.PasswordCredentialable, renewBefore time.Duration, jitter time.Duration) bool {
+	return secretExpired(secret, renewBefore-jitter)
+}
+
+// rotationUrgent returns true if the secret needs to be rotated regardless of rotation windows.
+func rotationUrgent(secret models.PasswordCredentialable) bool {
+	return secretExpiresWithin(secret, key.UrgentRotationThreshold)
+}
+
+func secretExpiresWithin(secret models.PasswordCredentialable, d time.Duration) bool {
 	bestBefore := secret.GetEndDateTime()
 	if bestBefore == nil {
 		return true
 	}
-	if bestBefore.Before(time.Now().Add(10 * 24 * time.Hour)) {
-		return true
-	}
-	return false
+	return bestBefore.Before(time.Now().Add(d))
+}
+
+// secretEnded returns true if the secret can no longer be used.
+func secretEnded(secret models.PasswordCredentialable) bool {
+	endDateTime := secret.GetEndDateTime()
+	return endDateTime == nil || endDateTime.Before(time.Now())
 }
 
 func secretChanged(secret models.PasswordCredentialable, oldSecret string) bool {
@@ -72,6 +91,24 @@ func GetSecret(app models.Applicationable, name string) (models.PasswordCredenti
 	return nil, microerror.Maskf(notFoundError, "Did not find credential %s.", name)
 }
 
+// splitSecrets returns the secret of the app matching the old secret and all other secrets with the same name.
+// The current secret is nil if the old secret is unknown or does not match any secret.
+func splitSecrets(app models.Applicationable, name string, oldSecret string) (models.PasswordCredentialable, []models.PasswordCredentialable) {
+	var current models.PasswordCredentialable
+	previous := []models.PasswordCredentialable{}
+	for _, c := range app.GetPasswordCredentials() {
+		if credentialName := c.GetDisplayName(); credentialName == nil || *credentialName != name {
+			continue
+		}
+		if current == nil && oldSecret != "" && !secretChanged(c, oldSecret) {
+			current = c
+			continue
+		}
+		previous = append(previous, c)
+	}
+	return current, previous
+}
+
 func getSecretFromConfig(config string) (string, error) {
 	if config == "" {
 		return "", nil
@@ -0,0 +1,16 @@
+package provider
+
+import (
+	"github.com/giantswarm/microerror"
+)
+
+// ManualActionRequiredError is returned by providers whose service credentials can only be
+// renewed with the help of an administrator, e.g. because the identity provider has no API for it.
+var ManualActionRequiredError = &microerror.Error{
+	Kind: "manualActionRequiredError",
+}
+
+// IsManualActionRequired asserts ManualActionRequiredError.
+func IsManualActionRequired(err error) bool {
+	return microerror.Cause(err) == ManualActionRequiredError
+}
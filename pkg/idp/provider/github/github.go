@@ -11,6 +11,7 @@ import (
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider/github/manifest"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 	"github.com/giantswarm/dex-operator/pkg/yaml"
 
 	"github.com/bradleyfalzon/ghinstallation/v2"
@@ -29,10 +30,14 @@ const (
 	TeamKey               = "team"
 	AppIDKey              = "app-id"
 	PrivateKeyKey         = "private-key"
-	ClientIDKey           = "client-id"
-	ClientSecretKey       = "client-secret"
-	DefaultHost           = "github.com"
-	TeamNameFieldSlug     = "slug"
+	// PrivateKeyCreatedAtKey records when the private key was created, in RFC 3339 format.
+	PrivateKeyCreatedAtKey = "private-key-created-at"
+	// NextPrivateKeyKey holds a new private key generated by an administrator which replaces the private key.
+	NextPrivateKeyKey = "next-private-key"
+	ClientIDKey       = "client-id"
+	ClientSecretKey   = "client-secret"
+	DefaultHost       = "github.com"
+	TeamNameFieldSlug = "slug"
 )
 
 type Github struct {
@@ -42,10 +47,17 @@ type Github struct {
 	Description  string
 	Type         string
 	Owner        string
+	Display      provider.ConnectorDisplay
+	Selector     provider.ProviderSelector
+	Lifetime     provider.SecretLifetime
 	Organization string
 	Team         string
 	id           string
 	secret       string
+
+	appID               int64
+	privateKeyCreatedAt time.Time
+	nextPrivateKey      []byte
 }
 
 type Config struct {
@@ -55,6 +67,9 @@ type Config struct {
 	PrivateKey   []byte
 	ClientID     string
 	ClientSecret string
+	// PrivateKeyCreatedAt is zero if unknown.
+	PrivateKeyCreatedAt time.Time
+	NextPrivateKey      []byte
 }
 
 var _ provider.Provider = (*Github)(nil)
@@ -67,11 +82,14 @@ func New(config provider.ProviderConfig) (*Github, error) {
 	}
 
 	// get the client
-	itr, err := ghinstallation.NewAppsTransport(http.DefaultTransport, c.AppID, c.PrivateKey)
+	itr, err := ghinstallation.NewAppsTransport(tracing.NewTransport(http.DefaultTransport), c.AppID, c.PrivateKey)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	client, err := githubclient.NewClient(githubclient.WithHTTPClient(&http.Client{Transport: itr}))
 	if err != nil {
 		return nil, microerror.Mask(err)
 	}
-	client := githubclient.NewClient(&http.Client{Transport: itr})
 
 	return &Github{
 		Name:         key.GetProviderName(config.Credential.Owner, config.Credential.Name),
@@ -80,10 +98,17 @@ func New(config provider.ProviderConfig) (*Github, error) {
 		Type:         ProviderConnectorType,
 		Client:       client,
 		Owner:        config.Credential.Owner,
+		Display:      config.Credential.Display,
+		Selector:     config.Credential.Selector,
+		Lifetime:     config.Credential.Lifetime,
 		Organization: c.Organization,
 		Team:         c.Team,
 		id:           c.ClientID,
 		secret:       c.ClientSecret,
+
+		appID:               c.AppID,
+		privateKeyCreatedAt: c.PrivateKeyCreatedAt,
+		nextPrivateKey:      c.NextPrivateKey,
 	}, nil
 }
 
@@ -135,13 +160,25 @@ func newGithubConfig(p provider.ProviderCredential, log logr.Logger) (Config, er
 		}
 	}
 
+	var privateKeyCreatedAt time.Time
+	{
+		if value := p.Credentials[PrivateKeyCreatedAtKey]; value != "" {
+			var err error
+			if privateKeyCreatedAt, err = time.Parse(time.RFC3339, value); err != nil {
+				return Config{}, microerror.Maskf(invalidConfigError, "%s is not a valid value for %s: %v", value, PrivateKeyCreatedAtKey, err)
+			}
+		}
+	}
+
 	return Config{
-		Organization: organization,
-		Team:         team,
-		AppID:        int64(appID),
-		PrivateKey:   privateKey,
-		ClientSecret: clientSecret,
-		ClientID:     clientID,
+		Organization:        organization,
+		Team:                team,
+		AppID:               int64(appID),
+		PrivateKey:          privateKey,
+		ClientSecret:        clientSecret,
+		ClientID:            clientID,
+		PrivateKeyCreatedAt: privateKeyCreatedAt,
+		NextPrivateKey:      []byte(p.Credentials[NextPrivateKeyKey]),
 	}, nil
 }
 
@@ -161,6 +198,18 @@ func (g *Github) GetOwner() string {
 	return g.Owner
 }
 
+func (g *Github) GetConnectorDisplay() provider.ConnectorDisplay {
+	return g.Display
+}
+
+func (g *Github) GetSelector() provider.ProviderSelector {
+	return g.Selector
+}
+
+func (g *Github) GetSecretLifetime() provider.SecretLifetime {
+	return g.Lifetime
+}
+
 func (g *Github) CreateOrUpdateApp(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderApp, error) {
 	secret, err := g.createOrUpdateSecret(config, ctx, oldConnector)
 	if err != nil {
@@ -196,7 +245,7 @@ func (g *Github) CreateOrUpdateApp(config provider.AppConfig, ctx context.Contex
 
 func (g *Github) DeleteApp(name string, ctx context.Context) error {
 	// get authenticated app
-	_, _, err := g.Client.Apps.Get(ctx, "")
+	_, err := g.getApp(ctx)
 	if err != nil {
 		return microerror.Mask(err)
 	}
@@ -204,9 +253,15 @@ func (g *Github) DeleteApp(name string, ctx context.Context) error {
 	return nil
 }
 
+// SetAppRetention does nothing. All dex targets share the github app of dex-operator, whose settings
+// cannot be changed through the API, so there is no registration per target to mark.
+func (g *Github) SetAppRetention(ctx context.Context, name string, retention *provider.AppRetention) error {
+	return nil
+}
+
 func (g *Github) createOrUpdateSecret(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderSecret, error) {
 	// get authenticated app, check if the callback URI is present
-	app, _, err := g.Client.Apps.Get(ctx, "")
+	app, err := g.getApp(ctx)
 	if err != nil {
 		return provider.ProviderSecret{}, microerror.Mask(err)
 	}
@@ -218,10 +273,10 @@ func (g *Github) createOrUpdateSecret(config provider.AppConfig, ctx context.Con
 		//We return here since we can not set the update
 		return provider.ProviderSecret{}, microerror.Maskf(missingCallbackURIError, "%s app %s for %s in github organization %s needs update", g.Type, app.GetSlug(), g.Owner, g.Organization)
 	}
-	return g.getSecret(app, oldConnector)
+	return g.getSecret(app, config, oldConnector)
 }
 
-func (g *Github) getSecret(app *githubclient.App, oldConnector dex.Connector) (provider.ProviderSecret, error) {
+func (g *Github) getSecret(app *githubclient.App, config provider.AppConfig, oldConnector dex.Connector) (provider.ProviderSecret, error) {
 	var err error
 	var endDateTime time.Time
 	var clientID, clientSecret string
@@ -237,7 +292,7 @@ func (g *Github) getSecret(app *githubclient.App, oldConnector dex.Connector) (p
 			clientID = g.id
 			clientSecret = g.secret
 		}
-		endDateTime = app.GetCreatedAt().AddDate(0, 6, 0)
+		endDateTime = app.GetCreatedAt().Add(config.SecretValidity)
 	}
 
 	return provider.ProviderSecret{
@@ -315,6 +370,8 @@ func (g *Github) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (m
 		TeamKey:         c.Team,
 		AppIDKey:        fmt.Sprint(c.AppID),
 		PrivateKeyKey:   string(c.PrivateKey),
+		// the private key is created together with the app
+		PrivateKeyCreatedAtKey: time.Now().UTC().Format(time.RFC3339),
 	}, nil
 }
 func (g *Github) CleanCredentialsForAuthenticatedApp(config provider.AppConfig) error {
@@ -358,15 +415,144 @@ func getDeletionURLForApp(host string, organization string, slug string) string
 	return fmt.Sprintf("https://%s/organizations/%s/settings/apps/%s/advanced", host, organization, slug)
 }
 
-// Self-renewal methods implementation - Github doesn't support renewal yet
+// Self-renewal of the private key of the github app of dex-operator.
+// GitHub has no API to create private keys, so a new key is generated in the app settings by an administrator
+// and added as next-private-key to the credentials. dex-operator verifies it and promotes it to the private key.
 func (g *Github) SupportsServiceCredentialRenewal() bool {
-	return false
+	return true
 }
 
+// ShouldRotateServiceCredentials returns true if a new private key was provided or the private key is older than
+// its validity minus the renewal threshold. Keys of unknown age are not rotated, the time they are first seen
+// is recorded instead, see GetMissingServiceCredentialMetadata.
 func (g *Github) ShouldRotateServiceCredentials(ctx context.Context, config provider.AppConfig) (bool, error) {
-	return false, nil
+	if len(g.nextPrivateKey) > 0 {
+		g.Log.Info("A new private key was provided for the github app", "app", config.Name)
+		return true, nil
+	}
+	if g.privateKeyCreatedAt.IsZero() {
+		g.Log.Info(fmt.Sprintf("Age of the private key of the github app is unknown since %s is not set", PrivateKeyCreatedAtKey), "app", config.Name)
+		return false, nil
+	}
+	age := time.Since(g.privateKeyCreatedAt)
+	g.Log.Info("Github private key age check",
+		"app", config.Name,
+		"created", g.privateKeyCreatedAt,
+		"age", age)
+	return privateKeyDue(g.privateKeyCreatedAt, config), nil
 }
 
+// RotateServiceCredentials returns the next private key as the new private key.
+// Without a next private key, it returns a manual action required error explaining how to provide one.
 func (g *Github) RotateServiceCredentials(ctx context.Context, config provider.AppConfig) (map[string]string, error) {
-	return nil, microerror.Maskf(invalidConfigError, "Github provider does not support service credential rotation yet")
+	appURL := getAppURL(DefaultHost, g.Organization, g.getAppSlug(ctx, config))
+	if len(g.nextPrivateKey) == 0 {
+		return nil, microerror.Maskf(provider.ManualActionRequiredError,
+			"Generate a new private key for github app %s at %s and add it as %s to the credentials of provider %s.",
+			config.Name, appURL, NextPrivateKeyKey, g.Name)
+	}
+	g.Log.Info(fmt.Sprintf("Rotated private key of github app %s. Delete the previous private key at %s once the new key is in use.", config.Name, appURL))
+	return map[string]string{
+		PrivateKeyKey:          string(g.nextPrivateKey),
+		PrivateKeyCreatedAtKey: time.Now().UTC().Format(time.RFC3339),
+		NextPrivateKeyKey:      "",
+	}, nil
+}
+
+// VerifyServiceCredentials authenticates as the github app with the private key of the credentials.
+func (g *Github) VerifyServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	privateKey := credentials[PrivateKeyKey]
+	if privateKey == "" {
+		return microerror.Maskf(invalidConfigError, "%s must not be empty.", PrivateKeyKey)
+	}
+	if err := g.verifyPrivateKey(ctx, []byte(privateKey)); err != nil {
+		return microerror.Mask(err)
+	}
+	g.Log.Info("Verified github private key", "app", config.Name)
+	return nil
+}
+
+// RevertServiceCredentials does nothing since github does not allow to delete private keys via API.
+// Keys provided as next-private-key are kept in the credentials until they are replaced.
+func (g *Github) RevertServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	return nil
+}
+
+func (g *Github) GetServiceCredentialCreatedAt() (time.Time, bool) {
+	return g.privateKeyCreatedAt, !g.privateKeyCreatedAt.IsZero()
+}
+
+// GetMissingServiceCredentialMetadata records the current time as creation time of private keys of unknown age,
+// e.g. keys added manually, so that their age is counted from the time they are first seen.
+// Keys provided as next-private-key get their creation time recorded when they are promoted.
+func (g *Github) GetMissingServiceCredentialMetadata() map[string]string {
+	if !g.privateKeyCreatedAt.IsZero() || len(g.nextPrivateKey) > 0 {
+		return nil
+	}
+	return map[string]string{
+		PrivateKeyCreatedAtKey: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// GetServiceCredentialExpiry returns false since private keys of github apps do not expire. Their age is tracked instead.
+func (g *Github) GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
+// verifyPrivateKey authenticates as the github app with the private key.
+func (g *Github) verifyPrivateKey(ctx context.Context, privateKey []byte) error {
+	itr, err := ghinstallation.NewAppsTransport(tracing.NewTransport(http.DefaultTransport), g.appID, privateKey)
+	if err != nil {
+		return microerror.Maskf(invalidConfigError, "not a valid private key: %v", err)
+	}
+	opts := []githubclient.ClientOptionsFunc{githubclient.WithHTTPClient(&http.Client{Transport: itr})}
+	if g.Client != nil {
+		baseURL := g.Client.BaseURL()
+		opts = append(opts, githubclient.WithURLs(&baseURL, nil))
+	}
+	client, err := githubclient.NewClient(opts...)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	app, resp, err := client.Apps.Get(ctx, "")
+	if err != nil {
+		return microerror.Maskf(requestFailedError, "Failed to authenticate with private key: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return microerror.Maskf(requestFailedError, "request returned not ok status %v", resp)
+	}
+	if app.GetID() != g.appID {
+		return microerror.Maskf(invalidConfigError, "private key belongs to github app %d instead of %d", app.GetID(), g.appID)
+	}
+	return nil
+}
+
+// getApp returns the authenticated github app and records the request in metrics.
+func (g *Github) getApp(ctx context.Context) (*githubclient.App, error) {
+	start := time.Now()
+	app, resp, err := g.Client.Apps.Get(ctx, "")
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	provider.ObserveRequest(g.Type, g.Name, provider.OperationGetApp, start, statusCode, err)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	return app, nil
+}
+
+// getAppSlug returns the slug of the github app for links to its settings.
+func (g *Github) getAppSlug(ctx context.Context, config provider.AppConfig) string {
+	if g.Client != nil {
+		if app, err := g.getApp(ctx); err == nil && app.GetSlug() != "" {
+			return app.GetSlug()
+		}
+	}
+	return config.Name
+}
+
+// privateKeyDue returns true once the private key is within the renewal threshold of its validity.
+func privateKeyDue(createdAt time.Time, config provider.AppConfig) bool {
+	return time.Now().After(createdAt.Add(config.SecretValidity - config.SecretRenewBefore))
 }
@@ -1,12 +1,22 @@
 package github
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 
 	"github.com/go-logr/logr"
+	githubclient "github.com/google/go-github/v88/github"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -92,6 +102,24 @@ func TestNewConfig(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "case 6",
+			credentials: provider.ProviderCredential{
+				Name:  "name",
+				Owner: "test",
+				Credentials: map[string]string{
+					OrganizationKey:        "org",
+					TeamKey:                "team",
+					AppIDKey:               "123",
+					PrivateKeyKey:          "abc",
+					ClientSecretKey:        "def",
+					ClientIDKey:            "456",
+					PrivateKeyCreatedAtKey: "last quarter",
+				},
+			},
+			log:         provider.GetTestLogger(),
+			expectError: true,
+		},
 	}
 
 	for i, tc := range testCases {
@@ -106,3 +134,161 @@ func TestNewConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestPrivateKeyDue(t *testing.T) {
+	config := provider.AppConfig{SecretValidity: 90 * 24 * time.Hour, SecretRenewBefore: 30 * 24 * time.Hour}
+	testCases := []struct {
+		name      string
+		createdAt time.Time
+		due       bool
+	}{
+		{
+			name:      "case 0: new key",
+			createdAt: time.Now().Add(-24 * time.Hour),
+		},
+		{
+			name:      "case 1: key within the renewal threshold",
+			createdAt: time.Now().Add(-61 * 24 * time.Hour),
+			due:       true,
+		},
+		{
+			name:      "case 2: key older than its validity",
+			createdAt: time.Now().Add(-365 * 24 * time.Hour),
+			due:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if due := privateKeyDue(tc.createdAt, config); due != tc.due {
+				t.Fatalf("Expected %v, got %v", tc.due, due)
+			}
+		})
+	}
+}
+
+func TestRotateServiceCredentials(t *testing.T) {
+	privateKey := generatePrivateKey(t)
+
+	testCases := []struct {
+		name                 string
+		nextPrivateKey       []byte
+		privateKeyCreatedAt  time.Time
+		serverAppID          int64
+		expectManualAction   bool
+		expectMetadata       bool
+		expectError          bool
+		expectedCredentials  []string
+		expectedShouldRotate bool
+	}{
+		{
+			name:                 "case 0: no next private key",
+			privateKeyCreatedAt:  time.Now().Add(-100 * 24 * time.Hour),
+			serverAppID:          123,
+			expectManualAction:   true,
+			expectedShouldRotate: true,
+		},
+		{
+			name:                 "case 1: valid next private key",
+			nextPrivateKey:       privateKey,
+			serverAppID:          123,
+			expectedCredentials:  []string{PrivateKeyKey, PrivateKeyCreatedAtKey, NextPrivateKeyKey},
+			expectedShouldRotate: true,
+		},
+		{
+			name:                 "case 2: next private key of another app",
+			nextPrivateKey:       privateKey,
+			serverAppID:          456,
+			expectedCredentials:  []string{PrivateKeyKey, PrivateKeyCreatedAtKey, NextPrivateKeyKey},
+			expectError:          true,
+			expectedShouldRotate: true,
+		},
+		{
+			name:                 "case 3: invalid next private key",
+			nextPrivateKey:       []byte("abc"),
+			serverAppID:          123,
+			expectedCredentials:  []string{PrivateKeyKey, PrivateKeyCreatedAtKey, NextPrivateKeyKey},
+			expectError:          true,
+			expectedShouldRotate: true,
+		},
+		{
+			name:               "case 4: private key of unknown age",
+			serverAppID:        123,
+			expectManualAction: true,
+			expectMetadata:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"id": %d, "slug": "test-dex-operator"}`, tc.serverAppID)
+			}))
+			defer server.Close()
+			baseURL := server.URL + "/"
+			client, err := githubclient.NewClient(githubclient.WithURLs(&baseURL, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := &Github{
+				Client:              client,
+				Log:                 provider.GetTestLogger(),
+				Name:                "giantswarm-github",
+				Organization:        "org",
+				appID:               123,
+				nextPrivateKey:      tc.nextPrivateKey,
+				privateKeyCreatedAt: tc.privateKeyCreatedAt,
+			}
+			config := provider.GetTestConfig()
+
+			metadata := g.GetMissingServiceCredentialMetadata()
+			if _, ok := metadata[PrivateKeyCreatedAtKey]; ok != tc.expectMetadata {
+				t.Fatalf("Expected %s to be recorded %v, got %v", PrivateKeyCreatedAtKey, tc.expectMetadata, metadata)
+			}
+
+			shouldRotate, err := g.ShouldRotateServiceCredentials(context.Background(), config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shouldRotate != tc.expectedShouldRotate {
+				t.Fatalf("Expected should rotate %v, got %v", tc.expectedShouldRotate, shouldRotate)
+			}
+
+			credentials, err := g.RotateServiceCredentials(context.Background(), config)
+			if tc.expectManualAction {
+				if !provider.IsManualActionRequired(err) {
+					t.Fatalf("Expected manual action required error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range tc.expectedCredentials {
+				if _, ok := credentials[k]; !ok {
+					t.Fatalf("Expected credential %s in %v", k, credentials)
+				}
+			}
+			if credentials[PrivateKeyKey] != string(tc.nextPrivateKey) {
+				t.Fatalf("Expected next private key to become the private key")
+			}
+
+			err = g.VerifyServiceCredentials(context.Background(), config, credentials)
+			if err != nil && !tc.expectError {
+				t.Fatal(err)
+			}
+			if err == nil && tc.expectError {
+				t.Fatalf("Expected an error, got success.")
+			}
+		})
+	}
+}
+
+func generatePrivateKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
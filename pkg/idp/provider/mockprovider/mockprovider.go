@@ -28,6 +28,11 @@ type MockProvider struct {
 	Description string
 	Type        string
 	Owner       string
+	Display     provider.ConnectorDisplay
+	Selector    provider.ProviderSelector
+	Lifetime    provider.SecretLifetime
+	// Retention holds the retention marks of app registrations by app name.
+	Retention map[string]provider.AppRetention
 }
 
 var _ provider.Provider = (*MockProvider)(nil)
@@ -38,6 +43,9 @@ func New(config provider.ProviderConfig) (*MockProvider, error) {
 		Description: config.Credential.GetConnectorDescription(ProviderDisplayName),
 		Type:        ProviderConnectorType,
 		Owner:       config.Credential.Owner,
+		Display:     config.Credential.Display,
+		Selector:    config.Credential.Selector,
+		Lifetime:    config.Credential.Lifetime,
 	}, nil
 }
 
@@ -57,6 +65,18 @@ func (m *MockProvider) GetOwner() string {
 	return m.Owner
 }
 
+func (m *MockProvider) GetConnectorDisplay() provider.ConnectorDisplay {
+	return m.Display
+}
+
+func (m *MockProvider) GetSelector() provider.ProviderSelector {
+	return m.Selector
+}
+
+func (m *MockProvider) GetSecretLifetime() provider.SecretLifetime {
+	return m.Lifetime
+}
+
 func (m *MockProvider) CreateOrUpdateApp(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderApp, error) {
 	connectorConfig := &mock.PasswordConfig{
 		Username: "test",
@@ -73,7 +93,7 @@ func (m *MockProvider) CreateOrUpdateApp(config provider.AppConfig, ctx context.
 			Name:   m.Description,
 			Config: string(data[:]),
 		},
-		SecretEndDateTime: time.Now().AddDate(0, 6, 0),
+		SecretEndDateTime: time.Now().Add(config.SecretValidity),
 	}, nil
 }
 
@@ -81,6 +101,18 @@ func (m *MockProvider) DeleteApp(name string, ctx context.Context) error {
 	return nil
 }
 
+func (m *MockProvider) SetAppRetention(ctx context.Context, name string, retention *provider.AppRetention) error {
+	if retention == nil {
+		delete(m.Retention, name)
+		return nil
+	}
+	if m.Retention == nil {
+		m.Retention = map[string]provider.AppRetention{}
+	}
+	m.Retention[name] = *retention
+	return nil
+}
+
 func (m *MockProvider) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (map[string]string, error) {
 	return map[string]string{
 		"client-id":     "abc",
@@ -109,6 +141,26 @@ func (m *MockProvider) RotateServiceCredentials(ctx context.Context, config prov
 	return nil, microerror.Maskf(invalidConfigError, "Mock provider does not support service credential rotation")
 }
 
+func (m *MockProvider) VerifyServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	return microerror.Maskf(invalidConfigError, "Mock provider does not support service credential rotation")
+}
+
+func (m *MockProvider) RevertServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	return nil
+}
+
+func (m *MockProvider) GetServiceCredentialCreatedAt() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (m *MockProvider) GetMissingServiceCredentialMetadata() map[string]string {
+	return nil
+}
+
+func (m *MockProvider) GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
 func MockCert() string {
 	return `-----BEGIN MOCK CERT-----
 mock
@@ -17,6 +17,9 @@ type ProviderConfig struct {
 	Credential            ProviderCredential
 	Log                   logr.Logger
 	ManagementClusterName string
+	// DryRun puts the provider in read-only mode. Changes that would be applied
+	// to the identity provider are logged instead of being executed.
+	DryRun bool
 }
 
 type Provider interface {
@@ -29,19 +32,57 @@ type Provider interface {
 	GetProviderName() string
 	GetOwner() string
 	GetType() string
+	GetConnectorDisplay() ConnectorDisplay
+	GetSelector() ProviderSelector
+	GetSecretLifetime() SecretLifetime
+	// SetAppRetention marks the app registration as retained in the identity provider, or removes the mark
+	// if retention is nil. Providers without a place for such marks do nothing.
+	SetAppRetention(ctx context.Context, name string, retention *AppRetention) error
 
 	// Self-renewal methods - all providers must implement these
 	// Providers that don't support renewal should return false from SupportsServiceCredentialRenewal()
 	SupportsServiceCredentialRenewal() bool
 	ShouldRotateServiceCredentials(ctx context.Context, config AppConfig) (bool, error)
 	RotateServiceCredentials(ctx context.Context, config AppConfig) (map[string]string, error)
+	// VerifyServiceCredentials authenticates with the given credentials. Missing credentials default to the current ones.
+	VerifyServiceCredentials(ctx context.Context, config AppConfig, credentials map[string]string) error
+	// RevertServiceCredentials removes credentials returned by RotateServiceCredentials which are not going to be used.
+	RevertServiceCredentials(ctx context.Context, config AppConfig, credentials map[string]string) error
+	// GetServiceCredentialCreatedAt returns when the service credentials were created, if the provider tracks it.
+	GetServiceCredentialCreatedAt() (time.Time, bool)
+	// GetMissingServiceCredentialMetadata returns credentials to add to the service credentials, e.g. the time
+	// a credential of unknown age was first seen. It returns nil if no metadata is missing.
+	GetMissingServiceCredentialMetadata() map[string]string
+	// GetServiceCredentialExpiry returns when the service credentials expire. It returns false if they do not expire.
+	GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error)
 }
 
 type AppConfig struct {
-	RedirectURI          string
-	Name                 string
-	IdentifierURI        string
-	SecretValidityMonths int
+	RedirectURI   string
+	IssuerURI     string
+	BaseDomain    string
+	Name          string
+	IdentifierURI string
+	// SecretValidity is the lifetime of new client secrets.
+	SecretValidity time.Duration
+	// SecretRenewBefore is the time before expiry at which client secrets are renewed.
+	SecretRenewBefore time.Duration
+	// DexConfigRolledOut is true once the dex config currently written to the dex config secret has been
+	// deployed, so previous client secrets are no longer in use and can be revoked.
+	DexConfigRolledOut bool
+	// RotationAllowed is true if scheduled rotations of client secrets may happen now, i.e. within a
+	// rotation window and with a free rotation slot. Missing or almost expired secrets are always renewed.
+	RotationAllowed bool
+	// RotationJitter delays scheduled rotations of the target to spread them over time.
+	RotationJitter time.Duration
+}
+
+// AppRetention describes an app registration which is kept after its dex target was deleted or no longer selects the provider.
+type AppRetention struct {
+	// Target is the namespaced name of the dex target the app registration belonged to.
+	Target string
+	// Until is when the retention sweeper deletes the app registration. Zero means it is kept until removed manually.
+	Until time.Time
 }
 
 type ProviderCredential struct {
@@ -49,24 +90,82 @@ type ProviderCredential struct {
 	Owner       string            `yaml:"owner"`
 	Credentials map[string]string `yaml:"credentials"`
 	Description string            `yaml:"description"`
+	// OwnerDisplayName is used in the default connector description, e.g. "GitHub for Partner Inc".
+	// Defaults to a well-known display name for giantswarm and customer and to the owner name otherwise.
+	OwnerDisplayName string `yaml:"ownerDisplayName,omitempty"`
+
+	Display ConnectorDisplay `yaml:",inline"`
+	// Selector restricts the dex targets which get a connector for this provider. All targets are selected if empty.
+	Selector ProviderSelector `yaml:"selector,omitempty"`
+	Lifetime SecretLifetime   `yaml:",inline"`
+}
+
+// SecretLifetime configures the validity of client secrets created for a provider and when they are renewed.
+// Durations use the Go syntax, e.g. 720h. Defaults apply to empty fields.
+type SecretLifetime struct {
+	// Validity of new client secrets of dex apps and of the credentials of dex-operator itself.
+	Validity string `yaml:"secretValidity,omitempty"`
+	// RenewBefore is the time before expiry at which client secrets of dex apps are rotated.
+	RenewBefore string `yaml:"secretRenewBefore,omitempty"`
+	// CredentialRenewBefore is the time before expiry at which dex-operator renews its own credentials.
+	CredentialRenewBefore string `yaml:"credentialRenewBefore,omitempty"`
+}
+
+// ProviderSelector selects the dex targets of a provider. All set fields need to match.
+// Label selectors use the kubectl syntax, e.g. "environment in (production),!legacy".
+type ProviderSelector struct {
+	// ManagementCluster selects only the dex of the management cluster if true and only dex of workload clusters if false.
+	ManagementCluster *bool `yaml:"managementCluster,omitempty"`
+	// Target matches the labels of the App CR or HelmRelease.
+	Target string `yaml:"target,omitempty"`
+	// Cluster matches the labels of the CAPI cluster of the dex target.
+	Cluster string `yaml:"cluster,omitempty"`
+	// Organization matches the labels of the organization namespace of the dex target.
+	Organization string `yaml:"organization,omitempty"`
+}
+
+func (s ProviderSelector) IsEmpty() bool {
+	return s.ManagementCluster == nil && s.Target == "" && s.Cluster == "" && s.Organization == ""
+}
+
+// ConnectorDisplay controls how the connector of a provider is shown on the dex login page.
+type ConnectorDisplay struct {
+	// Priority orders connectors within their owner, connectors with higher priority are shown first.
+	Priority int `yaml:"priority,omitempty"`
+	// Icon is a hint for the login page theme, e.g. a logo URL or icon name.
+	Icon string `yaml:"icon,omitempty"`
+	// Hidden connectors stay configured in dex but are not shown on the login page.
+	Hidden bool `yaml:"hidden,omitempty"`
+	// Disabled connectors are removed from the dex config while their app registrations are kept.
+	Disabled bool `yaml:"disabled,omitempty"`
 }
 
 func (c ProviderCredential) GetConnectorDescription(providerDisplayName string) string {
 	if c.Description != "" {
 		return c.Description
 	}
+	if c.OwnerDisplayName != "" {
+		return key.GetConnectorDescription(providerDisplayName, c.OwnerDisplayName)
+	}
 	return key.GetDefaultConnectorDescription(providerDisplayName, c.Owner)
 }
 
 type ProviderApp struct {
 	Connector         dex.Connector
 	SecretEndDateTime time.Time
+	// SecretCreated is true if a new client secret was created which dex still needs to pick up.
+	SecretCreated bool
 }
 
+// DryRunSecretPlaceholder is used as client secret in connectors computed in dry-run mode
+// whenever a new secret would be created in the identity provider.
+const DryRunSecretPlaceholder = "<dry-run: new secret>"
+
 type ProviderSecret struct {
 	ClientId     string
 	ClientSecret string
 	EndDateTime  time.Time
+	Created      bool
 }
 
 func ReadCredentials(fileLocation string) ([]ProviderCredential, error) {
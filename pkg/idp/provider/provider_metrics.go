@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricNamespace = "dex_operator"
+	metricSubsystem = "provider"
+
+	// Operations of requests to the APIs of identity providers in metrics
+	OperationGetApp       = "get_app"
+	OperationListApps     = "list_apps"
+	OperationCreateApp    = "create_app"
+	OperationPatchApp     = "patch_app"
+	OperationDeleteApp    = "delete_app"
+	OperationCreateSecret = "create_secret"
+	OperationDeleteSecret = "delete_secret"
+)
+
+var (
+	requestLabels = []string{
+		"provider_type",
+		"provider_name",
+		"operation",
+	}
+
+	// RequestDuration tracks the latency of requests to the APIs of identity providers by operation.
+	RequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Gives the duration of requests to the APIs of identity providers by operation.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		requestLabels,
+	)
+
+	// RequestErrors counts failed requests to the APIs of identity providers by operation and HTTP status code,
+	// e.g. 429 when the identity provider throttles dex-operator.
+	RequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: metricSubsystem,
+			Name:      "request_errors_total",
+			Help:      "Counts failed requests to the APIs of identity providers by operation and status code.",
+		},
+		append(requestLabels, "status_code"),
+	)
+)
+
+// ObserveRequest records the duration of a request to the API of an identity provider which started at start.
+// Failed requests are counted by status code, which is 0 if the request failed without a response.
+func ObserveRequest(providerType string, providerName string, operation string, start time.Time, statusCode int, err error) {
+	RequestDuration.WithLabelValues(providerType, providerName, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RequestErrors.WithLabelValues(providerType, providerName, operation, strconv.Itoa(statusCode)).Inc()
+	}
+}
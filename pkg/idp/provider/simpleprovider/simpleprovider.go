@@ -34,6 +34,9 @@ type SimpleProvider struct {
 	Description     string
 	Type            string
 	Owner           string
+	Display         provider.ConnectorDisplay
+	Selector        provider.ProviderSelector
+	Lifetime        provider.SecretLifetime
 	ConnectorType   string
 	ConnectorConfig string
 }
@@ -57,6 +60,9 @@ func New(config provider.ProviderConfig) (*SimpleProvider, error) {
 		Description:     config.Credential.GetConnectorDescription(ProviderDisplayName),
 		Type:            ProviderType,
 		Owner:           config.Credential.Owner,
+		Display:         config.Credential.Display,
+		Selector:        config.Credential.Selector,
+		Lifetime:        config.Credential.Lifetime,
 		ConnectorType:   c.connectorType,
 		ConnectorConfig: c.connectorConfig,
 	}, nil
@@ -106,6 +112,18 @@ func (s *SimpleProvider) GetOwner() string {
 	return s.Owner
 }
 
+func (s *SimpleProvider) GetConnectorDisplay() provider.ConnectorDisplay {
+	return s.Display
+}
+
+func (s *SimpleProvider) GetSelector() provider.ProviderSelector {
+	return s.Selector
+}
+
+func (s *SimpleProvider) GetSecretLifetime() provider.SecretLifetime {
+	return s.Lifetime
+}
+
 func (s *SimpleProvider) CreateOrUpdateApp(config provider.AppConfig, ctx context.Context, oldConnector dex.Connector) (provider.ProviderApp, error) {
 	// Inject the redirect URI into the connector config
 	connectorConfig := s.injectRedirectURI(config.RedirectURI)
@@ -123,7 +141,7 @@ func (s *SimpleProvider) CreateOrUpdateApp(config provider.AppConfig, ctx contex
 			Name:   s.Description,
 			Config: connectorConfig,
 		},
-		SecretEndDateTime: time.Now().AddDate(0, 6, 0),
+		SecretEndDateTime: time.Now().Add(config.SecretValidity),
 	}, nil
 }
 
@@ -131,6 +149,11 @@ func (s *SimpleProvider) DeleteApp(name string, ctx context.Context) error {
 	return nil
 }
 
+// SetAppRetention does nothing, the app registrations of simple providers are managed outside of dex-operator.
+func (s *SimpleProvider) SetAppRetention(ctx context.Context, name string, retention *provider.AppRetention) error {
+	return nil
+}
+
 func (s *SimpleProvider) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (map[string]string, error) {
 	s.Log.Info(fmt.Sprintf("No new credentials will be created for the %s provider because it does not allow dex-operator access.", ProviderName))
 	return map[string]string{}, nil
@@ -200,3 +223,23 @@ func (s *SimpleProvider) ShouldRotateServiceCredentials(ctx context.Context, con
 func (s *SimpleProvider) RotateServiceCredentials(ctx context.Context, config provider.AppConfig) (map[string]string, error) {
 	return nil, microerror.Maskf(invalidConfigError, "Simple provider does not support service credential rotation")
 }
+
+func (s *SimpleProvider) VerifyServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	return microerror.Maskf(invalidConfigError, "Simple provider does not support service credential rotation")
+}
+
+func (s *SimpleProvider) RevertServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	return nil
+}
+
+func (s *SimpleProvider) GetServiceCredentialCreatedAt() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (s *SimpleProvider) GetMissingServiceCredentialMetadata() map[string]string {
+	return nil
+}
+
+func (s *SimpleProvider) GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
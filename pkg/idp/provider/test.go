@@ -1,12 +1,14 @@
 package provider
 
 import (
+	"github.com/giantswarm/dex-operator/pkg/key"
+
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 func GetTestConfig() AppConfig {
-	return AppConfig{RedirectURI: "hello.io", Name: "test", SecretValidityMonths: 6}
+	return AppConfig{RedirectURI: "hello.io", Name: "test", SecretValidity: key.DefaultSecretValidity, SecretRenewBefore: key.DefaultSecretRenewBefore}
 }
 
 func GetTestCredential() ProviderCredential {
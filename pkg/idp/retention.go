@@ -0,0 +1,338 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const (
+	// DeletionPolicyDelete removes app registrations from identity providers together with the dex target.
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyRetain keeps app registrations until they are removed manually.
+	DeletionPolicyRetain = "Retain"
+	// DeletionPolicyRetainForPrefix keeps app registrations for the duration following the prefix,
+	// e.g. RetainFor:168h, after which they are removed by the retention sweeper.
+	DeletionPolicyRetainForPrefix = "RetainFor:"
+)
+
+// DeletionPolicy describes what happens to identity provider app registrations
+// when their dex target is deleted.
+type DeletionPolicy struct {
+	Retain bool
+	// RetainFor limits how long retained app registrations are kept.
+	// Zero means they are kept until removed manually.
+	RetainFor time.Duration
+}
+
+func (p DeletionPolicy) String() string {
+	switch {
+	case !p.Retain:
+		return DeletionPolicyDelete
+	case p.RetainFor > 0:
+		return DeletionPolicyRetainForPrefix + p.RetainFor.String()
+	default:
+		return DeletionPolicyRetain
+	}
+}
+
+// ParseDeletionPolicy parses Delete, Retain or RetainFor:<duration>. An empty policy defaults to Delete.
+func ParseDeletionPolicy(policy string) (DeletionPolicy, error) {
+	switch {
+	case policy == "" || policy == DeletionPolicyDelete:
+		return DeletionPolicy{}, nil
+	case policy == DeletionPolicyRetain:
+		return DeletionPolicy{Retain: true}, nil
+	case strings.HasPrefix(policy, DeletionPolicyRetainForPrefix):
+		d, err := time.ParseDuration(strings.TrimPrefix(policy, DeletionPolicyRetainForPrefix))
+		if err != nil {
+			return DeletionPolicy{}, microerror.Maskf(invalidConfigError, "invalid retention duration in deletion policy %s: %s", policy, err)
+		}
+		if d <= 0 {
+			return DeletionPolicy{}, microerror.Maskf(invalidConfigError, "retention duration in deletion policy %s must be positive", policy)
+		}
+		return DeletionPolicy{Retain: true, RetainFor: d}, nil
+	}
+	return DeletionPolicy{}, microerror.Maskf(invalidConfigError, "deletion policy %s is not one of %s, %s or %s<duration>", policy, DeletionPolicyDelete, DeletionPolicyRetain, DeletionPolicyRetainForPrefix)
+}
+
+// getDeletionPolicy returns the deletion policy of the target, falling back to the operator-wide policy.
+func (s *Service) getDeletionPolicy() (DeletionPolicy, error) {
+	policy, ok := s.target.GetObject().GetAnnotations()[key.DeletionPolicyAnnotation]
+	if !ok {
+		return s.deletionPolicy, nil
+	}
+	return ParseDeletionPolicy(policy)
+}
+
+// retention returns how the app registrations of the target are retained under the policy from now on.
+func (s *Service) retention(policy DeletionPolicy) provider.AppRetention {
+	retention := provider.AppRetention{Target: s.target.GetNamespacedName().String()}
+	if policy.RetainFor > 0 {
+		retention.Until = time.Now().Add(policy.RetainFor).UTC()
+	}
+	return retention
+}
+
+// retainProviderApps marks the app registrations of the target as retained in the identity providers instead of
+// deleting them and records them in a configmap. The record is kept in the retention namespace, so that it outlives
+// the namespace of the target, and is the index the retention sweeper uses once the retention duration has passed.
+func (s *Service) retainProviderApps(ctx context.Context, appName string, policy DeletionPolicy) error {
+	nn := s.target.GetNamespacedName()
+	retention := s.retention(policy)
+	for _, p := range s.providers {
+		if err := p.SetAppRetention(ctx, appName, &retention); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if err := s.recordRetainedProviderApps(ctx, key.GetRetainedAppsName(nn.Namespace, nn.Name), appName, "", retention); err != nil {
+		return microerror.Mask(err)
+	}
+	for _, provider := range s.providers {
+		AppInfo.DeleteLabelValues(nn.Name, nn.Namespace, provider.GetOwner(), provider.GetType(), provider.GetName(), appName)
+	}
+	s.log.Info(fmt.Sprintf("Retained app registrations %s according to deletion policy %s.", appName, policy))
+	return nil
+}
+
+// retainProviderApp records the app registration of a single provider, e.g. one which is no longer selected
+// for the target, in its own retention record.
+func (s *Service) retainProviderApp(ctx context.Context, appName string, p provider.Provider, policy DeletionPolicy) error {
+	nn := s.target.GetNamespacedName()
+	retention := s.retention(policy)
+	if err := p.SetAppRetention(ctx, appName, &retention); err != nil {
+		return microerror.Mask(err)
+	}
+	if err := s.recordRetainedProviderApps(ctx, key.GetRetainedProviderAppsName(nn.Namespace, nn.Name, p.GetName()), appName, p.GetName(), retention); err != nil {
+		return microerror.Mask(err)
+	}
+	AppInfo.DeleteLabelValues(nn.Name, nn.Namespace, p.GetOwner(), p.GetType(), p.GetName(), appName)
+	s.log.Info(fmt.Sprintf("Retained app %s of type %s for %s according to deletion policy %s.", p.GetName(), p.GetType(), p.GetOwner(), policy))
+	return nil
+}
+
+// recordRetainedProviderApps creates or updates a retention record. Records with a provider only
+// cover the app registration of that provider.
+func (s *Service) recordRetainedProviderApps(ctx context.Context, name string, appName string, providerName string, retention provider.AppRetention) error {
+	nn := s.target.GetNamespacedName()
+
+	record := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.retentionNamespace,
+		},
+	}
+	if err := s.Get(ctx, client.ObjectKeyFromObject(record), record); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return microerror.Mask(err)
+		}
+	}
+	if record.Labels == nil {
+		record.Labels = map[string]string{}
+	}
+	record.Labels[key.RetainedAppsLabel] = "true"
+	if record.Annotations == nil {
+		record.Annotations = map[string]string{}
+	}
+	delete(record.Annotations, key.RetainUntilAnnotation)
+	if !retention.Until.IsZero() {
+		record.Annotations[key.RetainUntilAnnotation] = retention.Until.Format(time.RFC3339)
+	}
+	record.Data = map[string]string{
+		key.RetainedAppNameKey: appName,
+		key.RetainedTargetKey:  nn.String(),
+	}
+	if providerName != "" {
+		record.Data[key.RetainedProviderKey] = providerName
+	}
+
+	if record.ResourceVersion == "" {
+		if err := s.Create(ctx, record); err != nil {
+			return microerror.Mask(err)
+		}
+	} else {
+		if err := s.Update(ctx, record); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	return nil
+}
+
+// releaseRetainedProviderApps removes the retention record of the target, if any.
+// This happens when a dex target with the same name takes over the retained app registrations.
+func (s *Service) releaseRetainedProviderApps(ctx context.Context) error {
+	nn := s.target.GetNamespacedName()
+
+	record := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{Name: key.GetRetainedAppsName(nn.Namespace, nn.Name), Namespace: s.retentionNamespace}, record); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return microerror.Mask(err)
+	}
+	if record.Labels[key.RetainedAppsLabel] != "true" {
+		return nil
+	}
+	for _, p := range s.providers {
+		if err := p.SetAppRetention(ctx, record.Data[key.RetainedAppNameKey], nil); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if err := s.Delete(ctx, record); err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+	s.log.Info(fmt.Sprintf("Took over retained app registrations %s.", record.Data[key.RetainedAppNameKey]))
+	return nil
+}
+
+// releaseRetainedProviderApp removes the retention record of the app registration of a single provider, if any.
+// This happens when the provider is selected for the target again and reuses its app registration.
+func (s *Service) releaseRetainedProviderApp(ctx context.Context, providerName string) error {
+	nn := s.target.GetNamespacedName()
+
+	record := &corev1.ConfigMap{}
+	if err := s.Get(ctx, types.NamespacedName{Name: key.GetRetainedProviderAppsName(nn.Namespace, nn.Name, providerName), Namespace: s.retentionNamespace}, record); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return microerror.Mask(err)
+	}
+	if record.Labels[key.RetainedAppsLabel] != "true" || record.Data[key.RetainedProviderKey] != providerName {
+		return nil
+	}
+	for _, p := range s.providers {
+		if p.GetName() != providerName {
+			continue
+		}
+		if err := p.SetAppRetention(ctx, record.Data[key.RetainedAppNameKey], nil); err != nil {
+			return microerror.Mask(err)
+		}
+	}
+	if err := s.Delete(ctx, record); err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+	s.log.Info(fmt.Sprintf("Took over retained app %s of app registrations %s.", providerName, record.Data[key.RetainedAppNameKey]))
+	return nil
+}
+
+type RetentionSweeperConfig struct {
+	Client    client.Client
+	Log       logr.Logger
+	Providers []provider.Provider
+	// Namespace is the retention namespace the records of retained app registrations are kept in.
+	Namespace string
+	DryRun    bool
+}
+
+// RetentionSweeper removes retained app registrations from identity providers once their retention expired.
+type RetentionSweeper struct {
+	client.Client
+	log       logr.Logger
+	providers []provider.Provider
+	namespace string
+	dryRun    bool
+}
+
+func NewRetentionSweeper(c RetentionSweeperConfig) (*RetentionSweeper, error) {
+	if c.Client == nil {
+		return nil, microerror.Maskf(invalidConfigError, "client cannot be nil")
+	}
+	if (logr.Logger{}) == c.Log {
+		return nil, microerror.Maskf(invalidConfigError, "log cannot be nil")
+	}
+	if c.Providers == nil {
+		return nil, microerror.Maskf(invalidConfigError, "providers can not be nil")
+	}
+	if c.Namespace == "" {
+		return nil, microerror.Maskf(invalidConfigError, "namespace cannot be empty")
+	}
+	return &RetentionSweeper{
+		Client:    c.Client,
+		log:       c.Log,
+		providers: c.Providers,
+		namespace: c.Namespace,
+		dryRun:    c.DryRun,
+	}, nil
+}
+
+// Sweep deletes all retained app registrations whose retention expired before now.
+// Failures for single records are logged and do not stop the sweep.
+func (s *RetentionSweeper) Sweep(ctx context.Context, now time.Time) error {
+	records := &corev1.ConfigMapList{}
+	if err := s.List(ctx, records, client.InNamespace(s.namespace), client.MatchingLabels{key.RetainedAppsLabel: "true"}); err != nil {
+		return microerror.Mask(err)
+	}
+
+	var failed int
+	for i := range records.Items {
+		record := &records.Items[i]
+		expired, err := retentionExpired(record, now)
+		if err != nil {
+			s.log.Error(err, fmt.Sprintf("Skipping retained app registrations %s/%s.", record.Namespace, record.Name))
+			failed++
+			continue
+		}
+		if !expired {
+			continue
+		}
+		if err := s.sweep(ctx, record); err != nil {
+			s.log.Error(err, fmt.Sprintf("Failed to remove retained app registrations %s/%s.", record.Namespace, record.Name))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return microerror.Maskf(sweepFailedError, "%d retained app registration records could not be swept", failed)
+	}
+	return nil
+}
+
+func (s *RetentionSweeper) sweep(ctx context.Context, record *corev1.ConfigMap) error {
+	appName := record.Data[key.RetainedAppNameKey]
+	if appName == "" {
+		return microerror.Maskf(invalidConfigError, "record %s/%s does not contain an app name", record.Namespace, record.Name)
+	}
+	if s.dryRun {
+		s.log.Info(fmt.Sprintf("Dry run: would delete retained app registrations %s and record %s/%s.", appName, record.Namespace, record.Name))
+		return nil
+	}
+	providerName := record.Data[key.RetainedProviderKey]
+	for _, provider := range s.providers {
+		if providerName != "" && provider.GetName() != providerName {
+			continue
+		}
+		if err := provider.DeleteApp(appName, ctx); err != nil {
+			return microerror.Mask(err)
+		}
+		s.log.Info(fmt.Sprintf("Deleted retained app %s of type %s for %s.", provider.GetName(), provider.GetType(), provider.GetOwner()))
+	}
+	if err := s.Delete(ctx, record); err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+	return nil
+}
+
+// retentionExpired checks if the retain-until annotation of the record lies before now.
+// Records without the annotation are retained indefinitely.
+func retentionExpired(record *corev1.ConfigMap, now time.Time) (bool, error) {
+	until, ok := record.Annotations[key.RetainUntilAnnotation]
+	if !ok {
+		return false, nil
+	}
+	t, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return false, microerror.Maskf(invalidConfigError, "invalid %s annotation %s: %s", key.RetainUntilAnnotation, until, err)
+	}
+	return !now.Before(t), nil
+}
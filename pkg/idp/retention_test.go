@@ -0,0 +1,339 @@
+package idp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestParseDeletionPolicy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		policy        string
+		expected      DeletionPolicy
+		expectedError bool
+	}{
+		{
+			name:     "case 0: default",
+			policy:   "",
+			expected: DeletionPolicy{},
+		},
+		{
+			name:     "case 1: delete",
+			policy:   DeletionPolicyDelete,
+			expected: DeletionPolicy{},
+		},
+		{
+			name:     "case 2: retain",
+			policy:   DeletionPolicyRetain,
+			expected: DeletionPolicy{Retain: true},
+		},
+		{
+			name:     "case 3: retain for duration",
+			policy:   "RetainFor:168h",
+			expected: DeletionPolicy{Retain: true, RetainFor: 168 * time.Hour},
+		},
+		{
+			name:          "case 4: invalid duration",
+			policy:        "RetainFor:a week",
+			expectedError: true,
+		},
+		{
+			name:          "case 5: negative duration",
+			policy:        "RetainFor:-1h",
+			expectedError: true,
+		},
+		{
+			name:          "case 6: unknown policy",
+			policy:        "Orphan",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ParseDeletionPolicy(tc.policy)
+			if tc.expectedError {
+				if err == nil {
+					t.Fatalf("expected an error, got success")
+				}
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if policy != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, policy)
+			}
+		})
+	}
+}
+
+func TestRetainAndReleaseProviderApps(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	app := getExampleApp()
+	app.Annotations = map[string]string{key.DeletionPolicyAnnotation: "RetainFor:24h"}
+	p := getExampleProvider(key.OwnerGiantswarm).(*mockprovider.MockProvider)
+	s := Service{
+		Client:         fakeClient,
+		log:            ctrl.Log.WithName("test"),
+		target:         dextarget.NewAppTarget(app),
+		providers:      []provider.Provider{p},
+		deletionPolicy: DeletionPolicy{},
+		// records are kept outside of the namespace of the target, so that they outlive it
+		retentionNamespace: key.MCDexAppDefaultNamespace,
+	}
+
+	policy, err := s.getDeletionPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.retainProviderApps(ctx, "mc-example-test", policy); err != nil {
+		t.Fatal(err)
+	}
+
+	record := &corev1.ConfigMap{}
+	nn := types.NamespacedName{Name: key.GetRetainedAppsName(app.Namespace, app.Name), Namespace: key.MCDexAppDefaultNamespace}
+	if err := fakeClient.Get(ctx, nn, record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Labels[key.RetainedAppsLabel] != "true" {
+		t.Fatalf("expected retained apps label to be set")
+	}
+	if record.Data[key.RetainedAppNameKey] != "mc-example-test" {
+		t.Fatalf("expected app name mc-example-test, got %s", record.Data[key.RetainedAppNameKey])
+	}
+	if record.Data[key.RetainedTargetKey] != "example/test" {
+		t.Fatalf("expected target example/test, got %s", record.Data[key.RetainedTargetKey])
+	}
+	if _, ok := record.Annotations[key.RetainUntilAnnotation]; !ok {
+		t.Fatalf("expected retain until annotation to be set")
+	}
+	retention, ok := p.Retention["mc-example-test"]
+	if !ok {
+		t.Fatalf("expected app registration to be marked as retained in the identity provider")
+	}
+	if retention.Target != "example/test" {
+		t.Fatalf("expected retention target example/test, got %s", retention.Target)
+	}
+	if retention.Until.Format(time.RFC3339) != record.Annotations[key.RetainUntilAnnotation] {
+		t.Fatalf("expected retention until %s, got %s", record.Annotations[key.RetainUntilAnnotation], retention.Until.Format(time.RFC3339))
+	}
+
+	if err := s.releaseRetainedProviderApps(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Get(ctx, nn, record); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected retention record to be deleted, got %v", err)
+	}
+	if _, ok := p.Retention["mc-example-test"]; ok {
+		t.Fatalf("expected retention mark to be removed from the identity provider")
+	}
+}
+
+func TestRemoveDeselectedProviderApps(t *testing.T) {
+	testCases := []struct {
+		name           string
+		deletionPolicy string
+		oldConnectors  []string
+		expectedRecord bool
+		expectedGauge  bool
+	}{
+		{
+			name:          "case 0: deselected provider is deleted",
+			oldConnectors: []string{"giantswarm-mock", "customer-mock"},
+		},
+		{
+			name:           "case 1: deselected provider is retained",
+			deletionPolicy: "RetainFor:24h",
+			oldConnectors:  []string{"giantswarm-mock", "customer-mock"},
+			expectedRecord: true,
+		},
+		{
+			name:          "case 2: provider which was never selected",
+			oldConnectors: []string{"giantswarm-mock"},
+			expectedGauge: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+			app := getExampleApp()
+			app.Annotations = map[string]string{key.ProvidersExcludeAnnotation: "customer-mock"}
+			if tc.deletionPolicy != "" {
+				app.Annotations[key.DeletionPolicyAnnotation] = tc.deletionPolicy
+			}
+			deselected := getExampleProvider(key.OwnerCustomer)
+			s := Service{
+				Client:             fakeClient,
+				log:                ctrl.Log.WithName("test"),
+				target:             dextarget.NewAppTarget(app),
+				providers:          []provider.Provider{getExampleProvider(key.OwnerGiantswarm), deselected},
+				retentionNamespace: key.MCDexAppDefaultNamespace,
+			}
+			oldConnectors := map[string]dex.Connector{}
+			for _, name := range tc.oldConnectors {
+				oldConnectors[name] = dex.Connector{ID: name}
+			}
+			appConfig := provider.GetTestConfig()
+			AppInfo.WithLabelValues(app.Name, app.Namespace, deselected.GetOwner(), deselected.GetType(), deselected.GetName(), appConfig.Name).Set(1)
+
+			config, err := s.CreateOrUpdateProviderApps(appConfig, ctx, oldConnectors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := getConnectorsFromConfig(config)[deselected.GetName()]; ok {
+				t.Fatalf("expected no connector of deselected provider")
+			}
+			if gauge := AppInfo.DeleteLabelValues(app.Name, app.Namespace, deselected.GetOwner(), deselected.GetType(), deselected.GetName(), appConfig.Name); gauge != tc.expectedGauge {
+				t.Fatalf("expected app info of deselected provider %v, got %v", tc.expectedGauge, gauge)
+			}
+
+			record := &corev1.ConfigMap{}
+			nn := types.NamespacedName{Name: key.GetRetainedProviderAppsName(app.Namespace, app.Name, deselected.GetName()), Namespace: key.MCDexAppDefaultNamespace}
+			err = fakeClient.Get(ctx, nn, record)
+			if !tc.expectedRecord {
+				if !apierrors.IsNotFound(err) {
+					t.Fatalf("expected no retention record, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Data[key.RetainedProviderKey] != deselected.GetName() {
+				t.Fatalf("expected retention record of provider %s, got %s", deselected.GetName(), record.Data[key.RetainedProviderKey])
+			}
+
+			// the record is released once the provider is selected again
+			if err := s.releaseRetainedProviderApp(ctx, deselected.GetName()); err != nil {
+				t.Fatal(err)
+			}
+			if err := fakeClient.Get(ctx, nn, record); !apierrors.IsNotFound(err) {
+				t.Fatalf("expected retention record to be deleted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		record          *corev1.ConfigMap
+		dryRun          bool
+		expectedDeleted bool
+		expectedError   bool
+	}{
+		{
+			name:            "case 0: expired",
+			record:          getRetentionRecord(now.Add(-time.Hour).Format(time.RFC3339)),
+			expectedDeleted: true,
+		},
+		{
+			name:   "case 1: not yet expired",
+			record: getRetentionRecord(now.Add(time.Hour).Format(time.RFC3339)),
+		},
+		{
+			name:   "case 2: retained indefinitely",
+			record: getRetentionRecord(""),
+		},
+		{
+			name:   "case 3: expired in dry run",
+			record: getRetentionRecord(now.Add(-time.Hour).Format(time.RFC3339)),
+			dryRun: true,
+		},
+		{
+			name:          "case 4: invalid retain until",
+			record:        getRetentionRecord("tomorrow"),
+			expectedError: true,
+		},
+		{
+			name: "case 5: expired outside of the retention namespace",
+			record: func() *corev1.ConfigMap {
+				record := getRetentionRecord(now.Add(-time.Hour).Format(time.RFC3339))
+				record.Namespace = "example"
+				return record
+			}(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.record).Build()
+
+			sweeper, err := NewRetentionSweeper(RetentionSweeperConfig{
+				Client:    fakeClient,
+				Log:       ctrl.Log.WithName("test"),
+				Providers: []provider.Provider{getExampleProvider(key.OwnerGiantswarm)},
+				Namespace: key.MCDexAppDefaultNamespace,
+				DryRun:    tc.dryRun,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sweeper.Sweep(ctx, now)
+			if tc.expectedError {
+				if !IsSweepFailed(err) {
+					t.Fatalf("expected sweep failed error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			err = fakeClient.Get(ctx, types.NamespacedName{Name: tc.record.Name, Namespace: tc.record.Namespace}, &corev1.ConfigMap{})
+			if tc.expectedDeleted && !apierrors.IsNotFound(err) {
+				t.Fatalf("expected record to be deleted, got %v", err)
+			}
+			if !tc.expectedDeleted && err != nil {
+				t.Fatalf("expected record to be kept, got %v", err)
+			}
+		})
+	}
+}
+
+func getRetentionRecord(retainUntil string) *corev1.ConfigMap {
+	record := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.GetRetainedAppsName("example", "test"),
+			Namespace: key.MCDexAppDefaultNamespace,
+			Labels:    map[string]string{key.RetainedAppsLabel: "true"},
+		},
+		Data: map[string]string{key.RetainedAppNameKey: "mc-example-test"},
+	}
+	if retainUntil != "" {
+		record.Annotations = map[string]string{key.RetainUntilAnnotation: retainUntil}
+	}
+	return record
+}
@@ -0,0 +1,217 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/giantswarm/microerror"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const dexConfigRolloutTimeoutReason = "DexConfigRolloutTimeout"
+
+// isDexConfigRolledOut returns true once all pods of the dex deployment run with the dex config written
+// after the connectors in the dex config secret were last changed. This is the case once the config checksum
+// in the pod template of the deployment differs from the one recorded with the change and the deployment
+// finished rolling out its pod template. Until then, identity providers keep previous client secrets so
+// that dex never serves a revoked one. The observed rollout is recorded in the dex config secret.
+func (s *Service) isDexConfigRolledOut(ctx context.Context, secret *corev1.Secret) (bool, error) {
+	value, ok := secret.Annotations[key.DexConfigUpdatedAtAnnotation]
+	if !ok {
+		// configs written before secrets were rotated in two phases have no previous secrets to revoke
+		return true, nil
+	}
+	if _, ok := secret.Annotations[key.DexConfigRolledOutAtAnnotation]; ok {
+		return true, nil
+	}
+	updatedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		s.log.Error(err, fmt.Sprintf("Ignoring invalid annotation %s of dex config secret %s/%s.", key.DexConfigUpdatedAtAnnotation, secret.Namespace, secret.Name))
+		return false, nil
+	}
+
+	deployment, err := s.getDexDeployment(ctx)
+	if err != nil {
+		// an unreachable workload cluster must not block the reconciliation, the rollout times out instead
+		s.log.Error(err, "Failed to get dex deployment, assuming the dex config is not rolled out yet.")
+	} else if deployment == nil {
+		s.log.V(1).Info("No dex deployment found, assuming the dex config is not rolled out yet.")
+	} else if isDeploymentRolledOut(deployment, secret.Annotations[key.DexConfigPreviousChecksumAnnotation]) {
+		if !s.dryRun {
+			secret.Annotations[key.DexConfigRolledOutAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+			if err := s.Update(ctx, secret); err != nil {
+				return false, microerror.Mask(err)
+			}
+		}
+		s.log.Info(fmt.Sprintf("Dex config has been rolled out to dex deployment %s/%s.", deployment.Namespace, deployment.Name))
+		return true, nil
+	}
+
+	if time.Since(updatedAt) >= key.DexConfigRolloutTimeout {
+		s.releaseTimedOutRotation(updatedAt)
+	}
+	return false, nil
+}
+
+// isDeploymentRolledOut returns true if the config checksum of the pod template differs from the previous one
+// and all replicas of the deployment run the current pod template.
+func isDeploymentRolledOut(deployment *appsv1.Deployment, previousChecksum string) bool {
+	if deployment.Spec.Template.Annotations[key.DexDeploymentConfigChecksumAnnotation] == previousChecksum {
+		return false
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.UpdatedReplicas >= replicas && status.Replicas == status.UpdatedReplicas && status.AvailableReplicas >= replicas
+}
+
+// releaseTimedOutRotation releases the rotation slot of a target whose dex config was not rolled out in time,
+// so that it does not block the rotations of other targets. Previous client secrets are kept.
+func (s *Service) releaseTimedOutRotation(updatedAt time.Time) {
+	if s.rotationPolicy == nil || !s.rotationPolicy.IsRotating(s.rotationTarget()) {
+		return
+	}
+	s.rotationPolicy.Finish(s.rotationTarget())
+	message := fmt.Sprintf("Dex config updated at %s was not rolled out within %s, released the secret rotation slot and kept previous client secrets.",
+		updatedAt.Format(time.RFC3339), key.DexConfigRolloutTimeout)
+	s.log.Info(message)
+	if s.recorder != nil {
+		s.recorder.Event(s.target.GetObject(), corev1.EventTypeWarning, dexConfigRolloutTimeoutReason, message)
+	}
+}
+
+// getDexConfigChecksum returns the config checksum of the pod template of the dex deployment or an empty string
+// if it is not known.
+func (s *Service) getDexConfigChecksum(ctx context.Context) string {
+	deployment, err := s.getDexDeployment(ctx)
+	if err != nil {
+		s.log.Error(err, "Failed to get dex deployment, the config checksum is not recorded.")
+		return ""
+	}
+	if deployment == nil {
+		return ""
+	}
+	return deployment.Spec.Template.Annotations[key.DexDeploymentConfigChecksumAnnotation]
+}
+
+// getDexDeployment returns the deployment of the helm release of the target with a config checksum in its
+// pod template. It is read from the cluster the target is deployed to. Returns nil if there is none.
+func (s *Service) getDexDeployment(ctx context.Context) (*appsv1.Deployment, error) {
+	c, err := s.getTargetClusterClient(ctx)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	release := s.target.GetRelease()
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(release.Namespace), client.MatchingLabels{key.DexDeploymentReleaseLabel: release.Name}); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	for i := range deployments.Items {
+		if _, ok := deployments.Items[i].Spec.Template.Annotations[key.DexDeploymentConfigChecksumAnnotation]; ok {
+			return &deployments.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getTargetClusterClient returns a client for the cluster the target is deployed to, built from the
+// kubeconfig secret of the target for workload clusters.
+func (s *Service) getTargetClusterClient(ctx context.Context) (client.Client, error) {
+	nn, secretKey, remote := s.target.GetKubeConfigSecret()
+	if !remote {
+		return s.Client, nil
+	}
+	secret := &corev1.Secret{}
+	if err := s.Get(ctx, nn, secret); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	kubeconfig, ok := secret.Data[secretKey]
+	if !ok {
+		return nil, microerror.Maskf(invalidConfigError, "kubeconfig secret %s has no key %s", nn, secretKey)
+	}
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: s.scheme})
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	return c, nil
+}
+
+// rotationTarget identifies the dex target in the operator-wide rotation policy.
+func (s *Service) rotationTarget() string {
+	nn := s.target.GetNamespacedName()
+	return fmt.Sprintf("%s/%s", s.target.GetTargetType(), nn)
+}
+
+// prepareRotation decides whether scheduled secret rotations may happen for the target in this reconciliation.
+// A target holds a rotation slot from its first rotated secret until the dex config with the new secrets is rolled out.
+// Returns true if a slot was reserved which needs to be released by finishRotation if no secret is rotated.
+func (s *Service) prepareRotation(appConfig *provider.AppConfig) bool {
+	if s.rotationPolicy == nil {
+		appConfig.RotationAllowed = true
+		return false
+	}
+	target := s.rotationTarget()
+	if appConfig.DexConfigRolledOut && s.rotationPolicy.IsRotating(target) {
+		s.rotationPolicy.Finish(target)
+		s.log.Info("Finished secret rotation, the dex config has been rolled out.")
+	}
+	appConfig.RotationJitter = s.rotationPolicy.Jitter(target)
+	if !s.rotationPolicy.InWindow(time.Now()) {
+		return false
+	}
+	rotating := s.rotationPolicy.IsRotating(target)
+	if !s.rotationPolicy.TryStart(target) {
+		s.log.V(1).Info("Postponing secret rotations since the maximum of concurrent rotations is reached.")
+		return false
+	}
+	appConfig.RotationAllowed = true
+	return !rotating
+}
+
+// finishRotation keeps the rotation slot of the target while new secrets are being rolled out and releases
+// a slot reserved by prepareRotation otherwise.
+func (s *Service) finishRotation(reserved bool) {
+	if s.rotationPolicy == nil {
+		return
+	}
+	if s.secretCreated && !s.dryRun {
+		// secrets which could not be postponed count against the cap as well
+		s.rotationPolicy.Start(s.rotationTarget())
+		return
+	}
+	if reserved {
+		s.rotationPolicy.Finish(s.rotationTarget())
+	}
+}
+
+// maxRotationJitter returns the maximum delay of scheduled rotations of the operator-wide rotation policy.
+func (s *Service) maxRotationJitter() time.Duration {
+	if s.rotationPolicy == nil {
+		return 0
+	}
+	return s.rotationPolicy.MaxJitter()
+}
+
+// releaseRotation releases the rotation slot of a deleted target, whose rollout will never be observed.
+func (s *Service) releaseRotation() {
+	if s.rotationPolicy == nil {
+		return
+	}
+	s.rotationPolicy.Finish(s.rotationTarget())
+}
@@ -0,0 +1,294 @@
+package idp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/rotation"
+)
+
+func TestIsDexConfigRolledOut(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	deployment := func(checksum string, generation, observedGeneration int64, updated, replicas int32) *appsv1.Deployment {
+		desired := int32(2)
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "dex",
+				Namespace:  "dex",
+				Generation: generation,
+				Labels:     map[string]string{key.DexDeploymentReleaseLabel: "test"},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &desired,
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{key.DexDeploymentConfigChecksumAnnotation: checksum}},
+				},
+			},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: observedGeneration,
+				Replicas:           replicas,
+				UpdatedReplicas:    updated,
+				AvailableReplicas:  updated,
+			},
+		}
+	}
+
+	testCases := []struct {
+		name             string
+		annotations      map[string]string
+		deployment       *appsv1.Deployment
+		rotating         bool
+		rolledOut        bool
+		expectedRotating bool
+		expectedRecorded bool
+		expectedTimeouts int
+	}{
+		{
+			name:      "case 0: no update recorded",
+			rolledOut: true,
+		},
+		{
+			name: "case 1: rollout already recorded",
+			annotations: map[string]string{
+				key.DexConfigUpdatedAtAnnotation:   now.Add(-time.Hour).Format(time.RFC3339),
+				key.DexConfigRolledOutAtAnnotation: now.Format(time.RFC3339),
+			},
+			rolledOut:        true,
+			expectedRecorded: true,
+		},
+		{
+			name:        "case 2: no dex deployment",
+			annotations: map[string]string{key.DexConfigUpdatedAtAnnotation: now.Add(-time.Minute).Format(time.RFC3339)},
+		},
+		{
+			name: "case 3: config checksum unchanged",
+			annotations: map[string]string{
+				key.DexConfigUpdatedAtAnnotation:        now.Add(-time.Minute).Format(time.RFC3339),
+				key.DexConfigPreviousChecksumAnnotation: "old",
+			},
+			deployment: deployment("old", 1, 1, 2, 2),
+		},
+		{
+			name: "case 4: generation not observed yet",
+			annotations: map[string]string{
+				key.DexConfigUpdatedAtAnnotation:        now.Add(-time.Minute).Format(time.RFC3339),
+				key.DexConfigPreviousChecksumAnnotation: "old",
+			},
+			deployment: deployment("new", 2, 1, 2, 2),
+		},
+		{
+			name: "case 5: old pods still running",
+			annotations: map[string]string{
+				key.DexConfigUpdatedAtAnnotation:        now.Add(-time.Minute).Format(time.RFC3339),
+				key.DexConfigPreviousChecksumAnnotation: "old",
+			},
+			deployment: deployment("new", 2, 2, 1, 3),
+		},
+		{
+			name: "case 6: rolled out",
+			annotations: map[string]string{
+				key.DexConfigUpdatedAtAnnotation:        now.Add(-time.Minute).Format(time.RFC3339),
+				key.DexConfigPreviousChecksumAnnotation: "old",
+			},
+			deployment:       deployment("new", 2, 2, 2, 2),
+			rolledOut:        true,
+			expectedRecorded: true,
+		},
+		{
+			name:        "case 7: invalid annotation",
+			annotations: map[string]string{key.DexConfigUpdatedAtAnnotation: "yesterday"},
+			deployment:  deployment("new", 2, 2, 2, 2),
+		},
+		{
+			name:             "case 8: rollout within the timeout keeps the rotation slot",
+			annotations:      map[string]string{key.DexConfigUpdatedAtAnnotation: now.Add(-time.Minute).Format(time.RFC3339)},
+			rotating:         true,
+			expectedRotating: true,
+		},
+		{
+			name:             "case 9: rollout timeout releases the rotation slot",
+			annotations:      map[string]string{key.DexConfigUpdatedAtAnnotation: now.Add(-time.Hour).Format(time.RFC3339)},
+			rotating:         true,
+			expectedTimeouts: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			_ = appsv1.AddToScheme(scheme)
+
+			app := getExampleApp()
+			app.Spec.Namespace = "dex"
+			app.Spec.KubeConfig.InCluster = true
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.GetDexConfigName(app.Name), Namespace: app.Namespace, Annotations: tc.annotations}}
+			objects := []client.Object{secret}
+			if tc.deployment != nil {
+				objects = append(objects, tc.deployment)
+			}
+			policy, err := rotation.NewPolicy(rotation.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.rotating {
+				policy.Start("App/example/test")
+			}
+			recorder := record.NewFakeRecorder(10)
+			s := Service{
+				Client:         fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+				log:            ctrl.Log.WithName("test"),
+				target:         dextarget.NewAppTarget(app),
+				scheme:         scheme,
+				rotationPolicy: policy,
+				recorder:       recorder,
+			}
+			if err := s.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret); err != nil {
+				t.Fatal(err)
+			}
+
+			rolledOut, err := s.isDexConfigRolledOut(ctx, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rolledOut != tc.rolledOut {
+				t.Fatalf("expected %v, got %v", tc.rolledOut, rolledOut)
+			}
+			if err := s.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if _, recorded := secret.Annotations[key.DexConfigRolledOutAtAnnotation]; recorded != tc.expectedRecorded {
+				t.Fatalf("expected rollout recorded %v, got %v", tc.expectedRecorded, recorded)
+			}
+			if rotating := policy.IsRotating("App/example/test"); rotating != tc.expectedRotating {
+				t.Fatalf("expected rotating %v, got %v", tc.expectedRotating, rotating)
+			}
+			if len(recorder.Events) != tc.expectedTimeouts {
+				t.Fatalf("expected %d %s events, got %d", tc.expectedTimeouts, dexConfigRolloutTimeoutReason, len(recorder.Events))
+			}
+		})
+	}
+}
+
+func TestPrepareRotation(t *testing.T) {
+	testCases := []struct {
+		name             string
+		windows          string
+		maxConcurrent    int
+		rotating         []string
+		rolledOut        bool
+		secretCreated    bool
+		expectedAllowed  bool
+		expectedRotating int
+	}{
+		{
+			name:            "case 0: unrestricted",
+			expectedAllowed: true,
+		},
+		{
+			name:    "case 1: outside of window",
+			windows: "0 0 1 1 * 1m",
+		},
+		{
+			name:             "case 2: cap reached",
+			maxConcurrent:    1,
+			rotating:         []string{"App/other/dex-app"},
+			expectedRotating: 1,
+		},
+		{
+			name:             "case 3: secret created keeps the slot",
+			maxConcurrent:    2,
+			rotating:         []string{"App/other/dex-app"},
+			secretCreated:    true,
+			expectedAllowed:  true,
+			expectedRotating: 2,
+		},
+		{
+			name:             "case 4: own rotation not rolled out yet",
+			maxConcurrent:    1,
+			rotating:         []string{"App/example/test"},
+			expectedAllowed:  true,
+			expectedRotating: 1,
+		},
+		{
+			name:             "case 5: own rotation rolled out releases the slot",
+			maxConcurrent:    1,
+			rotating:         []string{"App/example/test"},
+			rolledOut:        true,
+			expectedAllowed:  true,
+			expectedRotating: 0,
+		},
+		{
+			name:             "case 6: urgent secret created outside of window",
+			windows:          "0 0 1 1 * 1m",
+			secretCreated:    true,
+			expectedRotating: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			windows, err := rotation.ParseWindows(tc.windows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policy, err := rotation.NewPolicy(rotation.Config{Windows: windows, MaxConcurrent: tc.maxConcurrent})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tc.rotating {
+				policy.Start(r)
+			}
+			s := Service{
+				log:            ctrl.Log.WithName("test"),
+				target:         dextarget.NewAppTarget(getExampleApp()),
+				rotationPolicy: policy,
+			}
+			appConfig := provider.AppConfig{DexConfigRolledOut: tc.rolledOut}
+			reserved := s.prepareRotation(&appConfig)
+			if appConfig.RotationAllowed != tc.expectedAllowed {
+				t.Fatalf("expected rotation allowed %v, got %v", tc.expectedAllowed, appConfig.RotationAllowed)
+			}
+			s.secretCreated = tc.secretCreated
+			s.finishRotation(reserved)
+			if policy.Rotating() != tc.expectedRotating {
+				t.Fatalf("expected %d rotating targets, got %d", tc.expectedRotating, policy.Rotating())
+			}
+		})
+	}
+}
+
+func TestReconcileDeleteReleasesRotation(t *testing.T) {
+	policy, err := rotation.NewPolicy(rotation.Config{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.Start("App/example/test")
+	s := Service{
+		log:            ctrl.Log.WithName("test"),
+		target:         dextarget.NewAppTarget(getExampleApp()),
+		rotationPolicy: policy,
+		dryRun:         true,
+	}
+	if err := s.ReconcileDelete(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if policy.IsRotating("App/example/test") {
+		t.Fatalf("expected the rotation slot of the deleted target to be released")
+	}
+}
@@ -0,0 +1,185 @@
+package idp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/giantswarm/microerror"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+// providerSelector is the parsed form of a provider.ProviderSelector. Unset label selectors are nil.
+type providerSelector struct {
+	managementCluster *bool
+	target            labels.Selector
+	cluster           labels.Selector
+	organization      labels.Selector
+}
+
+func parseProviderSelector(selector provider.ProviderSelector) (providerSelector, error) {
+	parsed := providerSelector{managementCluster: selector.ManagementCluster}
+	for _, s := range []struct {
+		name     string
+		value    string
+		selector *labels.Selector
+	}{
+		{name: "target", value: selector.Target, selector: &parsed.target},
+		{name: "cluster", value: selector.Cluster, selector: &parsed.cluster},
+		{name: "organization", value: selector.Organization, selector: &parsed.organization},
+	} {
+		if s.value == "" {
+			continue
+		}
+		l, err := labels.Parse(s.value)
+		if err != nil {
+			return providerSelector{}, microerror.Maskf(invalidConfigError, "%s selector %q is not valid: %s", s.name, s.value, err)
+		}
+		*s.selector = l
+	}
+	return parsed, nil
+}
+
+// selectionLabels holds the labels the provider selectors are matched against.
+// Cluster and organization labels are only fetched if a selector needs them.
+type selectionLabels struct {
+	target       labels.Set
+	cluster      labels.Set
+	organization labels.Set
+}
+
+// getSelectedProviders returns the providers which are configured for the dex target.
+// Providers listed in the exclude annotation are never selected, providers listed in the include
+// annotation are always selected and all other providers are selected if their selector matches.
+func (s *Service) getSelectedProviders(ctx context.Context) ([]provider.Provider, error) {
+	annotations := s.target.GetObject().GetAnnotations()
+	include := parseProviderList(annotations[key.ProvidersIncludeAnnotation])
+	exclude := parseProviderList(annotations[key.ProvidersExcludeAnnotation])
+
+	l := &selectionLabels{target: labels.Set(s.target.GetObject().GetLabels())}
+	selected := []provider.Provider{}
+	for _, p := range s.providers {
+		switch {
+		case exclude[p.GetName()]:
+			s.log.V(1).Info(fmt.Sprintf("Provider %s is excluded by annotation %s.", p.GetName(), key.ProvidersExcludeAnnotation))
+			continue
+		case include[p.GetName()]:
+			selected = append(selected, p)
+			continue
+		}
+		selector, err := parseProviderSelector(p.GetSelector())
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		matches, err := s.matchesProviderSelector(ctx, selector, l)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if !matches {
+			s.log.V(1).Info(fmt.Sprintf("Provider %s is not selected for this dex target.", p.GetName()))
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+func (s *Service) matchesProviderSelector(ctx context.Context, selector providerSelector, l *selectionLabels) (bool, error) {
+	if selector.managementCluster != nil && *selector.managementCluster != s.isManagementClusterTarget() {
+		return false, nil
+	}
+	if selector.target != nil && !selector.target.Matches(l.target) {
+		return false, nil
+	}
+	if selector.cluster != nil {
+		if l.cluster == nil {
+			clusterLabels, err := s.getClusterLabels(ctx)
+			if err != nil {
+				return false, microerror.Mask(err)
+			}
+			l.cluster = clusterLabels
+		}
+		if !selector.cluster.Matches(l.cluster) {
+			return false, nil
+		}
+	}
+	if selector.organization != nil {
+		if l.organization == nil {
+			organizationLabels, err := s.getOrganizationLabels(ctx)
+			if err != nil {
+				return false, microerror.Mask(err)
+			}
+			l.organization = organizationLabels
+		}
+		if !selector.organization.Matches(l.organization) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isManagementClusterTarget returns true for the dex of the management cluster itself.
+func (s *Service) isManagementClusterTarget() bool {
+	cluster := s.target.GetClusterLabel()
+	return cluster == "" || cluster == s.managementClusterName
+}
+
+// getClusterLabels returns the labels of the CAPI cluster of the target, which is looked up in the
+// organization namespace and the namespace of the target. Targets without a cluster have no labels.
+func (s *Service) getClusterLabels(ctx context.Context) (labels.Set, error) {
+	cluster := s.target.GetClusterLabel()
+	if cluster == "" {
+		return labels.Set{}, nil
+	}
+	nn := s.target.GetNamespacedName()
+	namespaces := []string{key.GetOrganizationNamespace(nn.Namespace, s.target.GetOrganizationLabel())}
+	if namespaces[0] != nn.Namespace {
+		namespaces = append(namespaces, nn.Namespace)
+	}
+	for _, namespace := range namespaces {
+		c := &capi.Cluster{}
+		err := s.Get(ctx, types.NamespacedName{Name: cluster, Namespace: namespace}, c)
+		if err == nil {
+			return labels.Set(c.GetLabels()), nil
+		}
+		if meta.IsNoMatchError(err) {
+			break
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, microerror.Mask(err)
+		}
+	}
+	return labels.Set{}, nil
+}
+
+// getOrganizationLabels returns the labels of the organization namespace of the target.
+// Namespaces are read through the cache of the manager, which needs list and watch permissions on them.
+func (s *Service) getOrganizationLabels(ctx context.Context) (labels.Set, error) {
+	nn := s.target.GetNamespacedName()
+	namespace := &corev1.Namespace{}
+	if err := s.Get(ctx, types.NamespacedName{Name: key.GetOrganizationNamespace(nn.Namespace, s.target.GetOrganizationLabel())}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return labels.Set{}, nil
+		}
+		return nil, microerror.Mask(err)
+	}
+	return labels.Set(namespace.GetLabels()), nil
+}
+
+func parseProviderList(value string) map[string]bool {
+	providers := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			providers[name] = true
+		}
+	}
+	return providers
+}
@@ -0,0 +1,150 @@
+package idp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	capi "sigs.k8s.io/cluster-api/api/core/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider/mockprovider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestGetSelectedProviders(t *testing.T) {
+	yes, no := true, false
+	workloadCluster := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "wc-dex",
+			Namespace: "org-acme",
+			Labels:    map[string]string{label.Cluster: "wc", label.Organization: "acme", "tier": "gold"},
+		},
+	}
+	managementCluster := &v1alpha1.App{
+		ObjectMeta: metav1.ObjectMeta{Name: "dex-app", Namespace: "giantswarm"},
+	}
+	objects := []runtime.Object{
+		&capi.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "wc", Namespace: "org-acme", Labels: map[string]string{"environment": "production"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "org-acme", Labels: map[string]string{"customer": "acme"}}},
+	}
+
+	testCases := []struct {
+		name        string
+		app         *v1alpha1.App
+		annotations map[string]string
+		selectors   map[string]provider.ProviderSelector
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:      "case 0: no selectors",
+			app:       workloadCluster,
+			selectors: map[string]provider.ProviderSelector{key.OwnerGiantswarm: {}, key.OwnerCustomer: {}},
+			expected:  []string{"giantswarm-mock", "customer-mock"},
+		},
+		{
+			name: "case 1: management cluster only",
+			app:  workloadCluster,
+			selectors: map[string]provider.ProviderSelector{
+				key.OwnerGiantswarm: {ManagementCluster: &yes},
+				key.OwnerCustomer:   {ManagementCluster: &no},
+			},
+			expected: []string{"customer-mock"},
+		},
+		{
+			name: "case 2: management cluster dex",
+			app:  managementCluster,
+			selectors: map[string]provider.ProviderSelector{
+				key.OwnerGiantswarm: {ManagementCluster: &yes},
+				key.OwnerCustomer:   {Organization: "customer=acme"},
+			},
+			expected: []string{"giantswarm-mock"},
+		},
+		{
+			name: "case 3: target, cluster and organization labels",
+			app:  workloadCluster,
+			selectors: map[string]provider.ProviderSelector{
+				key.OwnerGiantswarm: {Target: "tier=gold", Cluster: "environment in (production)"},
+				key.OwnerCustomer:   {Organization: "customer=other"},
+				"partner":           {Cluster: "!environment"},
+			},
+			expected: []string{"giantswarm-mock"},
+		},
+		{
+			name:        "case 4: opt-in and opt-out annotations",
+			app:         workloadCluster,
+			annotations: map[string]string{key.ProvidersIncludeAnnotation: "customer-mock, partner-mock", key.ProvidersExcludeAnnotation: "giantswarm-mock,partner-mock"},
+			selectors: map[string]provider.ProviderSelector{
+				key.OwnerGiantswarm: {},
+				key.OwnerCustomer:   {ManagementCluster: &yes},
+				"partner":           {},
+			},
+			expected: []string{"customer-mock"},
+		},
+		{
+			name:        "case 5: invalid selector",
+			app:         workloadCluster,
+			selectors:   map[string]provider.ProviderSelector{key.OwnerGiantswarm: {Target: "tier in gold"}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			_ = capi.AddToScheme(scheme)
+
+			app := tc.app.DeepCopy()
+			app.Annotations = tc.annotations
+			providers := []provider.Provider{}
+			for _, owner := range []string{key.OwnerGiantswarm, key.OwnerCustomer, "partner"} {
+				selector, ok := tc.selectors[owner]
+				if !ok {
+					continue
+				}
+				p, err := mockprovider.New(provider.ProviderConfig{
+					Credential: provider.ProviderCredential{Name: mockprovider.ProviderName, Owner: owner, Selector: selector},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				providers = append(providers, p)
+			}
+			s := Service{
+				Client:                fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objects...).Build(),
+				providers:             providers,
+				log:                   ctrl.Log.WithName("test"),
+				target:                dextarget.NewAppTarget(app),
+				managementClusterName: "mc",
+			}
+
+			selected, err := s.getSelectedProviders(context.Background())
+			if tc.expectError {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, p := range selected {
+				names = append(names, p.GetName())
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
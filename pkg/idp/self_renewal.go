@@ -2,15 +2,19 @@ package idp
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"time"
 
 	"github.com/giantswarm/microerror"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
 	"github.com/giantswarm/dex-operator/pkg/key"
+	"github.com/giantswarm/dex-operator/pkg/tracing"
 )
 
 // Error definitions
@@ -23,6 +27,19 @@ const (
 	CredentialsSecretName = "dex-operator-credentials"
 	// SelfRenewalAnnotation marks when self-renewal was performed
 	SelfRenewalAnnotation = "dex-operator.giantswarm.io/last-self-renewal"
+	// SelfRenewalChecksumAnnotation is the checksum of the credentials written by the last self-renewal
+	SelfRenewalChecksumAnnotation = "dex-operator.giantswarm.io/self-renewal-checksum"
+	// CredentialsKey holds the credentials in the credentials secret
+	CredentialsKey = "credentials"
+	// CredentialsBackupKey keeps the credentials from before the last self-renewal until the renewed credentials are verified
+	CredentialsBackupKey = "credentials-backup"
+
+	// Outcomes of service credential rotations in metrics
+	RotationOutcomeSuccess              = "success"
+	RotationOutcomeFailure              = "failure"
+	RotationOutcomeManualActionRequired = "manual_action_required"
+	RotationOutcomeVerificationFailed   = "verification_failed"
+	RotationOutcomeReverted             = "reverted"
 )
 
 // CredentialsConfig represents the structure of the credentials YAML
@@ -36,15 +53,27 @@ type ProviderConfig struct {
 	Owner       string            `yaml:"owner"`
 	Credentials map[string]string `yaml:"credentials"`
 	Description string            `yaml:"description,omitempty"`
+	// Settings keeps all other fields of the provider, e.g. display settings and selectors, when the credentials are rewritten.
+	Settings map[string]interface{} `yaml:",inline"`
 }
 
 type ProviderCredentialUpdate struct {
 	ProviderName string
-	Credentials  map[string]string
+	// Owner restricts the update to the provider of this owner. Providers of all owners match if empty.
+	Owner string
+	// Credentials are set on the provider. Empty values remove the credential.
+	Credentials map[string]string
 }
 
 // CheckAndRotateServiceCredentials checks if any providers need credential rotation and performs it
 func (s *Service) CheckAndRotateServiceCredentials(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "idp.Service.CheckAndRotateServiceCredentials", tracing.TargetAttributes(s.target.GetTargetType(), s.target.GetNamespacedName())...)
+	err := s.checkAndRotateServiceCredentials(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *Service) checkAndRotateServiceCredentials(ctx context.Context) error {
 	s.log.Info("Checking if dex-operator service credentials need rotation")
 
 	// Get the app config for the dex-operator itself
@@ -53,15 +82,12 @@ func (s *Service) CheckAndRotateServiceCredentials(ctx context.Context) error {
 		return microerror.Mask(err)
 	}
 
-	// Use the operator's own name instead of a dex app name
-	selfAppConfig := provider.AppConfig{
-		Name:                 key.GetDexOperatorName(s.managementClusterName),
-		RedirectURI:          appConfig.RedirectURI,
-		IdentifierURI:        key.GetIdentifierURI(key.GetDexOperatorName(s.managementClusterName)),
-		SecretValidityMonths: key.SecretValidityMonths,
+	// credentials written by the last self-renewal are verified once more and reverted if they do not work
+	if err := s.verifyRenewedCredentials(ctx, appConfig); err != nil {
+		return microerror.Mask(err)
 	}
 
-	rotationNeeded := false
+	var rotatedProviders []provider.Provider
 	var credentialsToUpdate []ProviderCredentialUpdate
 
 	// Check each provider for self-renewal capability
@@ -72,6 +98,32 @@ func (s *Service) CheckAndRotateServiceCredentials(ctx context.Context) error {
 			continue
 		}
 
+		selfAppConfig, err := s.getSelfAppConfig(prov, appConfig)
+		if err != nil {
+			s.log.Error(err, "Invalid secret lifetime of provider", "provider", prov.GetName())
+			continue
+		}
+
+		if createdAt, ok := prov.GetServiceCredentialCreatedAt(); ok {
+			ServiceCredentialCreated.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName()).Set(float64(createdAt.Unix()))
+		}
+		if expiry, ok, err := prov.GetServiceCredentialExpiry(ctx); err != nil {
+			s.log.Error(err, "Failed to get service credential expiry", "provider", prov.GetName())
+		} else if ok {
+			ServiceCredentialExpiry.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName()).Set(float64(expiry.Unix()))
+		}
+
+		if metadata := prov.GetMissingServiceCredentialMetadata(); len(metadata) > 0 {
+			s.log.Info("Recording missing service credential metadata",
+				"provider", prov.GetName())
+			credentialsToUpdate = append(credentialsToUpdate, ProviderCredentialUpdate{
+				ProviderName: prov.GetProviderName(),
+				Owner:        prov.GetOwner(),
+				Credentials:  metadata,
+			})
+			continue
+		}
+
 		shouldRotate, err := prov.ShouldRotateServiceCredentials(ctx, selfAppConfig)
 		if err != nil {
 			s.log.Error(err, "Failed to check if service credentials should rotate",
@@ -84,29 +136,171 @@ func (s *Service) CheckAndRotateServiceCredentials(ctx context.Context) error {
 				"provider", prov.GetName())
 
 			newCredentials, err := prov.RotateServiceCredentials(ctx, selfAppConfig)
-			if err != nil {
+			if provider.IsManualActionRequired(err) {
+				s.log.Info("Service credential rotation requires manual action",
+					"provider", prov.GetName(), "action", microerror.Pretty(err, false))
+				recordServiceCredentialRotation(prov, RotationOutcomeManualActionRequired)
+				continue
+			} else if err != nil {
 				s.log.Error(err, "Failed to rotate service credentials",
 					"provider", prov.GetName())
+				recordServiceCredentialRotation(prov, RotationOutcomeFailure)
+				continue
+			}
+
+			// the previous credentials stay in place if the new ones do not work
+			if err := prov.VerifyServiceCredentials(ctx, selfAppConfig, newCredentials); err != nil {
+				s.log.Error(err, "Rotated service credentials failed verification, keeping the previous credentials",
+					"provider", prov.GetName())
+				if err := prov.RevertServiceCredentials(ctx, selfAppConfig, newCredentials); err != nil {
+					s.log.Error(err, "Failed to revert rotated service credentials",
+						"provider", prov.GetName())
+				}
+				recordServiceCredentialRotation(prov, RotationOutcomeVerificationFailed)
 				continue
 			}
 
 			credentialsToUpdate = append(credentialsToUpdate, ProviderCredentialUpdate{
 				ProviderName: prov.GetProviderName(),
+				Owner:        prov.GetOwner(),
 				Credentials:  newCredentials,
 			})
-			rotationNeeded = true
+			rotatedProviders = append(rotatedProviders, prov)
 		}
 	}
 
-	if rotationNeeded {
-		s.log.Info("Updating credentials secret with rotated credentials")
-		return s.updateCredentialsSecret(ctx, credentialsToUpdate)
+	if len(credentialsToUpdate) > 0 {
+		s.log.Info("Updating credentials secret with rotated credentials and metadata")
+		err := s.updateCredentialsSecret(ctx, credentialsToUpdate)
+		for _, prov := range rotatedProviders {
+			if err != nil {
+				recordServiceCredentialRotation(prov, RotationOutcomeFailure)
+			} else {
+				recordServiceCredentialRotation(prov, RotationOutcomeSuccess)
+			}
+		}
+		return microerror.Mask(err)
 	}
 
 	s.log.Info("No service credential rotation needed")
 	return nil
 }
 
+// getSelfAppConfig returns the app config of the app of dex-operator itself in the identity provider.
+func (s *Service) getSelfAppConfig(prov provider.Provider, appConfig provider.AppConfig) (provider.AppConfig, error) {
+	lifetime, err := parseSecretLifetime(prov.GetSecretLifetime(), s.maxRotationJitter())
+	if err != nil {
+		return provider.AppConfig{}, microerror.Mask(err)
+	}
+	// Use the operator's own name instead of a dex app name
+	return provider.AppConfig{
+		Name:              key.GetDexOperatorName(s.managementClusterName),
+		RedirectURI:       appConfig.RedirectURI,
+		IdentifierURI:     key.GetIdentifierURI(key.GetDexOperatorName(s.managementClusterName)),
+		SecretValidity:    lifetime.validity,
+		SecretRenewBefore: lifetime.credentialRenewBefore,
+	}, nil
+}
+
+// verifyRenewedCredentials verifies the credentials written by the last self-renewal as they are stored in the
+// credentials secret. The backup of the previous credentials is removed if they work and restored otherwise.
+// The backup is dropped without verification if the credentials were changed by someone else in the meantime.
+func (s *Service) verifyRenewedCredentials(ctx context.Context, appConfig provider.AppConfig) error {
+	secret := &corev1.Secret{}
+	nn := s.target.GetNamespacedName()
+	err := s.Get(ctx, types.NamespacedName{
+		Name:      CredentialsSecretName,
+		Namespace: nn.Namespace,
+	}, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return microerror.Maskf(renewalError, "Failed to get existing credentials secret: %v", err)
+	}
+	backup, ok := secret.Data[CredentialsBackupKey]
+	if !ok {
+		return nil
+	}
+	credentialsData := secret.Data[CredentialsKey]
+	if secret.Annotations[SelfRenewalChecksumAnnotation] != getCredentialsChecksum(credentialsData) {
+		s.log.Info("Credentials were changed since the last self-renewal, dropping the backup of the previous credentials")
+		return s.removeCredentialsBackup(ctx, secret)
+	}
+
+	var existingProviders []ProviderConfig
+	if err := yaml.Unmarshal(credentialsData, &existingProviders); err != nil {
+		return microerror.Maskf(renewalError, "Failed to parse existing credentials: %v", err)
+	}
+	for _, prov := range s.providers {
+		if !prov.SupportsServiceCredentialRenewal() {
+			continue
+		}
+		credentials, ok := findProviderCredentials(existingProviders, prov.GetProviderName(), prov.GetOwner())
+		if !ok {
+			continue
+		}
+		selfAppConfig, err := s.getSelfAppConfig(prov, appConfig)
+		if err != nil {
+			return microerror.Mask(err)
+		}
+		verifyErr := prov.VerifyServiceCredentials(ctx, selfAppConfig, credentials)
+		if verifyErr == nil {
+			continue
+		}
+		// restore the previous credentials of all providers since they were renewed together
+		secret.Data[CredentialsKey] = backup
+		delete(secret.Data, CredentialsBackupKey)
+		delete(secret.Annotations, SelfRenewalChecksumAnnotation)
+		if err := s.Update(ctx, secret); err != nil {
+			return microerror.Maskf(renewalError, "Failed to restore previous credentials: %v", err)
+		}
+		if err := prov.RevertServiceCredentials(ctx, selfAppConfig, credentials); err != nil {
+			s.log.Error(err, "Failed to revert renewed service credentials", "provider", prov.GetName())
+		}
+		recordServiceCredentialRotation(prov, RotationOutcomeReverted)
+		return microerror.Maskf(renewalError, "Renewed credentials of provider %s failed verification and were reverted to the previous credentials: %v", prov.GetName(), verifyErr)
+	}
+
+	s.log.Info("Verified renewed credentials, removing the backup of the previous credentials")
+	return s.removeCredentialsBackup(ctx, secret)
+}
+
+func (s *Service) removeCredentialsBackup(ctx context.Context, secret *corev1.Secret) error {
+	delete(secret.Data, CredentialsBackupKey)
+	delete(secret.Annotations, SelfRenewalChecksumAnnotation)
+	if err := s.Update(ctx, secret); err != nil {
+		return microerror.Maskf(renewalError, "Failed to remove backup of previous credentials: %v", err)
+	}
+	return nil
+}
+
+// findProviderCredentials returns the credentials of the provider with the given name and owner.
+func findProviderCredentials(providers []ProviderConfig, name string, owner string) (map[string]string, bool) {
+	for _, p := range providers {
+		if p.Name == name && (owner == "" || p.Owner == owner) {
+			return p.Credentials, true
+		}
+	}
+	return nil, false
+}
+
+// recordServiceCredentialRotation counts a rotation of the service credentials of the provider by outcome.
+// Outcomes other than success and manual action required count as failed rotation.
+func recordServiceCredentialRotation(prov provider.Provider, outcome string) {
+	ServiceCredentialRotations.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName(), outcome).Inc()
+	switch outcome {
+	case RotationOutcomeSuccess:
+		ServiceCredentialLastRotation.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName(), RotationOutcomeSuccess).SetToCurrentTime()
+	case RotationOutcomeManualActionRequired:
+	default:
+		ServiceCredentialLastRotation.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName(), RotationOutcomeFailure).SetToCurrentTime()
+	}
+}
+
+func getCredentialsChecksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
 // updateCredentialsSecret updates the existing dex-operator-credentials secret with rotated credentials
 func (s *Service) updateCredentialsSecret(ctx context.Context, updates []ProviderCredentialUpdate) error {
 	// Get the existing credentials secret
@@ -121,7 +315,7 @@ func (s *Service) updateCredentialsSecret(ctx context.Context, updates []Provide
 	}
 
 	// Decode the existing credentials
-	credentialsData, exists := secret.Data["credentials"]
+	credentialsData, exists := secret.Data[CredentialsKey]
 	if !exists {
 		return microerror.Maskf(renewalError, "No credentials data found in secret")
 	}
@@ -136,12 +330,16 @@ func (s *Service) updateCredentialsSecret(ctx context.Context, updates []Provide
 	for _, update := range updates {
 		updated := false
 		for i := range existingProviders {
-			if existingProviders[i].Name == update.ProviderName {
+			if existingProviders[i].Name == update.ProviderName && (update.Owner == "" || existingProviders[i].Owner == update.Owner) {
 				// Update with new credentials
 				for key, value := range update.Credentials {
 					if existingProviders[i].Credentials == nil {
 						existingProviders[i].Credentials = make(map[string]string)
 					}
+					if value == "" {
+						delete(existingProviders[i].Credentials, key)
+						continue
+					}
 					existingProviders[i].Credentials[key] = value
 				}
 				updated = true
@@ -163,11 +361,17 @@ func (s *Service) updateCredentialsSecret(ctx context.Context, updates []Provide
 		return microerror.Maskf(renewalError, "Failed to marshal updated credentials: %v", err)
 	}
 
+	// Keep the previous credentials until the renewed ones are verified. An existing backup is older and still unverified.
+	if _, ok := secret.Data[CredentialsBackupKey]; !ok {
+		secret.Data[CredentialsBackupKey] = credentialsData
+	}
+
 	// Update the secret
-	secret.Data["credentials"] = updatedData
+	secret.Data[CredentialsKey] = updatedData
 
 	// Add renewal annotation using helper function
 	s.addSelfRenewalAnnotation(secret)
+	secret.Annotations[SelfRenewalChecksumAnnotation] = getCredentialsChecksum(updatedData)
 
 	if err := s.Update(ctx, secret); err != nil {
 		return microerror.Maskf(renewalError, "Failed to update credentials secret: %v", err)
@@ -15,6 +15,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	"github.com/giantswarm/microerror"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/giantswarm/dex-operator/pkg/dex"
 	"github.com/giantswarm/dex-operator/pkg/dextarget"
@@ -33,6 +35,12 @@ type testSelfRenewalProvider struct {
 	rotateError           error
 	rotateCallCount       int
 	shouldRotateCallCount int
+	verifyError           error
+	verifyCallCount       int
+	revertCallCount       int
+	credentialCreatedAt   time.Time
+	credentialExpiry      time.Time
+	missingMetadata       map[string]string
 }
 
 var _ provider.Provider = (*testSelfRenewalProvider)(nil)
@@ -45,6 +53,10 @@ func (t *testSelfRenewalProvider) DeleteApp(name string, ctx context.Context) er
 	return nil
 }
 
+func (t *testSelfRenewalProvider) SetAppRetention(ctx context.Context, name string, retention *provider.AppRetention) error {
+	return nil
+}
+
 func (t *testSelfRenewalProvider) GetCredentialsForAuthenticatedApp(config provider.AppConfig) (map[string]string, error) {
 	return map[string]string{}, nil
 }
@@ -73,6 +85,18 @@ func (t *testSelfRenewalProvider) GetType() string {
 	return "test"
 }
 
+func (t *testSelfRenewalProvider) GetConnectorDisplay() provider.ConnectorDisplay {
+	return provider.ConnectorDisplay{}
+}
+
+func (t *testSelfRenewalProvider) GetSelector() provider.ProviderSelector {
+	return provider.ProviderSelector{}
+}
+
+func (t *testSelfRenewalProvider) GetSecretLifetime() provider.SecretLifetime {
+	return provider.SecretLifetime{}
+}
+
 func (t *testSelfRenewalProvider) SupportsServiceCredentialRenewal() bool {
 	return t.supportsRenewal
 }
@@ -90,6 +114,28 @@ func (t *testSelfRenewalProvider) RotateServiceCredentials(ctx context.Context,
 	return t.rotateCredentials, nil
 }
 
+func (t *testSelfRenewalProvider) VerifyServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	t.verifyCallCount++
+	return t.verifyError
+}
+
+func (t *testSelfRenewalProvider) RevertServiceCredentials(ctx context.Context, config provider.AppConfig, credentials map[string]string) error {
+	t.revertCallCount++
+	return nil
+}
+
+func (t *testSelfRenewalProvider) GetServiceCredentialCreatedAt() (time.Time, bool) {
+	return t.credentialCreatedAt, !t.credentialCreatedAt.IsZero()
+}
+
+func (t *testSelfRenewalProvider) GetMissingServiceCredentialMetadata() map[string]string {
+	return t.missingMetadata
+}
+
+func (t *testSelfRenewalProvider) GetServiceCredentialExpiry(ctx context.Context) (time.Time, bool, error) {
+	return t.credentialExpiry, !t.credentialExpiry.IsZero(), nil
+}
+
 func TestCheckAndRotateServiceCredentials(t *testing.T) {
 	testCases := []struct {
 		name                   string
@@ -99,6 +145,7 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 		expectedSecretUpdated  bool
 		expectedError          bool
 		expectedAnnotation     bool
+		expectedOutcome        string
 		validateCredentials    func(t *testing.T, secret *corev1.Secret)
 	}{
 		{
@@ -141,8 +188,10 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 					supportsRenewal:   true,
 					shouldRotate:      true,
 					rotateCredentials: map[string]string{"client-id": "new-client", "client-secret": "new-secret"},
+					credentialExpiry:  time.Unix(1700000000, 0),
 				},
 			},
+			expectedOutcome:        "success",
 			existingSecret:         getTestCredentialsSecret(),
 			expectedRotationCalled: true,
 			expectedSecretUpdated:  true,
@@ -181,7 +230,32 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 					t.Errorf("Expected client-secret 'new-secret', got %v", creds["client-secret"])
 					return
 				}
+
+				if string(secret.Data[CredentialsBackupKey]) != string(getTestCredentialsSecret().Data[CredentialsKey]) {
+					t.Errorf("Expected previous credentials to be kept as backup, got %s", secret.Data[CredentialsBackupKey])
+				}
+				if secret.Annotations[SelfRenewalChecksumAnnotation] != getCredentialsChecksum(secret.Data[CredentialsKey]) {
+					t.Errorf("Expected checksum annotation of the renewed credentials")
+				}
+			},
+		},
+		{
+			name: "Rotated credentials fail verification",
+			providers: []provider.Provider{
+				&testSelfRenewalProvider{
+					name:              "test-provider",
+					providerName:      "test",
+					owner:             "giantswarm",
+					supportsRenewal:   true,
+					shouldRotate:      true,
+					rotateCredentials: map[string]string{"client-id": "new-client", "client-secret": "new-secret"},
+					verifyError:       errors.New("invalid client secret"),
+				},
 			},
+			expectedOutcome:        "verification_failed",
+			existingSecret:         getTestCredentialsSecret(),
+			expectedRotationCalled: true,
+			expectedSecretUpdated:  false,
 		},
 		{
 			name: "Provider rotation fails",
@@ -195,10 +269,53 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 					rotateError:     errors.New("rotation failed"),
 				},
 			},
+			expectedOutcome:        "failure",
 			existingSecret:         getTestCredentialsSecret(),
 			expectedRotationCalled: true,  // Rotation IS called, but it fails
 			expectedSecretUpdated:  false, // Secret is NOT updated due to failure
 		},
+		{
+			name: "Provider rotation requires manual action",
+			providers: []provider.Provider{
+				&testSelfRenewalProvider{
+					name:                "test-provider",
+					providerName:        "test",
+					owner:               "giantswarm",
+					supportsRenewal:     true,
+					shouldRotate:        true,
+					rotateError:         microerror.Maskf(provider.ManualActionRequiredError, "generate a new key"),
+					credentialCreatedAt: time.Now().Add(-100 * 24 * time.Hour),
+				},
+			},
+			expectedOutcome:        "manual_action_required",
+			existingSecret:         getTestCredentialsSecret(),
+			expectedRotationCalled: true,
+			expectedSecretUpdated:  false,
+		},
+		{
+			name: "Service credential of unknown age",
+			providers: []provider.Provider{
+				&testSelfRenewalProvider{
+					name:            "test-provider",
+					providerName:    "test",
+					owner:           "giantswarm",
+					supportsRenewal: true,
+					missingMetadata: map[string]string{"created-at": "2026-01-01T00:00:00Z"},
+				},
+			},
+			existingSecret:         getTestCredentialsSecret(),
+			expectedRotationCalled: false,
+			expectedSecretUpdated:  true,
+			validateCredentials: func(t *testing.T, secret *corev1.Secret) {
+				var providers []ProviderConfig
+				if err := yaml.Unmarshal(secret.Data[CredentialsKey], &providers); err != nil {
+					t.Fatalf("Failed to unmarshal credentials: %v", err)
+				}
+				if len(providers) != 1 || providers[0].Credentials["created-at"] != "2026-01-01T00:00:00Z" {
+					t.Errorf("Expected the first seen time to be recorded, got %v", providers)
+				}
+			},
+		},
 		{
 			name: "Missing credentials secret",
 			providers: []provider.Provider{
@@ -239,6 +356,11 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 				managementClusterName: "test-cluster",
 			}
 
+			rotations := map[provider.Provider]float64{}
+			for _, prov := range tc.providers {
+				rotations[prov] = getServiceCredentialRotations(prov, tc.expectedOutcome)
+			}
+
 			err := service.CheckAndRotateServiceCredentials(ctx)
 
 			// Check error expectation
@@ -277,6 +399,46 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 				}
 			}
 
+			if tc.expectedOutcome != "" {
+				for _, prov := range tc.providers {
+					if got := getServiceCredentialRotations(prov, tc.expectedOutcome) - rotations[prov]; got != 1 {
+						t.Errorf("Expected one rotation with outcome %s for provider %s, got %v", tc.expectedOutcome, prov.GetName(), got)
+					}
+				}
+			}
+
+			for _, prov := range tc.providers {
+				if testProv, ok := prov.(*testSelfRenewalProvider); ok && !testProv.credentialExpiry.IsZero() && testProv.supportsRenewal {
+					expiry := testutil.ToFloat64(ServiceCredentialExpiry.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName()))
+					if expiry != float64(testProv.credentialExpiry.Unix()) {
+						t.Errorf("Expected service credential expiry %d, got %v", testProv.credentialExpiry.Unix(), expiry)
+					}
+				}
+			}
+
+			// Rotated credentials which fail verification are reverted in the identity provider
+			for _, prov := range tc.providers {
+				if testProv, ok := prov.(*testSelfRenewalProvider); ok && testProv.verifyError != nil {
+					if testProv.revertCallCount != 1 {
+						t.Errorf("Expected rotated credentials to be reverted once for provider %s, got %d",
+							testProv.name, testProv.revertCallCount)
+					}
+				}
+			}
+
+			if !tc.expectedSecretUpdated && tc.existingSecret != nil {
+				secret := &corev1.Secret{}
+				if err := fakeClient.Get(ctx, types.NamespacedName{
+					Name:      CredentialsSecretName,
+					Namespace: "example",
+				}, secret); err != nil {
+					t.Fatalf("Failed to get secret: %v", err)
+				}
+				if string(secret.Data[CredentialsKey]) != string(tc.existingSecret.Data[CredentialsKey]) {
+					t.Errorf("Expected credentials to be unchanged, got %s", secret.Data[CredentialsKey])
+				}
+			}
+
 			// Check if secret was updated
 			if tc.expectedSecretUpdated || tc.expectedAnnotation {
 				updatedSecret := &corev1.Secret{}
@@ -305,13 +467,122 @@ func TestCheckAndRotateServiceCredentials(t *testing.T) {
 	}
 }
 
+func TestVerifyRenewedCredentials(t *testing.T) {
+	renewedCredentials := `- name: test
+  owner: giantswarm
+  credentials:
+    client-id: original-client
+    client-secret: new-secret
+`
+	previousCredentials := string(getTestCredentialsSecret().Data[CredentialsKey])
+
+	testCases := []struct {
+		name                string
+		secret              *corev1.Secret
+		verifyError         error
+		expectedError       bool
+		expectedVerified    bool
+		expectedReverted    bool
+		expectedCredentials string
+	}{
+		{
+			name:                "case 0: no backup of previous credentials",
+			secret:              getTestCredentialsSecret(),
+			expectedCredentials: previousCredentials,
+		},
+		{
+			name:                "case 1: renewed credentials work",
+			secret:              getRenewedCredentialsSecret(renewedCredentials, previousCredentials, getCredentialsChecksum([]byte(renewedCredentials))),
+			expectedVerified:    true,
+			expectedCredentials: renewedCredentials,
+		},
+		{
+			name:                "case 2: renewed credentials fail verification",
+			secret:              getRenewedCredentialsSecret(renewedCredentials, previousCredentials, getCredentialsChecksum([]byte(renewedCredentials))),
+			verifyError:         errors.New("invalid client secret"),
+			expectedError:       true,
+			expectedVerified:    true,
+			expectedReverted:    true,
+			expectedCredentials: previousCredentials,
+		},
+		{
+			name:                "case 3: credentials were changed after renewal",
+			secret:              getRenewedCredentialsSecret(renewedCredentials, previousCredentials, getCredentialsChecksum([]byte(previousCredentials))),
+			verifyError:         errors.New("invalid client secret"),
+			expectedCredentials: renewedCredentials,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.secret).Build()
+
+			prov := &testSelfRenewalProvider{
+				name:            "test-provider",
+				providerName:    "test",
+				owner:           "giantswarm",
+				supportsRenewal: true,
+				verifyError:     tc.verifyError,
+			}
+			service := Service{
+				Client:                fakeClient,
+				log:                   ctrl.Log.WithName("test"),
+				target:                dextarget.NewAppTarget(getTestApp()),
+				providers:             []provider.Provider{prov},
+				managementClusterName: "test-cluster",
+			}
+
+			reverted := getServiceCredentialRotations(prov, RotationOutcomeReverted)
+
+			err := service.verifyRenewedCredentials(ctx, provider.AppConfig{})
+			if tc.expectedError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tc.expectedError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tc.expectedVerified != (prov.verifyCallCount == 1) {
+				t.Errorf("Expected verification %v, got %d calls", tc.expectedVerified, prov.verifyCallCount)
+			}
+			if tc.expectedReverted != (prov.revertCallCount == 1) {
+				t.Errorf("Expected revert %v, got %d calls", tc.expectedReverted, prov.revertCallCount)
+			}
+			if tc.expectedReverted != (getServiceCredentialRotations(prov, RotationOutcomeReverted)-reverted == 1) {
+				t.Errorf("Expected reverted rotation to be counted: %v", tc.expectedReverted)
+			}
+
+			secret := &corev1.Secret{}
+			if err := fakeClient.Get(ctx, types.NamespacedName{
+				Name:      CredentialsSecretName,
+				Namespace: "example",
+			}, secret); err != nil {
+				t.Fatalf("Failed to get secret: %v", err)
+			}
+			if string(secret.Data[CredentialsKey]) != tc.expectedCredentials {
+				t.Errorf("Expected credentials %q, got %q", tc.expectedCredentials, secret.Data[CredentialsKey])
+			}
+			if _, ok := secret.Data[CredentialsBackupKey]; ok {
+				t.Errorf("Expected backup of previous credentials to be removed")
+			}
+			if _, ok := secret.Annotations[SelfRenewalChecksumAnnotation]; ok {
+				t.Errorf("Expected checksum annotation to be removed")
+			}
+		})
+	}
+}
+
 func TestUpdateCredentialsSecret(t *testing.T) {
 	testCases := []struct {
-		name               string
-		existingSecret     *corev1.Secret
-		updates            []ProviderCredentialUpdate
-		expectedError      bool
-		expectedAnnotation bool
+		name                string
+		existingSecret      *corev1.Secret
+		updates             []ProviderCredentialUpdate
+		expectedError       bool
+		expectedAnnotation  bool
+		expectedCredentials string
 	}{
 		{
 			name:           "Single provider update",
@@ -327,6 +598,47 @@ func TestUpdateCredentialsSecret(t *testing.T) {
 			},
 			expectedAnnotation: true,
 		},
+		{
+			name: "Update keeps settings and removes empty credentials",
+			existingSecret: getCredentialsSecret(`- name: test
+  owner: customer
+  priority: 10
+  selector:
+    managementCluster: true
+  credentials:
+    client-id: customer-client
+- name: test
+  owner: giantswarm
+  secretValidity: 2160h
+  credentials:
+    client-id: original-client
+    next-key: staged
+`),
+			updates: []ProviderCredentialUpdate{
+				{
+					ProviderName: "test",
+					Owner:        "giantswarm",
+					Credentials: map[string]string{
+						"client-id": "updated-client",
+						"next-key":  "",
+					},
+				},
+			},
+			expectedAnnotation: true,
+			expectedCredentials: `- name: test
+  owner: customer
+  credentials:
+    client-id: customer-client
+  priority: 10
+  selector:
+    managementCluster: true
+- name: test
+  owner: giantswarm
+  credentials:
+    client-id: updated-client
+  secretValidity: 2160h
+`,
+		},
 		{
 			name:           "Provider not found in credentials",
 			existingSecret: getTestCredentialsSecret(),
@@ -407,6 +719,10 @@ func TestUpdateCredentialsSecret(t *testing.T) {
 					t.Errorf("Expected self-renewal annotation to be set")
 				}
 			}
+
+			if tc.expectedCredentials != "" && string(updatedSecret.Data["credentials"]) != tc.expectedCredentials {
+				t.Errorf("Expected credentials\n%s\ngot\n%s", tc.expectedCredentials, updatedSecret.Data["credentials"])
+			}
 		})
 	}
 }
@@ -479,12 +795,15 @@ func getTestApp() *v1alpha1.App {
 }
 
 func getTestCredentialsSecret() *corev1.Secret {
-	credentialsYAML := `- name: test
+	return getCredentialsSecret(`- name: test
   owner: giantswarm
   credentials:
     client-id: original-client
     client-secret: original-secret
-`
+`)
+}
+
+func getCredentialsSecret(credentialsYAML string) *corev1.Secret {
 	return &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      CredentialsSecretName,
@@ -507,3 +826,16 @@ func getSecretWithoutCredentialsData() *corev1.Secret {
 		},
 	}
 }
+
+func getRenewedCredentialsSecret(credentialsYAML string, backupYAML string, checksum string) *corev1.Secret {
+	secret := getCredentialsSecret(credentialsYAML)
+	secret.Data[CredentialsBackupKey] = []byte(backupYAML)
+	secret.Annotations = map[string]string{
+		SelfRenewalChecksumAnnotation: checksum,
+	}
+	return secret
+}
+
+func getServiceCredentialRotations(prov provider.Provider, outcome string) float64 {
+	return testutil.ToFloat64(ServiceCredentialRotations.WithLabelValues(prov.GetOwner(), prov.GetType(), prov.GetName(), outcome))
+}
@@ -0,0 +1,303 @@
+package idp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/giantswarm/k8smetadata/pkg/label"
+	"github.com/giantswarm/microerror"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+const staticClientSecretBytes = 32
+
+// StaticClient configures a dex static client which is added to every dex target.
+// Redirect URIs are templates which can reference {{ .BaseDomain }} and {{ .IssuerURI }} of the target.
+type StaticClient struct {
+	ID           string   `yaml:"id"`
+	Name         string   `yaml:"name,omitempty"`
+	Public       bool     `yaml:"public,omitempty"`
+	RedirectURIs []string `yaml:"redirectURIs,omitempty"`
+	TrustedPeers []string `yaml:"trustedPeers,omitempty"`
+	// RotationPeriod is the maximum age of the generated client secret, e.g. 2160h. Secrets are not rotated if empty.
+	RotationPeriod string `yaml:"rotationPeriod,omitempty"`
+
+	rotationPeriod time.Duration
+	redirectURIs   []*template.Template
+}
+
+// ReadStaticClients reads the static clients for all dex targets from a file.
+func ReadStaticClients(fileLocation string) ([]StaticClient, error) {
+	clients := []StaticClient{}
+
+	file, err := os.ReadFile(fileLocation) //nolint:gosec,G304
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	if err := yaml.Unmarshal(file, &clients); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if err := parseStaticClients(clients); err != nil {
+		return nil, microerror.Mask(err)
+	}
+
+	return clients, nil
+}
+
+// ValidatePublicStaticClient returns an error unless a public static client with the id is configured.
+// Public clients need no client secret, so they can be used by kubectl on user machines.
+func ValidatePublicStaticClient(clients []StaticClient, id string) error {
+	for _, c := range clients {
+		if c.ID != id {
+			continue
+		}
+		if !c.Public {
+			return microerror.Maskf(invalidConfigError, "static client %s is not public", id)
+		}
+		return nil
+	}
+	return microerror.Maskf(invalidConfigError, "static client %s is not configured", id)
+}
+
+// parseStaticClients validates the static clients and parses their rotation periods and redirect URI templates.
+func parseStaticClients(clients []StaticClient) error {
+	ids := map[string]bool{}
+	for i := range clients {
+		c := &clients[i]
+		if c.ID == "" {
+			return microerror.Maskf(invalidConfigError, "static client %d has no id", i)
+		}
+		if ids[c.ID] {
+			return microerror.Maskf(invalidConfigError, "static client id %s is not unique", c.ID)
+		}
+		ids[c.ID] = true
+		if len(c.RedirectURIs) == 0 && !c.Public {
+			return microerror.Maskf(invalidConfigError, "static client %s has no redirect URIs", c.ID)
+		}
+		if c.RotationPeriod != "" {
+			period, err := time.ParseDuration(c.RotationPeriod)
+			if err != nil || period <= 0 {
+				return microerror.Maskf(invalidConfigError, "static client %s has invalid rotation period %s", c.ID, c.RotationPeriod)
+			}
+			c.rotationPeriod = period
+		}
+		c.redirectURIs = nil
+		for _, uri := range c.RedirectURIs {
+			t, err := template.New(c.ID).Option("missingkey=error").Parse(uri)
+			if err != nil {
+				return microerror.Maskf(invalidConfigError, "static client %s has invalid redirect URI %s: %s", c.ID, uri, err)
+			}
+			c.redirectURIs = append(c.redirectURIs, t)
+		}
+	}
+	return nil
+}
+
+func (c StaticClient) getRedirectURIs(appConfig provider.AppConfig) ([]string, error) {
+	uris := []string{}
+	for _, t := range c.redirectURIs {
+		var uri bytes.Buffer
+		if err := t.Execute(&uri, appConfig); err != nil {
+			return nil, microerror.Maskf(invalidConfigError, "static client %s: %s", c.ID, err)
+		}
+		uris = append(uris, uri.String())
+	}
+	return uris, nil
+}
+
+// reconcileStaticClients returns the dex static clients of the target.
+// Client secrets are generated per target and kept in the static clients secret next to the dex config secret.
+// Missing secrets and secrets older than the rotation period of their client are (re)generated,
+// secrets of clients which are no longer configured are removed.
+func (s *Service) reconcileStaticClients(ctx context.Context, appConfig provider.AppConfig) ([]dex.StaticClient, error) {
+	if len(s.staticClients) == 0 {
+		return nil, s.deleteStaticClientsSecret(ctx)
+	}
+
+	nn := s.target.GetNamespacedName()
+	secretName := key.GetStaticClientsSecretName(nn.Name)
+
+	secret := &corev1.Secret{}
+	exists := true
+	if err := s.Get(ctx, types.NamespacedName{Name: secretName, Namespace: nn.Namespace}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, microerror.Mask(err)
+		}
+		exists = false
+		secret = getStaticClientsSecret(secretName, nn.Namespace)
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	rotatedAt, err := getStaticClientsRotatedAt(secret)
+	if err != nil {
+		// broken rotation data only causes early rotation
+		s.log.Error(err, fmt.Sprintf("Ignoring invalid rotation data of static clients secret %s/%s.", nn.Namespace, secretName))
+		rotatedAt = map[string]time.Time{}
+	}
+
+	now := time.Now().UTC()
+	changed := false
+	clients := []dex.StaticClient{}
+	configured := map[string]bool{}
+	for _, c := range s.staticClients {
+		configured[c.ID] = true
+		redirectURIs, err := c.getRedirectURIs(appConfig)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		client := dex.StaticClient{
+			ID:           c.ID,
+			Name:         c.Name,
+			Public:       c.Public,
+			RedirectURIs: redirectURIs,
+			TrustedPeers: c.TrustedPeers,
+		}
+		if !c.Public {
+			clientSecret := string(secret.Data[c.ID])
+			last, known := rotatedAt[c.ID]
+			expired := c.rotationPeriod > 0 && known && now.Sub(last) >= c.rotationPeriod
+			if clientSecret == "" || expired {
+				if s.dryRun {
+					s.log.Info(fmt.Sprintf("Dry run: would generate secret for static client %s in secret %s/%s.", c.ID, nn.Namespace, secretName))
+				} else {
+					clientSecret, err = generateClientSecret()
+					if err != nil {
+						return nil, microerror.Mask(err)
+					}
+					secret.Data[c.ID] = []byte(clientSecret)
+					rotatedAt[c.ID] = now
+					changed = true
+					s.log.Info(fmt.Sprintf("Generated secret for static client %s.", c.ID))
+				}
+			} else if !known {
+				// secrets added by hand are rotated one period after they were first seen
+				rotatedAt[c.ID] = now
+				changed = true
+			}
+			client.Secret = clientSecret
+		}
+		clients = append(clients, client)
+	}
+	for id := range secret.Data {
+		if !configured[id] {
+			if s.dryRun {
+				s.log.Info(fmt.Sprintf("Dry run: would remove secret of static client %s which is no longer configured.", id))
+				continue
+			}
+			delete(secret.Data, id)
+			delete(rotatedAt, id)
+			changed = true
+			s.log.Info(fmt.Sprintf("Removed secret of static client %s which is no longer configured.", id))
+		}
+	}
+
+	if !changed || s.dryRun {
+		return clients, nil
+	}
+	if err := setStaticClientsRotatedAt(secret, rotatedAt); err != nil {
+		return nil, microerror.Mask(err)
+	}
+	if !exists {
+		if err := controllerutil.SetControllerReference(s.owner, secret, s.scheme); err != nil {
+			return nil, microerror.Mask(err)
+		}
+		if err := s.Create(ctx, secret); err != nil {
+			return nil, microerror.Mask(err)
+		}
+		s.log.Info(fmt.Sprintf("Created static clients secret %s/%s.", nn.Namespace, secretName))
+	} else {
+		if err := s.Update(ctx, secret); err != nil {
+			return nil, microerror.Mask(err)
+		}
+		s.log.Info(fmt.Sprintf("Updated static clients secret %s/%s.", nn.Namespace, secretName))
+	}
+	return clients, nil
+}
+
+// deleteStaticClientsSecret removes the static clients secret of the target if it exists.
+func (s *Service) deleteStaticClientsSecret(ctx context.Context) error {
+	nn := s.target.GetNamespacedName()
+	secret := &corev1.Secret{}
+	if err := s.Get(ctx, types.NamespacedName{Name: key.GetStaticClientsSecretName(nn.Name), Namespace: nn.Namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return microerror.Mask(err)
+	}
+	if s.dryRun {
+		s.log.Info(fmt.Sprintf("Dry run: would delete static clients secret %s/%s.", secret.Namespace, secret.Name))
+		return nil
+	}
+	if err := s.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return microerror.Mask(err)
+	}
+	s.log.Info(fmt.Sprintf("Deleted static clients secret %s/%s.", secret.Namespace, secret.Name))
+	return nil
+}
+
+func getStaticClientsSecret(name string, namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				label.ManagedBy: key.DexOperatorLabelValue,
+			},
+		},
+		Data: map[string][]byte{},
+	}
+}
+
+func getStaticClientsRotatedAt(secret *corev1.Secret) (map[string]time.Time, error) {
+	rotatedAt := map[string]time.Time{}
+	value, ok := secret.Annotations[key.StaticClientsRotatedAtAnnotation]
+	if !ok {
+		return rotatedAt, nil
+	}
+	if err := json.Unmarshal([]byte(value), &rotatedAt); err != nil {
+		return map[string]time.Time{}, microerror.Mask(err)
+	}
+	return rotatedAt, nil
+}
+
+func setStaticClientsRotatedAt(secret *corev1.Secret, rotatedAt map[string]time.Time) error {
+	data, err := json.Marshal(rotatedAt)
+	if err != nil {
+		return microerror.Mask(err)
+	}
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[key.StaticClientsRotatedAtAnnotation] = string(data)
+	return nil
+}
+
+func generateClientSecret() (string, error) {
+	b := make([]byte, staticClientSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", microerror.Mask(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
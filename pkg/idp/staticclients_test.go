@@ -0,0 +1,211 @@
+package idp
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/dex-operator/pkg/dex"
+	"github.com/giantswarm/dex-operator/pkg/dextarget"
+	"github.com/giantswarm/dex-operator/pkg/idp/provider"
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+func TestParseStaticClients(t *testing.T) {
+	testCases := []struct {
+		name        string
+		clients     []StaticClient
+		expectError bool
+	}{
+		{
+			name: "case 0: valid clients",
+			clients: []StaticClient{
+				{ID: "grafana", RedirectURIs: []string{"https://grafana.{{ .BaseDomain }}/login/generic_oauth"}, RotationPeriod: "2160h"},
+				{ID: "kubectl", Public: true},
+			},
+		},
+		{
+			name:        "case 1: missing id",
+			clients:     []StaticClient{{RedirectURIs: []string{"http://localhost:8000"}}},
+			expectError: true,
+		},
+		{
+			name: "case 2: duplicate id",
+			clients: []StaticClient{
+				{ID: "grafana", RedirectURIs: []string{"http://localhost:8000"}},
+				{ID: "grafana", RedirectURIs: []string{"http://localhost:8000"}},
+			},
+			expectError: true,
+		},
+		{
+			name:        "case 3: confidential client without redirect URIs",
+			clients:     []StaticClient{{ID: "grafana"}},
+			expectError: true,
+		},
+		{
+			name:        "case 4: invalid rotation period",
+			clients:     []StaticClient{{ID: "grafana", RedirectURIs: []string{"http://localhost:8000"}, RotationPeriod: "90d"}},
+			expectError: true,
+		},
+		{
+			name:        "case 5: invalid redirect URI template",
+			clients:     []StaticClient{{ID: "grafana", RedirectURIs: []string{"https://grafana.{{ .BaseDomain }/"}}},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := parseStaticClients(tc.clients)
+			if tc.expectError && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+			if !tc.expectError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidatePublicStaticClient(t *testing.T) {
+	clients := []StaticClient{
+		{ID: "grafana", RedirectURIs: []string{"http://localhost:8000"}},
+		{ID: "kubectl", Public: true},
+	}
+	testCases := []struct {
+		name        string
+		id          string
+		expectError bool
+	}{
+		{
+			name: "case 0: public client",
+			id:   "kubectl",
+		},
+		{
+			name:        "case 1: confidential client",
+			id:          "grafana",
+			expectError: true,
+		},
+		{
+			name:        "case 2: unknown client",
+			id:          "argocd",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePublicStaticClient(clients, tc.id)
+			if tc.expectError && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+			if !tc.expectError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReconcileStaticClients(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	app := getExampleApp()
+	clients := []StaticClient{
+		{ID: "grafana", Name: "Grafana", RedirectURIs: []string{"https://grafana.{{ .BaseDomain }}/login/generic_oauth"}, RotationPeriod: "720h"},
+		{ID: "kubectl", Public: true, RedirectURIs: []string{"http://localhost:8000"}},
+	}
+	if err := parseStaticClients(clients); err != nil {
+		t.Fatal(err)
+	}
+	s := Service{
+		Client:        fakeClient,
+		log:           ctrl.Log.WithName("test"),
+		target:        dextarget.NewAppTarget(app),
+		owner:         app,
+		scheme:        scheme,
+		staticClients: clients,
+	}
+	appConfig := provider.AppConfig{BaseDomain: "wc.example.com", IssuerURI: "https://dex.wc.example.com"}
+	nn := types.NamespacedName{Name: key.GetStaticClientsSecretName(app.Name), Namespace: app.Namespace}
+
+	// initial reconciliation generates the secret of the confidential client
+	result, err := s.reconcileStaticClients(ctx, appConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{}
+	if err := fakeClient.Get(ctx, nn, secret); err != nil {
+		t.Fatal(err)
+	}
+	generated := string(secret.Data["grafana"])
+	if generated == "" {
+		t.Fatal("expected generated grafana secret")
+	}
+	if _, ok := secret.Data["kubectl"]; ok {
+		t.Fatal("expected no secret for public client")
+	}
+	expected := []dex.StaticClient{
+		{ID: "grafana", Name: "Grafana", Secret: generated, RedirectURIs: []string{"https://grafana.wc.example.com/login/generic_oauth"}},
+		{ID: "kubectl", Public: true, RedirectURIs: []string{"http://localhost:8000"}},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+
+	// the secret is kept within the rotation period
+	result, err = s.reconcileStaticClients(ctx, appConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[0].Secret != generated {
+		t.Fatal("expected grafana secret to be kept")
+	}
+
+	// the secret is rotated after the rotation period and unknown clients are removed
+	if err := fakeClient.Get(ctx, nn, secret); err != nil {
+		t.Fatal(err)
+	}
+	rotatedAt, _ := json.Marshal(map[string]time.Time{"grafana": time.Now().Add(-721 * time.Hour)})
+	secret.Annotations[key.StaticClientsRotatedAtAnnotation] = string(rotatedAt)
+	secret.Data["argocd"] = []byte("removed")
+	if err := fakeClient.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	result, err = s.reconcileStaticClients(ctx, appConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result[0].Secret == generated || result[0].Secret == "" {
+		t.Fatal("expected grafana secret to be rotated")
+	}
+	if err := fakeClient.Get(ctx, nn, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := secret.Data["argocd"]; ok {
+		t.Fatal("expected secret of unknown client to be removed")
+	}
+
+	// the secret is removed once no static clients are configured
+	s.staticClients = nil
+	if _, err := s.reconcileStaticClients(ctx, appConfig); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Get(ctx, nn, secret); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected static clients secret to be deleted, got %v", err)
+	}
+}
@@ -50,13 +50,8 @@ func getDexConfigFromSecret(secret *corev1.Secret) (dex.DexConfig, error) {
 
 func getConnectorsFromConfig(config dex.DexConfig) map[string]dex.Connector {
 	connectors := map[string]dex.Connector{}
-	if config.Oidc.Customer != nil {
-		for _, connector := range config.Oidc.Customer.Connectors {
-			connectors[connector.ID] = connector
-		}
-	}
-	if config.Oidc.Giantswarm != nil {
-		for _, connector := range config.Oidc.Giantswarm.Connectors {
+	for _, name := range config.Oidc.GetOwnerNames() {
+		for _, connector := range config.Oidc.GetOwner(name).Connectors {
 			connectors[connector.ID] = connector
 		}
 	}
@@ -2,11 +2,13 @@ package key
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/giantswarm/apiextensions-application/api/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -28,9 +30,93 @@ const (
 	OwnerCustomer                = "customer"
 	OwnerGiantswarmDisplayName   = "Giant Swarm"
 	OwnerCustomerDisplayName     = "Customer"
+	OrganizationNamespacePrefix  = "org-"
 
-	SecretValidityMonths       = 3
-	CredentialRenewalThreshold = 30 * 24 * time.Hour // 30 days before expiry
+	// DefaultSecretValidity is the lifetime of new client secrets unless configured per provider or target.
+	DefaultSecretValidity = 90 * 24 * time.Hour
+	// DefaultSecretRenewBefore is the time before expiry at which client secrets of dex apps are rotated.
+	DefaultSecretRenewBefore = 10 * 24 * time.Hour
+	// DefaultCredentialRenewBefore is the time before expiry at which dex-operator renews its own credentials.
+	DefaultCredentialRenewBefore = 30 * 24 * time.Hour
+	// UrgentRotationThreshold is the time before expiry after which client secrets are rotated outside of rotation windows.
+	UrgentRotationThreshold = 24 * time.Hour
+
+	// PausedAnnotation can be set to "true" on a dex target or its dex config secret
+	// to stop dex-operator from changing them or calling identity providers.
+	PausedAnnotation = "dex-operator.giantswarm.io/paused"
+
+	// DeletionPolicyAnnotation overrides the operator-wide deletion policy for identity
+	// provider app registrations of a single dex target.
+	DeletionPolicyAnnotation = "dex-operator.giantswarm.io/deletion-policy"
+	// RetainedAppsLabel marks configmaps recording app registrations that were retained
+	// when their dex target was deleted.
+	RetainedAppsLabel = "dex-operator.giantswarm.io/retained-idp-apps"
+	// RetainUntilAnnotation holds the time after which retained app registrations are removed.
+	RetainUntilAnnotation = "dex-operator.giantswarm.io/retain-until"
+	RetainedAppsSuffix    = "retained-idp-apps"
+	RetainedAppNameKey    = "appName"
+	// RetainedTargetKey holds namespace and name of the deleted dex target in retention records.
+	RetainedTargetKey = "target"
+	// RetainedProviderKey limits a retention record to the app registration of a single provider,
+	// e.g. one which is no longer selected for its dex target.
+	RetainedProviderKey = "provider"
+
+	// SecretConfigPatchLabel marks configmaps holding the kustomize patch which adds the
+	// dex config secret to a Flux-managed HelmRelease.
+	SecretConfigPatchLabel  = "dex-operator.giantswarm.io/secret-config-patch"
+	SecretConfigPatchSuffix = "dex-config-patch"
+	SecretConfigPatchKey    = "patch.yaml"
+
+	// StaticClientsSecretSuffix names the secret holding the generated dex static client secrets of a dex target.
+	StaticClientsSecretSuffix = "dex-static-clients"
+	// StaticClientsRotatedAtAnnotation records when each static client secret was generated.
+	StaticClientsRotatedAtAnnotation = "dex-operator.giantswarm.io/static-clients-rotated-at"
+
+	// AuthBindingsConfigMapName is the configmap in an organization namespace holding
+	// additional auth bindings for the workload clusters of the organization.
+	AuthBindingsConfigMapName = "dex-operator-auth-bindings"
+	AuthBindingsKey           = "bindings"
+
+	// APIServerPortAnnotation can be set on a dex target to provide the API server port
+	// of its workload cluster, e.g. for imported clusters without a CAPI cluster.
+	APIServerPortAnnotation = "dex-operator.giantswarm.io/api-server-port"
+	APIServerHostAnnotation = "dex-operator.giantswarm.io/api-server-host"
+
+	// ConnectorDisplayAnnotation overrides the display settings of connectors for a single dex target.
+	// The value maps connector ids to priority, icon, hidden and disabled settings in YAML.
+	ConnectorDisplayAnnotation = "dex-operator.giantswarm.io/connector-display"
+
+	// ProvidersIncludeAnnotation adds connectors of providers to a dex target even if their selector does not match.
+	// ProvidersExcludeAnnotation removes connectors of providers from a dex target. Both take comma-separated connector ids.
+	ProvidersIncludeAnnotation = "dex-operator.giantswarm.io/providers-include"
+	ProvidersExcludeAnnotation = "dex-operator.giantswarm.io/providers-exclude"
+
+	// SecretValidityAnnotation and SecretRenewBeforeAnnotation override the validity and the renewal threshold
+	// of client secrets of all providers for a single dex target, e.g. 720h.
+	SecretValidityAnnotation    = "dex-operator.giantswarm.io/secret-validity"
+	SecretRenewBeforeAnnotation = "dex-operator.giantswarm.io/secret-renew-before"
+
+	// DexConfigUpdatedAtAnnotation records when the connectors in the dex config secret were last changed.
+	// DexConfigPreviousChecksumAnnotation records the config checksum of the dex deployment's pod template at that time
+	// and DexConfigRolledOutAtAnnotation when all dex pods were observed to run with a different checksum.
+	// Previous client secrets are revoked once the new dex config is rolled out.
+	DexConfigUpdatedAtAnnotation        = "dex-operator.giantswarm.io/config-updated-at"
+	DexConfigPreviousChecksumAnnotation = "dex-operator.giantswarm.io/config-previous-checksum"
+	DexConfigRolledOutAtAnnotation      = "dex-operator.giantswarm.io/config-rolled-out-at"
+	// DexConfigRolloutTimeout is the time after which a dex config which was not rolled out releases its rotation slot.
+	DexConfigRolloutTimeout = 30 * time.Minute
+	// DexDeploymentConfigChecksumAnnotation is the pod template annotation of the dex deployment which changes
+	// with the rendered dex config. DexDeploymentReleaseLabel selects the deployments of a helm release.
+	DexDeploymentConfigChecksumAnnotation = "checksum/config"
+	DexDeploymentReleaseLabel             = "app.kubernetes.io/instance"
+	// AppKubeConfigSecretKey and HelmReleaseKubeConfigSecretKey are the default keys of the kubeconfig in
+	// the kubeconfig secrets of App CRs and HelmReleases deploying to remote clusters.
+	AppKubeConfigSecretKey         = "kubeConfig"
+	HelmReleaseKubeConfigSecretKey = "value"
+
+	// KubeconfigSuffix names the configmap holding the OIDC kubeconfig of a workload cluster.
+	KubeconfigSuffix = "oidc-kubeconfig"
+	KubeconfigKey    = "kubeconfig"
 
 	// DexSecretConfigPriority is the priority for the dex secret config in App CR extraConfigs
 	DexSecretConfigPriority = 25
@@ -48,6 +134,11 @@ func IsManagementClusterDexApp(app *v1alpha1.App) bool {
 		app.Namespace == MCDexAppDefaultNamespace
 }
 
+// IsPaused checks if the paused annotation is set to "true" on the given object
+func IsPaused(o metav1.Object) bool {
+	return o.GetAnnotations()[PausedAnnotation] == "true"
+}
+
 func DexLabelSelector() metav1.LabelSelector {
 	return metav1.LabelSelector{
 		MatchLabels: map[string]string{
@@ -79,6 +170,19 @@ func GetProviderName(owner string, name string) string {
 	return fmt.Sprintf("%s-%s", owner, name)
 }
 
+// GetOrganizationNamespace returns the organization namespace of a dex target. Targets outside
+// of an organization namespace are assigned through their organization label.
+func GetOrganizationNamespace(targetNamespace string, organization string) string {
+	if IsOrganizationNamespace(targetNamespace) {
+		return targetNamespace
+	}
+	return OrganizationNamespacePrefix + organization
+}
+
+func IsOrganizationNamespace(namespace string) bool {
+	return strings.HasPrefix(namespace, OrganizationNamespacePrefix)
+}
+
 func GetDexConfigName(name string) string {
 	return fmt.Sprintf("%s-%s", name, DexConfigName)
 }
@@ -87,12 +191,50 @@ func GetAuthConfigName(name string) string {
 	return fmt.Sprintf("%s-%s", name, AuthConfigName)
 }
 
+func GetStaticClientsSecretName(name string) string {
+	return fmt.Sprintf("%s-%s", name, StaticClientsSecretSuffix)
+}
+
+func GetKubeconfigName(cluster string) string {
+	return fmt.Sprintf("%s-%s", cluster, KubeconfigSuffix)
+}
+
+// GetClusterCASecretName returns the name of the secret holding the CA of a CAPI workload cluster.
+func GetClusterCASecretName(cluster string) string {
+	return fmt.Sprintf("%s-ca", cluster)
+}
+
+func GetSecretConfigPatchName(name string) string {
+	return fmt.Sprintf("%s-%s", name, SecretConfigPatchSuffix)
+}
+
+// GetRetainedAppsName returns the name of the retention record of a dex target. Records are kept in the
+// namespace of dex-operator, so the name contains the namespace of the target.
+func GetRetainedAppsName(namespace string, name string) string {
+	return fmt.Sprintf("%s-%s-%s", namespace, name, RetainedAppsSuffix)
+}
+
+// GetRetainedProviderAppsName returns the name of the retention record of the app registration of a single provider.
+func GetRetainedProviderAppsName(namespace string, name string, provider string) string {
+	return fmt.Sprintf("%s-%s-%s-%s", namespace, name, provider, RetainedAppsSuffix)
+}
+
 func GetIdpAppName(managementClusterName string, namespace string, name string) string {
 	return fmt.Sprintf("%s-%s-%s", managementClusterName, namespace, name)
 }
 
 func GetDefaultConnectorDescription(connectorDisplayName string, owner string) string {
-	return fmt.Sprintf("%s for %s", connectorDisplayName, GetOwnerDisplayName(owner))
+	return GetConnectorDescription(connectorDisplayName, GetOwnerDisplayName(owner))
+}
+
+func GetConnectorDescription(connectorDisplayName string, ownerDisplayName string) string {
+	return fmt.Sprintf("%s for %s", connectorDisplayName, ownerDisplayName)
+}
+
+// IsValidOwner returns true if the owner can be used as connector group in the dex config.
+// Owner names need to be valid DNS labels, e.g. giantswarm, customer or a partner name.
+func IsValidOwner(owner string) bool {
+	return len(validation.IsDNS1123Label(owner)) == 0
 }
 
 func GetOwnerDisplayName(owner string) string {
@@ -110,6 +252,10 @@ func GetRedirectURI(issuerAddress string) string {
 	return fmt.Sprintf("https://%s/callback", issuerAddress)
 }
 
+func GetIssuerURI(issuerAddress string) string {
+	return fmt.Sprintf("https://%s", issuerAddress)
+}
+
 func GetIdentifierURI(name string) string {
 	return fmt.Sprintf("https://dex.giantswarm.io/%s", name)
 }
@@ -118,6 +264,10 @@ func GetIssuerAddress(clusterDomain string) string {
 	return fmt.Sprintf("dex.%s", clusterDomain)
 }
 
+func GetAPIServerHost(clusterDomain string) string {
+	return fmt.Sprintf("api.%s", clusterDomain)
+}
+
 func GetVintageClusterDomain(baseDomain string) string {
 	return fmt.Sprintf("g8s.%s", baseDomain)
 }
@@ -1,7 +1,49 @@
 package key
 
-import "testing"
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 func TestPlaceholder(t *testing.T) {
 	// Placeholder test for coverage tooling compatibility
 }
+
+func TestIsPaused(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:     "case 0: no annotations",
+			expected: false,
+		},
+		{
+			name:        "case 1: paused",
+			annotations: map[string]string{PausedAnnotation: "true"},
+			expected:    true,
+		},
+		{
+			name:        "case 2: explicitly not paused",
+			annotations: map[string]string{PausedAnnotation: "false"},
+			expected:    false,
+		},
+		{
+			name:        "case 3: other annotations",
+			annotations: map[string]string{"something": "true"},
+			expected:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			if paused := IsPaused(secret); paused != tc.expected {
+				t.Fatalf("expected %v, got %v", tc.expected, paused)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package rotation
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/giantswarm/microerror"
+
+	"github.com/giantswarm/dex-operator/pkg/key"
+)
+
+type Config struct {
+	// Windows restrict scheduled rotations to maintenance windows. Rotations are allowed at any time if empty.
+	Windows []Window
+	// MaxJitter delays the rotation of each target by a stable offset between zero and MaxJitter,
+	// so that secrets created at the same time are not all rotated at once. Secrets about to expire
+	// are rotated regardless of the jitter.
+	MaxJitter time.Duration
+	// MaxConcurrent caps the number of targets which are rotating at the same time. Unlimited if zero.
+	MaxConcurrent int
+	// SlotTimeout releases rotation slots which were not finished in time, e.g. because the rollout of a
+	// target is never observed. Defaults to DefaultSlotTimeout.
+	SlotTimeout time.Duration
+}
+
+// DefaultSlotTimeout bounds the time a target waits for the rollout of rotated secrets while holding a rotation slot.
+const DefaultSlotTimeout = time.Hour
+
+// Policy decides when scheduled secret rotations may happen. It is shared by all reconcilers of the operator.
+// Rotations which cannot be postponed, e.g. because a secret is missing or about to expire, are not subject to it.
+type Policy struct {
+	windows       []Window
+	maxJitter     time.Duration
+	maxConcurrent int
+	slotTimeout   time.Duration
+	now           func() time.Time
+
+	mutex sync.Mutex
+	// rotating holds the time each target reserved its rotation slot
+	rotating map[string]time.Time
+}
+
+func NewPolicy(c Config) (*Policy, error) {
+	if c.MaxJitter < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "rotation jitter must not be negative")
+	}
+	if c.MaxConcurrent < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "maximum of concurrent rotations must not be negative")
+	}
+	// rotations of secrets with the default renewal threshold must not be postponed until they are urgent
+	if c.MaxJitter > key.DefaultSecretRenewBefore-key.UrgentRotationThreshold {
+		return nil, microerror.Maskf(invalidConfigError, "rotation jitter must not exceed %s", key.DefaultSecretRenewBefore-key.UrgentRotationThreshold)
+	}
+	if c.SlotTimeout < 0 {
+		return nil, microerror.Maskf(invalidConfigError, "rotation slot timeout must not be negative")
+	}
+	slotTimeout := c.SlotTimeout
+	if slotTimeout == 0 {
+		slotTimeout = DefaultSlotTimeout
+	}
+	return &Policy{
+		windows:       c.Windows,
+		maxJitter:     c.MaxJitter,
+		maxConcurrent: c.MaxConcurrent,
+		slotTimeout:   slotTimeout,
+		now:           time.Now,
+		rotating:      map[string]time.Time{},
+	}, nil
+}
+
+// InWindow returns true if scheduled rotations are allowed at the given time.
+func (p *Policy) InWindow(t time.Time) bool {
+	if len(p.windows) == 0 {
+		return true
+	}
+	for _, w := range p.windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Jitter returns the stable rotation delay of a target.
+func (p *Policy) Jitter(target string) time.Duration {
+	if p.maxJitter <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(target))
+	return time.Duration(h.Sum64() % uint64(p.maxJitter))
+}
+
+// MaxJitter returns the maximum delay of scheduled rotations.
+func (p *Policy) MaxJitter() time.Duration {
+	return p.maxJitter
+}
+
+// IsRotating returns true if the target holds a rotation slot.
+func (p *Policy) IsRotating(target string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.expireSlots()
+	_, ok := p.rotating[target]
+	return ok
+}
+
+// TryStart reserves a rotation slot for the target. It returns true if the target holds a slot afterwards.
+func (p *Policy) TryStart(target string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.expireSlots()
+	if _, ok := p.rotating[target]; ok {
+		return true
+	}
+	if p.maxConcurrent > 0 && len(p.rotating) >= p.maxConcurrent {
+		return false
+	}
+	p.rotating[target] = p.now()
+	return true
+}
+
+// Start marks the target as rotating regardless of the cap, e.g. for rotations which could not be postponed.
+// The slot timeout starts again for targets which already hold a slot.
+func (p *Policy) Start(target string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.rotating[target] = p.now()
+}
+
+// Finish releases the rotation slot of the target.
+func (p *Policy) Finish(target string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.rotating, target)
+}
+
+// Rotating returns the number of targets which are currently rotating.
+func (p *Policy) Rotating() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.expireSlots()
+	return len(p.rotating)
+}
+
+// expireSlots releases the slots of targets whose rollout was not observed within the slot timeout,
+// e.g. because they were deleted or their dex deployment is stuck. Callers must hold the mutex.
+func (p *Policy) expireSlots() {
+	now := p.now()
+	for target, started := range p.rotating {
+		if now.Sub(started) >= p.slotTimeout {
+			delete(p.rotating, target)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewPolicy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      Config
+		expectError bool
+	}{
+		{
+			name: "case 0: unrestricted",
+		},
+		{
+			name:   "case 1: jitter and cap",
+			config: Config{MaxJitter: 48 * time.Hour, MaxConcurrent: 3},
+		},
+		{
+			name:        "case 2: negative jitter",
+			config:      Config{MaxJitter: -time.Hour},
+			expectError: true,
+		},
+		{
+			name:        "case 3: negative cap",
+			config:      Config{MaxConcurrent: -1},
+			expectError: true,
+		},
+		{
+			name:        "case 4: jitter postpones rotations until they are urgent",
+			config:      Config{MaxJitter: 240 * time.Hour},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPolicy(tc.config)
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectError {
+				t.Fatal("expected an error")
+			}
+			if err != nil && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyInWindow(t *testing.T) {
+	windows, err := ParseWindows("0 2 * * * 1h;0 14 * * * 1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := NewPolicy(Config{Windows: windows})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	if !p.InWindow(day.Add(14*time.Hour + 30*time.Minute)) {
+		t.Fatal("expected time in second window")
+	}
+	if p.InWindow(day.Add(12 * time.Hour)) {
+		t.Fatal("expected time outside of windows")
+	}
+
+	unrestricted, err := NewPolicy(Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !unrestricted.InWindow(day.Add(12 * time.Hour)) {
+		t.Fatal("expected rotations to be allowed at any time without windows")
+	}
+}
+
+func TestPolicyJitter(t *testing.T) {
+	p, err := NewPolicy(Config{MaxJitter: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := p.Jitter("App/giantswarm/dex-app")
+	if a < 0 || a >= 48*time.Hour {
+		t.Fatalf("jitter %s out of range", a)
+	}
+	if b := p.Jitter("App/giantswarm/dex-app"); a != b {
+		t.Fatalf("expected stable jitter, got %s and %s", a, b)
+	}
+	if c := p.Jitter("HelmRelease/org-acme/dex-app"); a == c {
+		t.Fatalf("expected different jitter for different targets, got %s", c)
+	}
+
+	noJitter, err := NewPolicy(Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if j := noJitter.Jitter("App/giantswarm/dex-app"); j != 0 {
+		t.Fatalf("expected no jitter, got %s", j)
+	}
+}
+
+func TestPolicyConcurrency(t *testing.T) {
+	p, err := NewPolicy(Config{MaxConcurrent: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.TryStart("a") || !p.TryStart("b") {
+		t.Fatal("expected slots to be available")
+	}
+	if !p.TryStart("a") {
+		t.Fatal("expected target holding a slot to keep it")
+	}
+	if p.TryStart("c") {
+		t.Fatal("expected cap to be reached")
+	}
+	p.Start("c")
+	if p.Rotating() != 3 {
+		t.Fatalf("expected 3 rotating targets, got %d", p.Rotating())
+	}
+	p.Finish("a")
+	p.Finish("b")
+	if !p.IsRotating("c") || p.IsRotating("a") {
+		t.Fatal("unexpected rotating targets")
+	}
+	if !p.TryStart("d") {
+		t.Fatal("expected slot to be available after finishing")
+	}
+}
+
+func TestPolicySlotTimeout(t *testing.T) {
+	p, err := NewPolicy(Config{MaxConcurrent: 1, SlotTimeout: time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	if !p.TryStart("a") {
+		t.Fatal("expected slot to be available")
+	}
+	now = now.Add(59 * time.Minute)
+	if p.TryStart("b") {
+		t.Fatal("expected cap to be reached before the slot timeout")
+	}
+	now = now.Add(time.Minute)
+	if p.IsRotating("a") {
+		t.Fatal("expected slot to be released after the slot timeout")
+	}
+	if !p.TryStart("b") {
+		t.Fatal("expected slot to be available after the slot timeout")
+	}
+}
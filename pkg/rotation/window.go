@@ -0,0 +1,147 @@
+package rotation
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/giantswarm/microerror"
+)
+
+// maxWindowDuration bounds the length of a window so that checking it stays cheap.
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// Window is a recurring maintenance window. It starts at every minute matching a cron schedule
+// and lasts for a fixed duration, e.g. "0 2 * * 1-4 4h" from 02:00 to 06:00 UTC Monday to Thursday.
+type Window struct {
+	spec     string
+	minute   []bool
+	hour     []bool
+	day      []bool
+	month    []bool
+	weekday  []bool
+	duration time.Duration
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// ParseWindow parses a window from a five field cron expression in UTC followed by a duration.
+// Fields support *, lists, ranges and steps, e.g. "*/15 1,3 * 1-6 1-5 30m". Sunday is 0 or 7.
+func ParseWindow(spec string) (Window, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields)+1 {
+		return Window{}, microerror.Maskf(invalidConfigError, "rotation window %q needs five cron fields and a duration", spec)
+	}
+	w := Window{spec: strings.Join(fields, " ")}
+	targets := []*[]bool{&w.minute, &w.hour, &w.day, &w.month, &w.weekday}
+	for i, f := range cronFields {
+		values, err := parseCronField(fields[i], f)
+		if err != nil {
+			return Window{}, microerror.Maskf(invalidConfigError, "rotation window %q: %s", spec, err)
+		}
+		*targets[i] = values
+	}
+	// Sunday can be written as 0 or 7
+	if w.weekday[7] {
+		w.weekday[0] = true
+	}
+	duration, err := time.ParseDuration(fields[len(cronFields)])
+	if err != nil || duration <= 0 || duration > maxWindowDuration {
+		return Window{}, microerror.Maskf(invalidConfigError, "rotation window %q has invalid duration %s, it needs to be positive and at most %s", spec, fields[len(cronFields)], maxWindowDuration)
+	}
+	w.duration = duration
+	return w, nil
+}
+
+// ParseWindows parses a list of windows separated by semicolons. An empty list is valid.
+func ParseWindows(specs string) ([]Window, error) {
+	windows := []Window{}
+	for _, spec := range strings.Split(specs, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		w, err := ParseWindow(spec)
+		if err != nil {
+			return nil, microerror.Mask(err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func (w Window) String() string {
+	return w.spec
+}
+
+// Contains returns true if the time lies within an occurrence of the window.
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC().Truncate(time.Minute)
+	for start := t; t.Sub(start) < w.duration; start = start.Add(-time.Minute) {
+		if w.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Window) matches(t time.Time) bool {
+	return w.minute[t.Minute()] && w.hour[t.Hour()] && w.day[t.Day()] && w.month[int(t.Month())] && w.weekday[int(t.Weekday())]
+}
+
+func parseCronField(value string, f cronField) ([]bool, error) {
+	values := make([]bool, f.max+1)
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return nil, microerror.Maskf(invalidConfigError, "invalid step %q in %s field", stepPart, f.name)
+			}
+			step = s
+		}
+		first, last := f.min, f.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if first, err = parseCronValue(from, f); err != nil {
+				return nil, microerror.Mask(err)
+			}
+			last = first
+			if isRange {
+				if last, err = parseCronValue(to, f); err != nil {
+					return nil, microerror.Mask(err)
+				}
+			} else if hasStep {
+				last = f.max
+			}
+			if last < first {
+				return nil, microerror.Maskf(invalidConfigError, "invalid range %q in %s field", rangePart, f.name)
+			}
+		}
+		for v := first; v <= last; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, f cronField) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil || v < f.min || v > f.max {
+		return 0, microerror.Maskf(invalidConfigError, "invalid value %q in %s field, it needs to be between %d and %d", value, f.name, f.min, f.max)
+	}
+	return v, nil
+}
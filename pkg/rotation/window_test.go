@@ -0,0 +1,162 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spec        string
+		expectError bool
+	}{
+		{
+			name: "case 0: daily window",
+			spec: "0 2 * * * 4h",
+		},
+		{
+			name: "case 1: lists, ranges and steps",
+			spec: "*/15 1,3 1-15 1-6 1-5 30m",
+		},
+		{
+			name: "case 2: sunday as 7",
+			spec: "0 0 * * 7 24h",
+		},
+		{
+			name:        "case 3: missing duration",
+			spec:        "0 2 * * *",
+			expectError: true,
+		},
+		{
+			name:        "case 4: value out of range",
+			spec:        "0 24 * * * 1h",
+			expectError: true,
+		},
+		{
+			name:        "case 5: inverted range",
+			spec:        "0 2 * * 5-1 1h",
+			expectError: true,
+		},
+		{
+			name:        "case 6: invalid step",
+			spec:        "*/0 2 * * * 1h",
+			expectError: true,
+		},
+		{
+			name:        "case 7: duration too long",
+			spec:        "0 2 * * * 200h",
+			expectError: true,
+		},
+		{
+			name:        "case 8: negative duration",
+			spec:        "0 2 * * * -1h",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseWindow(tc.spec)
+			if err != nil && !tc.expectError {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expectError {
+				t.Fatal("expected an error")
+			}
+			if err != nil && !IsInvalidConfig(err) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 0 {
+		t.Fatalf("expected no windows, got %d", len(windows))
+	}
+	windows, err = ParseWindows("0 2 * * 1-4 4h; 0 10 * * 6 2h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windows) != 2 || windows[1].String() != "0 10 * * 6 2h" {
+		t.Fatalf("unexpected windows %v", windows)
+	}
+	if _, err = ParseWindows("0 2 * * 1-4 4h;invalid"); !IsInvalidConfig(err) {
+		t.Fatalf("expected invalid config error, got %v", err)
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2026-10-19 is a Monday
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		spec     string
+		time     time.Time
+		contains bool
+	}{
+		{
+			name:     "case 0: at the start",
+			spec:     "0 2 * * 1-4 4h",
+			time:     monday.Add(2 * time.Hour),
+			contains: true,
+		},
+		{
+			name:     "case 1: within the window",
+			spec:     "0 2 * * 1-4 4h",
+			time:     monday.Add(5*time.Hour + 59*time.Minute),
+			contains: true,
+		},
+		{
+			name: "case 2: at the end",
+			spec: "0 2 * * 1-4 4h",
+			time: monday.Add(6 * time.Hour),
+		},
+		{
+			name: "case 3: before the start",
+			spec: "0 2 * * 1-4 4h",
+			time: monday.Add(time.Hour + 59*time.Minute),
+		},
+		{
+			name: "case 4: other weekday",
+			spec: "0 2 * * 1-4 4h",
+			time: monday.Add(4*24*time.Hour + 3*time.Hour),
+		},
+		{
+			name:     "case 5: window spanning midnight",
+			spec:     "0 22 * * 1 4h",
+			time:     monday.Add(25 * time.Hour),
+			contains: true,
+		},
+		{
+			name:     "case 6: sunday as 7",
+			spec:     "0 0 * * 7 24h",
+			time:     monday.Add(-time.Hour),
+			contains: true,
+		},
+		{
+			name:     "case 7: time in other location",
+			spec:     "0 2 * * 1-4 4h",
+			time:     monday.Add(3 * time.Hour).In(time.FixedZone("UTC+5", 5*60*60)),
+			contains: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := ParseWindow(tc.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contains := w.Contains(tc.time); contains != tc.contains {
+				t.Fatalf("expected %v, got %v", tc.contains, contains)
+			}
+		})
+	}
+}
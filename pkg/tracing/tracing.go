@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/giantswarm/microerror"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// TracerName is the instrumentation scope of all spans created by dex-operator.
+	TracerName = "github.com/giantswarm/dex-operator"
+
+	TargetTypeKey      = attribute.Key("dex.target.type")
+	TargetNameKey      = attribute.Key("dex.target.name")
+	TargetNamespaceKey = attribute.Key("dex.target.namespace")
+	ProviderTypeKey    = attribute.Key("dex.provider.type")
+	ProviderNameKey    = attribute.Key("dex.provider.name")
+	ProviderOwnerKey   = attribute.Key("dex.provider.owner")
+)
+
+type Config struct {
+	// Endpoint is the URL of the OTLP/HTTP traces endpoint, e.g. http://otel-collector:4318/v1/traces.
+	// Tracing is disabled if empty.
+	Endpoint string
+	// SampleRatio is the ratio of root spans which are sampled, between 0 and 1.
+	SampleRatio float64
+	// ServiceName is reported as service.name resource attribute of all spans.
+	ServiceName string
+}
+
+// Setup registers a global tracer provider which exports spans to the configured OTLP endpoint.
+// It returns a function flushing and stopping the export on shutdown.
+// If no endpoint is configured, the global no-op tracer provider is kept and spans are not recorded.
+func Setup(ctx context.Context, c Config) (func(context.Context) error, error) {
+	if c.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if u, err := url.Parse(c.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, microerror.Maskf(invalidConfigError, "tracing endpoint %q must be a URL, e.g. http://otel-collector:4318/v1/traces", c.Endpoint)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, microerror.Maskf(invalidConfigError, "tracing sample ratio must be between 0 and 1")
+	}
+	if c.ServiceName == "" {
+		return nil, microerror.Maskf(invalidConfigError, "tracing service name must not be empty")
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(c.Endpoint))
+	if err != nil {
+		return nil, microerror.Mask(err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(c.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tracerProvider.Shutdown, nil
+}
+
+// Start starts a span as child of the span in the context, if any.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End marks the span as failed if an error is given and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TargetAttributes identify the dex target a span belongs to.
+func TargetAttributes(targetType string, nn types.NamespacedName) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		TargetTypeKey.String(targetType),
+		TargetNameKey.String(nn.Name),
+		TargetNamespaceKey.String(nn.Namespace),
+	}
+}
+
+// ProviderAttributes identify the identity provider a span belongs to.
+func ProviderAttributes(providerType, providerName, owner string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		ProviderTypeKey.String(providerType),
+		ProviderNameKey.String(providerName),
+		ProviderOwnerKey.String(owner),
+	}
+}
+
+// NewTransport wraps an http transport so that requests are traced as child spans of the span in their context.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetup(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        Config
+		expectedError error
+	}{
+		{
+			name:   "case 0: tracing disabled",
+			config: Config{},
+		},
+		{
+			name: "case 1: valid config",
+			config: Config{
+				Endpoint:    "http://localhost:4318/v1/traces",
+				SampleRatio: 0.5,
+				ServiceName: "dex-operator",
+			},
+		},
+		{
+			name: "case 2: endpoint is not a URL",
+			config: Config{
+				Endpoint:    "localhost:4318",
+				SampleRatio: 1,
+				ServiceName: "dex-operator",
+			},
+			expectedError: invalidConfigError,
+		},
+		{
+			name: "case 3: sample ratio out of range",
+			config: Config{
+				Endpoint:    "http://localhost:4318/v1/traces",
+				SampleRatio: 2,
+				ServiceName: "dex-operator",
+			},
+			expectedError: invalidConfigError,
+		},
+		{
+			name: "case 4: missing service name",
+			config: Config{
+				Endpoint:    "http://localhost:4318/v1/traces",
+				SampleRatio: 1,
+			},
+			expectedError: invalidConfigError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := otel.GetTracerProvider()
+			t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+			shutdown, err := Setup(context.Background(), tc.config)
+			if tc.expectedError != nil {
+				if !IsInvalidConfig(err) {
+					t.Fatalf("expected invalid config error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Fatalf("unexpected error on shutdown: %v", err)
+			}
+		})
+	}
+}
+
+func TestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+
+	ctx, reconcileSpan := Start(context.Background(), "reconcile", TargetAttributes("App", types.NamespacedName{Name: "dex", Namespace: "example"})...)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	End(reconcileSpan, errors.New("failed"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	requestSpan, reconcile := spans[0], spans[1]
+	if requestSpan.Parent().SpanID() != reconcile.SpanContext().SpanID() {
+		t.Fatalf("expected request span to be a child of the reconcile span")
+	}
+	if reconcile.Status().Code != codes.Error {
+		t.Fatalf("expected reconcile span to be marked as failed, got %v", reconcile.Status().Code)
+	}
+	if len(reconcile.Attributes()) != 3 || reconcile.Attributes()[1].Value.AsString() != "dex" {
+		t.Fatalf("expected target attributes on reconcile span, got %v", reconcile.Attributes())
+	}
+}
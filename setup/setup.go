@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/giantswarm/dex-operator/controllers"
 	"github.com/giantswarm/dex-operator/pkg/idp/provider"
@@ -25,6 +26,9 @@ const (
 	DeleteAction = "delete"
 	UpdateAction = "update"
 	CreateAction = "create"
+
+	// setupSecretValidity is the lifetime of the credentials created for dex-operator itself.
+	setupSecretValidity = 180 * 24 * time.Hour
 )
 
 type SetupConfig struct {
@@ -247,10 +251,10 @@ func providerAlreadyPresent(providers []provider.Provider, provider provider.Pro
 
 func getAppConfigForInstallation(managementClusterName string, domains []string) provider.AppConfig {
 	return provider.AppConfig{
-		Name:                 key.GetDexOperatorName(managementClusterName),
-		SecretValidityMonths: 6,
-		IdentifierURI:        key.GetIdentifierURI(key.GetDexOperatorName(managementClusterName)),
-		RedirectURI:          getGithubRedirectURLs(domains),
+		Name:           key.GetDexOperatorName(managementClusterName),
+		SecretValidity: setupSecretValidity,
+		IdentifierURI:  key.GetIdentifierURI(key.GetDexOperatorName(managementClusterName)),
+		RedirectURI:    getGithubRedirectURLs(domains),
 	}
 }
 